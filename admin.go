@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ijalalfrz/coinbit-test/entity"
+	"github.com/ijalalfrz/coinbit-test/pubsub"
+	"github.com/ijalalfrz/coinbit-test/wallet"
+	"github.com/lovoo/goka"
+	"github.com/sirupsen/logrus"
+)
+
+const adminUsage = `usage: admin <command> [flags] [arguments]
+
+commands:
+  get <table> <key>                    print the value of key
+  list [-prefix p] <table>             print the keys, one per line
+  dump [-prefix p] <table>             print the keys and values as json lines
+  set [-confirm] <table> <key> <json>  overwrite the value of key
+  delete [-confirm] <table> <key>      delete key
+  decode [-key k | -partition p [-offset o]] [-limit n]
+                                       print the decoded messages of the deposits stream as json lines
+
+tables are %s and %s, the flags come before the arguments.
+`
+
+// errNotConfirmed is returned by a write which is not confirmed, the change is only printed.
+var errNotConfirmed = errors.New("nothing is written, run again with -confirm to write")
+
+// adminTable is a group table the admin command can read and write.
+type adminTable struct {
+	group string
+	codec pubsub.GokaCodec
+}
+
+// adminMessage is a decoded message of the deposits stream.
+type adminMessage struct {
+	Key       string      `json:"key"`
+	Partition int32       `json:"partition"`
+	Offset    int64       `json:"offset"`
+	Timestamp time.Time   `json:"timestamp"`
+	Value     interface{} `json:"value"`
+}
+
+// runAdmin will run an admin command on the group tables or the deposits stream and print the result to stdout.
+func runAdmin(logger *logrus.Logger, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, adminUsage, cfg.Topic.BalanceGroup, cfg.Topic.ThresholdGroup)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	flags := flag.NewFlagSet("admin "+args[0], flag.ContinueOnError)
+	var err error
+	switch args[0] {
+	case "get":
+		err = adminGet(ctx, logger, flags, args[1:])
+	case "list":
+		err = adminList(ctx, logger, flags, args[1:], false)
+	case "dump":
+		err = adminList(ctx, logger, flags, args[1:], true)
+	case "set":
+		err = adminSet(ctx, logger, flags, args[1:])
+	case "delete":
+		err = adminDelete(ctx, logger, flags, args[1:])
+	case "decode":
+		err = adminDecode(ctx, logger, flags, args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, adminUsage, cfg.Topic.BalanceGroup, cfg.Topic.ThresholdGroup)
+		return 1
+	}
+	if err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			logger.Error(err)
+		}
+		return 1
+	}
+	return 0
+}
+
+func adminGet(ctx context.Context, logger *logrus.Logger, flags *flag.FlagSet, args []string) (err error) {
+	table, rest, err := parseAdminArgs(flags, args, "<table> <key>", 1)
+	if err != nil {
+		return
+	}
+	view, err := openAdminView(ctx, logger, table)
+	if err != nil {
+		return
+	}
+	defer view.Close()
+
+	data, err := view.Get(rest[0])
+	if err != nil {
+		return
+	}
+	if data == nil {
+		return fmt.Errorf("key %s is not found in %s", rest[0], table.group)
+	}
+	return printIndented(data)
+}
+
+func adminList(ctx context.Context, logger *logrus.Logger, flags *flag.FlagSet, args []string, values bool) (err error) {
+	prefix := flags.String("prefix", "", "only the keys starting with prefix")
+	table, _, err := parseAdminArgs(flags, args, "<table>", 0)
+	if err != nil {
+		return
+	}
+	view, err := openAdminView(ctx, logger, table)
+	if err != nil {
+		return
+	}
+	defer view.Close()
+
+	encoder := json.NewEncoder(os.Stdout)
+	var printErr error
+	err = view.IteratePrefix(*prefix, func(key string, value interface{}) bool {
+		if values {
+			printErr = encoder.Encode(map[string]interface{}{"key": key, "value": value})
+		} else {
+			_, printErr = fmt.Println(key)
+		}
+		return printErr == nil && ctx.Err() == nil
+	})
+	if err == nil {
+		err = printErr
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	return
+}
+
+func adminSet(ctx context.Context, logger *logrus.Logger, flags *flag.FlagSet, args []string) (err error) {
+	confirm := flags.Bool("confirm", false, "write the value, it is only printed otherwise")
+	table, rest, err := parseAdminArgs(flags, args, "<table> <key> <json>", 2)
+	if err != nil {
+		return
+	}
+	key := rest[0]
+	// the value is decoded and encoded again so that only a valid value is written
+	value, err := table.codec.Decode([]byte(rest[1]))
+	if err != nil {
+		return
+	}
+	if _, err = table.codec.Encode(value); err != nil {
+		return
+	}
+	if walletId := walletIdOf(value); walletId != key {
+		return fmt.Errorf("wallet_id %q of the value is not the key %q", walletId, key)
+	}
+	return writeAdminTable(ctx, logger, table, key, value, *confirm)
+}
+
+func adminDelete(ctx context.Context, logger *logrus.Logger, flags *flag.FlagSet, args []string) (err error) {
+	confirm := flags.Bool("confirm", false, "delete the key, it is only printed otherwise")
+	table, rest, err := parseAdminArgs(flags, args, "<table> <key>", 1)
+	if err != nil {
+		return
+	}
+	return writeAdminTable(ctx, logger, table, rest[0], nil, *confirm)
+}
+
+func adminDecode(ctx context.Context, logger *logrus.Logger, flags *flag.FlagSet, args []string) (err error) {
+	key := flags.String("key", "", "only the messages of key")
+	partition := flags.Int("partition", -1, "only the messages of partition")
+	offset := flags.Int64("offset", 0, "first offset of the partition")
+	limit := flags.Int("limit", 0, "largest number of printed messages, 0 prints every message")
+	if err = flags.Parse(args); err != nil {
+		return
+	}
+	if flags.NArg() > 0 || (*key != "" && *partition >= 0) {
+		flags.Usage()
+		return flag.ErrHelp
+	}
+
+	replayer, err := pubsub.NewGokaStreamReplayer(logger, cfg.SaramaKafka.Addresses, cfg.SaramaKafka.Config, cfg.Topic.Deposit, wallet.NewDepositCodec())
+	if err != nil {
+		return
+	}
+	defer replayer.Close()
+
+	encoder := json.NewEncoder(os.Stdout)
+	printed := 0
+	var printErr error
+	printMessage := func(message pubsub.ReplayedMessage) bool {
+		printErr = encoder.Encode(adminMessage{
+			Key:       message.Key,
+			Partition: message.Position.Partition,
+			Offset:    message.Position.Offset,
+			Timestamp: message.Timestamp,
+			Value:     message.Value,
+		})
+		printed++
+		return printErr == nil && (*limit <= 0 || printed < *limit)
+	}
+	switch {
+	case *key != "":
+		var oldest int64
+		oldest, err = replayer.Replay(ctx, *key, printMessage)
+		if oldest > 0 {
+			logger.Warnf("Messages before offset %d of the partition of key %s are not retained anymore", oldest, *key)
+		}
+	case *partition >= 0:
+		err = replayer.ReplayFrom(ctx, pubsub.Position{Partition: int32(*partition), Offset: *offset}, printMessage)
+	default:
+		_, err = replayer.ReplayAll(ctx, printMessage)
+	}
+	if err == nil {
+		err = printErr
+	}
+	return
+}
+
+// parseAdminArgs will parse the flags and return the table of the first argument and the n next arguments.
+func parseAdminArgs(flags *flag.FlagSet, args []string, usage string, n int) (table adminTable, rest []string, err error) {
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s [flags] %s\n", flags.Name(), usage)
+		flags.PrintDefaults()
+	}
+	if err = flags.Parse(args); err != nil {
+		return
+	}
+	if flags.NArg() != n+1 {
+		flags.Usage()
+		err = flag.ErrHelp
+		return
+	}
+
+	tables := map[string]adminTable{
+		cfg.Topic.BalanceGroup:   {group: cfg.Topic.BalanceGroup, codec: wallet.NewWalletCodec()},
+		cfg.Topic.ThresholdGroup: {group: cfg.Topic.ThresholdGroup, codec: wallet.NewThresholdCodec()},
+	}
+	table, ok := tables[flags.Arg(0)]
+	if !ok {
+		err = fmt.Errorf("unknown table %q, the tables are %s and %s", flags.Arg(0), cfg.Topic.BalanceGroup, cfg.Topic.ThresholdGroup)
+		return
+	}
+	rest = flags.Args()[1:]
+	return
+}
+
+// openAdminView will open a view of table and wait until it is recovered.
+func openAdminView(ctx context.Context, logger *logrus.Logger, table adminTable) (view pubsub.ViewTable, err error) {
+	view, err = pubsub.NewGokaViewTableAdapter(logger, table.group, cfg.SaramaKafka.Addresses, cfg.SaramaKafka.Config, table.codec)
+	if err != nil {
+		return
+	}
+	view.Open()
+	if err = view.WaitRunning(ctx); err != nil {
+		view.Close()
+		return nil, err
+	}
+	return
+}
+
+// writeAdminTable will print the change of key and write it to the table topic when confirm is true, a nil value
+// deletes the key.
+func writeAdminTable(ctx context.Context, logger *logrus.Logger, table adminTable, key string, value interface{}, confirm bool) (err error) {
+	view, err := openAdminView(ctx, logger, table)
+	if err != nil {
+		return
+	}
+	current, err := view.Get(key)
+	view.Close()
+	if err != nil {
+		return
+	}
+	if err = printIndented(map[string]interface{}{"key": key, "current": current, "new": value}); err != nil {
+		return
+	}
+	if !confirm {
+		return errNotConfirmed
+	}
+
+	topic := string(goka.GroupTable(goka.Group(table.group)))
+	publisher, err := pubsub.NewGokaProducerAdapter(logger, cfg.SaramaKafka.Addresses, cfg.SaramaKafka.Config, topic, table.codec)
+	if err != nil {
+		return
+	}
+	defer publisher.Close()
+	position, err := publisher.Send(ctx, key, value)
+	if err != nil {
+		return
+	}
+	logger.Infof("Key %s of %s is written at partition %d offset %d", key, topic, position.Partition, position.Offset)
+	return
+}
+
+// walletIdOf returns the wallet id of a value of a table.
+func walletIdOf(value interface{}) string {
+	switch v := value.(type) {
+	case *entity.Wallet:
+		return v.WalletId
+	case *entity.Threshold:
+		return v.WalletId
+	}
+	return ""
+}
+
+func printIndented(value interface{}) (err error) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return
+	}
+	_, err = fmt.Println(string(data))
+	return
+}
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// APIKeyHeader is the header carrying the api key of a service caller.
+const APIKeyHeader = "X-API-Key"
+
+// Errors returned by Authenticate.
+var (
+	ErrMissingCredentials = errors.New("missing credentials")
+	ErrInvalidAPIKey      = errors.New("invalid api key")
+	ErrInvalidToken       = errors.New("invalid token")
+)
+
+// Authenticator authenticates service callers by api key and end users by jwt bearer token.
+type Authenticator struct {
+	apiKeys     []string
+	keys        map[string]crypto.PublicKey
+	issuer      string
+	audience    string
+	walletClaim string
+}
+
+// NewAuthenticator is a constructor.
+// keys are the public keys verifying the user tokens keyed by key id, the key with an empty id
+// verifies tokens without kid. issuer and audience are only checked when they are set.
+// walletClaim is the token claim listing the wallets owned by the user.
+func NewAuthenticator(apiKeys []string, keys map[string]crypto.PublicKey, issuer, audience, walletClaim string) *Authenticator {
+	return &Authenticator{
+		apiKeys:     apiKeys,
+		keys:        keys,
+		issuer:      issuer,
+		audience:    audience,
+		walletClaim: walletClaim,
+	}
+}
+
+// Enabled returns true when any api key or jwt key is configured.
+func (a *Authenticator) Enabled() bool {
+	return len(a.apiKeys) > 0 || len(a.keys) > 0
+}
+
+// Authenticate will authenticate the request by its X-API-Key header or its bearer token.
+func (a *Authenticator) Authenticate(r *http.Request) (principal Principal, err error) {
+	return a.AuthenticateHeader(r.Header)
+}
+
+// AuthenticateHeader will authenticate the X-API-Key or the bearer token of the headers,
+// it is used for the callers which are not http requests such as the grpc metadata.
+func (a *Authenticator) AuthenticateHeader(header http.Header) (principal Principal, err error) {
+	if apiKey := header.Get(APIKeyHeader); apiKey != "" {
+		return a.authenticateAPIKey(apiKey)
+	}
+
+	authorization := header.Get("Authorization")
+	if len(authorization) > len("Bearer ") && strings.EqualFold(authorization[:len("Bearer ")], "Bearer ") {
+		return a.authenticateToken(authorization[len("Bearer "):])
+	}
+
+	err = ErrMissingCredentials
+	return
+}
+
+func (a *Authenticator) authenticateAPIKey(apiKey string) (principal Principal, err error) {
+	// every key is compared so the time taken does not tell which key matched
+	matched := -1
+	for i, key := range a.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) == 1 {
+			matched = i
+		}
+	}
+	if matched < 0 {
+		err = ErrInvalidAPIKey
+		return
+	}
+	principal = Principal{Kind: KindService, Subject: fmt.Sprintf("api-key-%d", matched+1)}
+	return
+}
+
+func (a *Authenticator) authenticateToken(tokenString string) (principal Principal, err error) {
+	if len(a.keys) == 0 {
+		err = ErrInvalidToken
+		return
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(tokenString, claims, a.verificationKey)
+	if err != nil {
+		err = fmt.Errorf("%w: %v", ErrInvalidToken, err)
+		return
+	}
+	if !claims.VerifyExpiresAt(jwt.TimeFunc().Unix(), true) {
+		err = fmt.Errorf("%w: token has no expiration", ErrInvalidToken)
+		return
+	}
+	if a.issuer != "" && !claims.VerifyIssuer(a.issuer, true) {
+		err = fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+		return
+	}
+	if a.audience != "" && !claims.VerifyAudience(a.audience, true) {
+		err = fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+		return
+	}
+
+	// the subject is the client of the rate limits, the tokens without one would share its buckets
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		err = fmt.Errorf("%w: token has no subject", ErrInvalidToken)
+		return
+	}
+	principal = Principal{
+		Kind:    KindUser,
+		Subject: subject,
+		Wallets: stringList(claims[a.walletClaim]),
+	}
+	return
+}
+
+// verificationKey will find the key of the token, the signing method must match the key type.
+func (a *Authenticator) verificationKey(token *jwt.Token) (key interface{}, err error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := a.keys[kid]
+	if !ok {
+		err = fmt.Errorf("unknown key id '%s'", kid)
+		return
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey:
+		_, okRSA := token.Method.(*jwt.SigningMethodRSA)
+		_, okPSS := token.Method.(*jwt.SigningMethodRSAPSS)
+		ok = okRSA || okPSS
+	case *ecdsa.PublicKey:
+		_, ok = token.Method.(*jwt.SigningMethodECDSA)
+	case ed25519.PublicKey:
+		_, ok = token.Method.(*jwt.SigningMethodEd25519)
+	default:
+		ok = false
+	}
+	if !ok {
+		err = fmt.Errorf("signing method %s does not match the key", token.Method.Alg())
+	}
+	return
+}
+
+// stringList reads a claim holding a string or a list of strings.
+func stringList(claim interface{}) (list []string) {
+	switch value := claim.(type) {
+	case string:
+		list = []string{value}
+	case []interface{}:
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,188 @@
+package auth_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/ijalalfrz/coinbit-test/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func newToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	assert.Nil(t, err)
+	return signed
+}
+
+func newRequest(header, value string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/wallet/v1/details/1", nil)
+	if header != "" {
+		r.Header.Set(header, value)
+	}
+	return r
+}
+
+func TestAuthenticator_APIKey(t *testing.T) {
+	authenticator := auth.NewAuthenticator([]string{"key-1", "key-2"}, nil, "", "", "wallets")
+
+	t.Run("when api key is valid", func(t *testing.T) {
+		principal, err := authenticator.Authenticate(newRequest(auth.APIKeyHeader, "key-2"))
+
+		assert.Nil(t, err)
+		assert.Equal(t, auth.KindService, principal.Kind)
+		assert.Equal(t, "api-key-2", principal.Subject)
+		assert.True(t, principal.CanAccessWallet("any"))
+	})
+
+	t.Run("when api key is invalid", func(t *testing.T) {
+		_, err := authenticator.Authenticate(newRequest(auth.APIKeyHeader, "key-3"))
+
+		assert.ErrorIs(t, err, auth.ErrInvalidAPIKey)
+	})
+
+	t.Run("when there is no credentials", func(t *testing.T) {
+		_, err := authenticator.Authenticate(newRequest("", ""))
+
+		assert.ErrorIs(t, err, auth.ErrMissingCredentials)
+	})
+
+	t.Run("when bearer token is sent without jwt keys", func(t *testing.T) {
+		_, err := authenticator.Authenticate(newRequest("Authorization", "Bearer abc"))
+
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+	})
+
+	t.Run("when api key is sent as lowercase metadata", func(t *testing.T) {
+		header := http.Header{}
+		header.Add("x-api-key", "key-1")
+
+		principal, err := authenticator.AuthenticateHeader(header)
+
+		assert.Nil(t, err)
+		assert.Equal(t, auth.KindService, principal.Kind)
+	})
+}
+
+func TestAuthenticator_Token(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	keys := map[string]crypto.PublicKey{"user-key": &key.PublicKey}
+	authenticator := auth.NewAuthenticator(nil, keys, "https://issuer", "wallet-service", "wallets")
+	claims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub":     "user-1",
+			"iss":     "https://issuer",
+			"aud":     "wallet-service",
+			"exp":     time.Now().Add(time.Minute).Unix(),
+			"wallets": []string{"1", "2"},
+		}
+	}
+
+	t.Run("when token is valid", func(t *testing.T) {
+		token := newToken(t, key, "user-key", claims())
+
+		principal, err := authenticator.Authenticate(newRequest("Authorization", "Bearer "+token))
+
+		assert.Nil(t, err)
+		assert.Equal(t, auth.KindUser, principal.Kind)
+		assert.Equal(t, "user-1", principal.Subject)
+		assert.True(t, principal.CanAccessWallet("2"))
+		assert.False(t, principal.CanAccessWallet("3"))
+	})
+
+	t.Run("when token is rejected", func(t *testing.T) {
+		expired := claims()
+		expired["exp"] = time.Now().Add(-time.Minute).Unix()
+		noExpiration := claims()
+		delete(noExpiration, "exp")
+		wrongIssuer := claims()
+		wrongIssuer["iss"] = "https://other"
+		wrongAudience := claims()
+		wrongAudience["aud"] = "other-service"
+		noSubject := claims()
+		delete(noSubject, "sub")
+
+		tokens := map[string]string{
+			"expired":        newToken(t, key, "user-key", expired),
+			"no expiration":  newToken(t, key, "user-key", noExpiration),
+			"wrong issuer":   newToken(t, key, "user-key", wrongIssuer),
+			"wrong audience": newToken(t, key, "user-key", wrongAudience),
+			"no subject":     newToken(t, key, "user-key", noSubject),
+			"unknown kid":    newToken(t, key, "other-key", claims()),
+			"wrong key":      newToken(t, other, "user-key", claims()),
+			"hmac":           signHMAC(t, claims()),
+		}
+		for name, token := range tokens {
+			_, err := authenticator.Authenticate(newRequest("Authorization", "Bearer "+token))
+
+			assert.ErrorIs(t, err, auth.ErrInvalidToken, name)
+		}
+	})
+}
+
+// signHMAC signs the claims with the public modulus, a verifier trusting the alg header would accept it.
+func signHMAC(t *testing.T, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = "user-key"
+	signed, err := token.SignedString([]byte("user-key"))
+	assert.Nil(t, err)
+	return signed
+}
+
+func TestParseJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	n := base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+	e := base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+
+	t.Run("when jwks is valid", func(t *testing.T) {
+		jwks := fmt.Sprintf(`{"keys":[{"kty":"RSA","kid":"sig","use":"sig","n":"%s","e":"%s"},`+
+			`{"kty":"RSA","kid":"enc","use":"enc","n":"%s","e":"%s"}]}`, n, e, n, e)
+
+		keys, err := auth.ParseJWKS([]byte(jwks))
+
+		assert.Nil(t, err)
+		assert.Len(t, keys, 1)
+		assert.Equal(t, &key.PublicKey, keys["sig"])
+	})
+
+	t.Run("when jwks is invalid", func(t *testing.T) {
+		for _, jwks := range []string{`not json`, `{"keys":[]}`, `{"keys":[{"kty":"oct","k":"abc"}]}`} {
+			_, err := auth.ParseJWKS([]byte(jwks))
+
+			assert.Error(t, err, jwks)
+		}
+	})
+}
+
+func TestParsePublicKeyPEM(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.Nil(t, err)
+
+	parsed, err := auth.ParsePublicKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	assert.Nil(t, err)
+	assert.Equal(t, &key.PublicKey, parsed)
+
+	_, err = auth.ParsePublicKeyPEM([]byte("not a pem"))
+	assert.Error(t, err)
+}
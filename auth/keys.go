@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+)
+
+// jwk is a json web key, only the public key parameters are read.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS will parse a json web key set into public keys keyed by key id.
+// Keys which are not used for signature are skipped.
+func ParseJWKS(data []byte) (keys map[string]crypto.PublicKey, err error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err = json.Unmarshal(data, &set); err != nil {
+		err = fmt.Errorf("invalid jwks: %v", err)
+		return
+	}
+
+	keys = map[string]crypto.PublicKey{}
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, parseErr := k.publicKey()
+		if parseErr != nil {
+			err = fmt.Errorf("invalid jwks key %d (kid '%s'): %v", i, k.Kid, parseErr)
+			return
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		err = fmt.Errorf("jwks does not contain any signature key")
+	}
+	return
+}
+
+func (k jwk) publicKey() (key crypto.PublicKey, err error) {
+	switch k.Kty {
+	case "RSA":
+		n, nErr := decodeBigInt(k.N)
+		e, eErr := decodeBigInt(k.E)
+		if nErr != nil || eErr != nil || !e.IsInt64() {
+			err = fmt.Errorf("invalid RSA modulus or exponent")
+			return
+		}
+		key = &rsa.PublicKey{N: n, E: int(e.Int64())}
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			err = fmt.Errorf("unsupported curve '%s'", k.Crv)
+			return
+		}
+		x, xErr := decodeBigInt(k.X)
+		y, yErr := decodeBigInt(k.Y)
+		if xErr != nil || yErr != nil || !curve.IsOnCurve(x, y) {
+			err = fmt.Errorf("invalid EC point")
+			return
+		}
+		key = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+	case "OKP":
+		x, xErr := base64.RawURLEncoding.DecodeString(k.X)
+		if k.Crv != "Ed25519" || xErr != nil || len(x) != ed25519.PublicKeySize {
+			err = fmt.Errorf("invalid Ed25519 key")
+			return
+		}
+		key = ed25519.PublicKey(x)
+	default:
+		err = fmt.Errorf("unsupported key type '%s'", k.Kty)
+	}
+	return
+}
+
+func decodeBigInt(s string) (i *big.Int, err error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return
+	}
+	if len(b) == 0 {
+		err = fmt.Errorf("empty value")
+		return
+	}
+	i = new(big.Int).SetBytes(b)
+	return
+}
+
+// ParsePublicKeyPEM will parse a pem encoded public key or certificate.
+func ParsePublicKeyPEM(data []byte) (key crypto.PublicKey, err error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		err = fmt.Errorf("no pem block found")
+		return
+	}
+
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, certErr := x509.ParseCertificate(block.Bytes)
+		if certErr != nil {
+			err = certErr
+			return
+		}
+		key = cert.PublicKey
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	return
+}
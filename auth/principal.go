@@ -0,0 +1,44 @@
+package auth
+
+import "context"
+
+// Kinds of authenticated callers.
+const (
+	// KindService is a service caller authenticated by an api key, it can access every wallet.
+	KindService = "service"
+	// KindUser is an end user authenticated by a jwt bearer token, it can only read its own wallets.
+	KindUser = "user"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Kind    string
+	Subject string
+	Wallets []string
+}
+
+// CanAccessWallet returns true when the principal may read the wallet.
+func (p Principal) CanAccessWallet(walletId string) bool {
+	if p.Kind == KindService {
+		return true
+	}
+	for _, owned := range p.Wallets {
+		if owned == walletId {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// ContextWithPrincipal returns a copy of ctx holding the principal.
+func ContextWithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal stored in ctx, ok is false when the request is not authenticated.
+func PrincipalFromContext(ctx context.Context) (principal Principal, ok bool) {
+	principal, ok = ctx.Value(principalKey{}).(Principal)
+	return
+}
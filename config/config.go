@@ -1,21 +1,93 @@
 package config
 
 import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
 	"os"
 	"path"
+	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Shopify/sarama"
+	"github.com/ijalalfrz/coinbit-test/auth"
+	"github.com/ijalalfrz/coinbit-test/ratelimit"
+	"github.com/ijalalfrz/coinbit-test/server"
+	"github.com/lovoo/goka"
 	"github.com/sirupsen/logrus"
 )
 
+// default values of the configuration.
+const (
+	defaultAppName             = "wallet-service"
+	defaultPort                = "9000"
+	defaultGRPCPort            = "9001"
+	defaultBrokers             = "localhost:9092"
+	defaultConfigWatchInterval = 10 * time.Second
+	defaultThreshold           = 10000
+	defaultRollingPeriod       = 120
+	defaultConsistencyTimeout  = 5 * time.Second
+	defaultBatchLookupWorkers  = 16
+	defaultReplayTimeout       = 20 * time.Second
+	defaultStatementDays       = 93
+	defaultStatementMonths     = 24
+	defaultDepositTopic        = "deposits"
+	defaultCorrectionTopic     = "balance-corrections"
+	defaultBalanceGroup        = "balance"
+	defaultThresholdGroup      = "aboveThreshold"
+	defaultStatementGroup      = "statements"
+	defaultPartitions          = 1
+	defaultReplication         = 1
+	defaultStreamRetention     = 0
+	defaultStreamCleanupPolicy = "delete"
+	defaultTableCleanupPolicy  = "compact"
+	defaultMismatchBehavior    = "warn"
+	defaultJWTWalletClaim      = "wallets"
+	defaultStreamMaxConns      = 1000
+	defaultStreamKeepalive     = 15 * time.Second
+	defaultStreamMaxDuration   = 50 * time.Second
+	defaultStreamMaxWallets    = 500
+	maxTopicNameLength         = 249
+)
+
+var topicNamePattern = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// default rate limits of the wallet routes, a zero rate disables the limit.
+var (
+	// the ip limit is taken before the authentication
+	defaultIPRateLimit      = ratelimit.Limit{Rate: 50, Burst: 100}
+	defaultDepositRateLimit = ratelimit.Policy{
+		Client: ratelimit.Limit{Rate: 10, Burst: 20},
+		Wallet: ratelimit.Limit{Rate: 5, Burst: 10},
+	}
+	defaultDetailsRateLimit = ratelimit.Policy{
+		Client: ratelimit.Limit{Rate: 20, Burst: 40},
+	}
+	// listing scans the whole view
+	defaultListRateLimit = ratelimit.Policy{
+		Client: ratelimit.Limit{Rate: 1, Burst: 5},
+	}
+	// a stream holds a connection until it is closed
+	defaultStreamRateLimit = ratelimit.Policy{
+		Client: ratelimit.Limit{Rate: 1, Burst: 10},
+	}
+)
+
 // Config is an app configuration.
 type Config struct {
 	Application struct {
 		Port string
-		Name string
+		// GRPCPort serves the wallet service grpc api.
+		GRPCPort string
+		Name     string
+		// ConfigWatchInterval is how often the config file is checked for changes, 0 disables it.
+		ConfigWatchInterval time.Duration
 	}
 	Logger struct {
 		Formatter logrus.Formatter
@@ -24,45 +96,367 @@ type Config struct {
 		Addresses []string
 		Config    *sarama.Config
 	}
+	Topic struct {
+		Deposit        string
+		Correction     string
+		BalanceGroup   string
+		ThresholdGroup string
+		StatementGroup string
+		// Partitions is shared by the deposit and correction streams and the group tables
+		// because goka requires them to be copartitioned.
+		Partitions          int
+		StreamReplication   int
+		TableReplication    int
+		StreamRetention     time.Duration
+		StreamCleanupPolicy string
+		TableCleanupPolicy  string
+		MismatchBehavior    string
+	}
 	Wallet struct {
 		Threshold     int64
 		RollingPeriod int
+		// ConsistencyTimeout is how long wallet details wait for a deposit of the consistency token.
+		ConsistencyTimeout time.Duration
+		// BatchLookupWorkers is the number of concurrent view lookups of a batch details request.
+		BatchLookupWorkers int
+		// ReplayTimeout is how long a point-in-time balance query may replay the deposits stream.
+		ReplayTimeout time.Duration
+		// StatementDays and StatementMonths are the number of daily and monthly statements kept per wallet.
+		StatementDays   int
+		StatementMonths int
+	}
+	// Auth is enabled when any api key or jwt key is configured.
+	Auth struct {
+		APIKeys []string
+		// JWTKeys verify the user tokens, keyed by key id. The key of AUTH_JWT_PUBLIC_KEY_FILE has an empty id.
+		JWTKeys        map[string]crypto.PublicKey
+		JWTIssuer      string
+		JWTAudience    string
+		JWTWalletClaim string
 	}
+	Stream struct {
+		MaxConnections    int
+		KeepaliveInterval time.Duration
+		// MaxDuration must be shorter than the http write timeout, clients reconnect after it.
+		MaxDuration time.Duration
+		// MaxWalletsPerConnection is the cap of wallets subscribed on a websocket.
+		MaxWalletsPerConnection int
+	}
+	// RateLimit is the limits of the wallet routes.
+	RateLimit struct {
+		// IP limits the requests of every ip address before they are authenticated.
+		IP      ratelimit.Limit
+		Deposit ratelimit.Policy
+		Details ratelimit.Policy
+		List    ratelimit.Policy
+		Stream  ratelimit.Policy
+	}
+
+	// problems found while loading, they are reported by Validate.
+	problems []string
+	// file is the values read from the config file keyed by environment variable name.
+	file map[string]string
+	// effective is the resolved values keyed by environment variable name.
+	effective map[string]string
 }
 
-// Load will load the configuration.
+// Load will load the configuration from the file on CONFIG_FILE (if any) and the environment variables.
 func Load() *Config {
+	return LoadFile(os.Getenv("CONFIG_FILE"))
+}
+
+// LoadFile will load the configuration from a yaml file, environment variables override the file values.
+// An empty path only loads the environment variables.
+func LoadFile(path string) *Config {
 	cfg := new(Config)
+	cfg.effective = map[string]string{}
+	cfg.readFile(path)
 	cfg.sarama()
+	cfg.topic()
 	cfg.logFormatter()
 	cfg.wallet()
+	cfg.auth()
+	cfg.rateLimit()
+	cfg.stream()
 	cfg.app()
 	return cfg
 }
 
+// WalletRule is the part of the wallet configuration which is reloaded without restarting.
+type WalletRule struct {
+	Threshold     int64
+	RollingPeriod int
+}
+
+// LoadWalletRule will load and validate only the wallet rule from the yaml file and the environment variables,
+// the other settings are not loaded so a reload never reads the key files again. The error names every
+// setting which blocks the reload.
+func LoadWalletRule(path string) (rule WalletRule, err error) {
+	cfg := new(Config)
+	cfg.effective = map[string]string{}
+	cfg.readFile(path)
+	cfg.walletRule()
+
+	problems := append(append([]string{}, cfg.problems...), cfg.walletRuleProblems()...)
+	if len(problems) > 0 {
+		err = fmt.Errorf("Invalid wallet rule:\n - %s", strings.Join(problems, "\n - "))
+		return
+	}
+	rule = WalletRule{Threshold: cfg.Wallet.Threshold, RollingPeriod: cfg.Wallet.RollingPeriod}
+	return
+}
+
+// Validate will check the loaded configuration and return every problem found.
+func (cfg *Config) Validate() (err error) {
+	problems := append([]string{}, cfg.problems...)
+
+	if port, convErr := strconv.Atoi(cfg.Application.Port); convErr != nil || port < 1 || port > 65535 {
+		problems = append(problems, fmt.Sprintf("PORT must be a number between 1 and 65535, got '%s'", cfg.Application.Port))
+	}
+	if port, convErr := strconv.Atoi(cfg.Application.GRPCPort); convErr != nil || port < 1 || port > 65535 {
+		problems = append(problems, fmt.Sprintf("GRPC_PORT must be a number between 1 and 65535, got '%s'", cfg.Application.GRPCPort))
+	} else if cfg.Application.GRPCPort == cfg.Application.Port {
+		problems = append(problems, fmt.Sprintf("GRPC_PORT must not be the same as PORT, got '%s'", cfg.Application.GRPCPort))
+	}
+	if cfg.Application.ConfigWatchInterval < 0 {
+		problems = append(problems, fmt.Sprintf("CONFIG_WATCH_INTERVAL must not be negative, got '%s'", cfg.Application.ConfigWatchInterval))
+	}
+	for _, address := range cfg.SaramaKafka.Addresses {
+		if _, _, splitErr := net.SplitHostPort(address); splitErr != nil {
+			problems = append(problems, fmt.Sprintf("KAFKA_BROKERS must be a list of host:port, got '%s'", address))
+		}
+	}
+	problems = append(problems, cfg.walletRuleProblems()...)
+	if cfg.Wallet.ConsistencyTimeout <= 0 {
+		problems = append(problems, fmt.Sprintf("CONSISTENCY_TIMEOUT must be a positive duration, got '%s'", cfg.Wallet.ConsistencyTimeout))
+	}
+	if cfg.Wallet.BatchLookupWorkers < 1 {
+		problems = append(problems, fmt.Sprintf("BATCH_LOOKUP_WORKERS must be at least 1, got %d", cfg.Wallet.BatchLookupWorkers))
+	}
+	if cfg.Wallet.StatementDays < 1 {
+		problems = append(problems, fmt.Sprintf("STATEMENT_DAYS must be at least 1, got %d", cfg.Wallet.StatementDays))
+	}
+	if cfg.Wallet.StatementMonths < 1 {
+		problems = append(problems, fmt.Sprintf("STATEMENT_MONTHS must be at least 1, got %d", cfg.Wallet.StatementMonths))
+	}
+	if cfg.Wallet.ReplayTimeout <= 0 || cfg.Wallet.ReplayTimeout >= server.WriteTimeout {
+		problems = append(problems, fmt.Sprintf("REPLAY_TIMEOUT must be a positive duration shorter than %s, got '%s'", server.WriteTimeout, cfg.Wallet.ReplayTimeout))
+	}
+
+	topics := [][2]string{
+		{"KAFKA_DEPOSIT_TOPIC", cfg.Topic.Deposit},
+		{"KAFKA_CORRECTION_TOPIC", cfg.Topic.Correction},
+		{"KAFKA_BALANCE_GROUP", cfg.Topic.BalanceGroup},
+		{"KAFKA_THRESHOLD_GROUP", cfg.Topic.ThresholdGroup},
+		{"KAFKA_STATEMENT_GROUP", cfg.Topic.StatementGroup},
+	}
+	for _, topic := range topics {
+		if !topicNamePattern.MatchString(topic[1]) || len(topic[1]) > maxTopicNameLength {
+			problems = append(problems, fmt.Sprintf("%s must be a valid kafka topic name, got '%s'", topic[0], topic[1]))
+		}
+	}
+	if cfg.Topic.BalanceGroup == cfg.Topic.ThresholdGroup || cfg.Topic.BalanceGroup == cfg.Topic.StatementGroup ||
+		cfg.Topic.ThresholdGroup == cfg.Topic.StatementGroup {
+		problems = append(problems, "KAFKA_BALANCE_GROUP, KAFKA_THRESHOLD_GROUP and KAFKA_STATEMENT_GROUP must be different")
+	}
+	if cfg.Topic.Partitions < 1 {
+		problems = append(problems, fmt.Sprintf("KAFKA_PARTITIONS must be at least 1, got %d", cfg.Topic.Partitions))
+	}
+	if cfg.Topic.StreamReplication < 1 {
+		problems = append(problems, fmt.Sprintf("KAFKA_STREAM_REPLICATION must be at least 1, got %d", cfg.Topic.StreamReplication))
+	}
+	if cfg.Topic.TableReplication < 1 {
+		problems = append(problems, fmt.Sprintf("KAFKA_TABLE_REPLICATION must be at least 1, got %d", cfg.Topic.TableReplication))
+	}
+	if cfg.Topic.StreamRetention < 0 {
+		problems = append(problems, fmt.Sprintf("KAFKA_STREAM_RETENTION must not be negative, got '%s'", cfg.Topic.StreamRetention))
+	}
+	if !validCleanupPolicy(cfg.Topic.StreamCleanupPolicy) {
+		problems = append(problems, fmt.Sprintf("KAFKA_STREAM_CLEANUP_POLICY must be delete, compact or compact,delete, got '%s'", cfg.Topic.StreamCleanupPolicy))
+	}
+	// group tables are the state of the processors, they must never lose the latest value of a key.
+	if !validCleanupPolicy(cfg.Topic.TableCleanupPolicy) || !strings.Contains(cfg.Topic.TableCleanupPolicy, "compact") {
+		problems = append(problems, fmt.Sprintf("KAFKA_TABLE_CLEANUP_POLICY must be compact or compact,delete, got '%s'", cfg.Topic.TableCleanupPolicy))
+	}
+	switch cfg.Topic.MismatchBehavior {
+	case "ignore", "warn", "fail":
+	default:
+		problems = append(problems, fmt.Sprintf("KAFKA_TOPIC_MISMATCH must be ignore, warn or fail, got '%s'", cfg.Topic.MismatchBehavior))
+	}
+
+	if len(cfg.Auth.JWTKeys) > 0 && cfg.Auth.JWTWalletClaim == "" {
+		problems = append(problems, "AUTH_JWT_WALLET_CLAIM must not be empty when jwt keys are configured")
+	}
+
+	if cfg.Stream.MaxConnections < 1 {
+		problems = append(problems, fmt.Sprintf("STREAM_MAX_CONNECTIONS must be at least 1, got %d", cfg.Stream.MaxConnections))
+	}
+	if cfg.Stream.MaxWalletsPerConnection < 1 {
+		problems = append(problems, fmt.Sprintf("STREAM_MAX_WALLETS_PER_CONNECTION must be at least 1, got %d", cfg.Stream.MaxWalletsPerConnection))
+	}
+	if cfg.Stream.KeepaliveInterval <= 0 {
+		problems = append(problems, fmt.Sprintf("STREAM_KEEPALIVE_INTERVAL must be a positive duration, got '%s'", cfg.Stream.KeepaliveInterval))
+	}
+	if cfg.Stream.MaxDuration <= 0 || cfg.Stream.MaxDuration >= server.WriteTimeout {
+		problems = append(problems, fmt.Sprintf("STREAM_MAX_DURATION must be a positive duration shorter than %s, got '%s'", server.WriteTimeout, cfg.Stream.MaxDuration))
+	}
+
+	limits := []struct {
+		prefix string
+		limit  ratelimit.Limit
+	}{
+		{"RATE_LIMIT_IP", cfg.RateLimit.IP},
+		{"RATE_LIMIT_DEPOSIT_CLIENT", cfg.RateLimit.Deposit.Client},
+		{"RATE_LIMIT_DEPOSIT_WALLET", cfg.RateLimit.Deposit.Wallet},
+		{"RATE_LIMIT_DETAILS_CLIENT", cfg.RateLimit.Details.Client},
+		{"RATE_LIMIT_DETAILS_WALLET", cfg.RateLimit.Details.Wallet},
+		{"RATE_LIMIT_LIST_CLIENT", cfg.RateLimit.List.Client},
+		{"RATE_LIMIT_LIST_WALLET", cfg.RateLimit.List.Wallet},
+		{"RATE_LIMIT_STREAM_CLIENT", cfg.RateLimit.Stream.Client},
+		{"RATE_LIMIT_STREAM_WALLET", cfg.RateLimit.Stream.Wallet},
+	}
+	for _, l := range limits {
+		if l.limit.Rate < 0 || l.limit.Burst < 0 || (l.limit.Rate > 0 && l.limit.Burst == 0) {
+			problems = append(problems, fmt.Sprintf("%s_RATE must not be negative and %s_BURST must be at least 1 when the rate is set, got %g and %d",
+				l.prefix, l.prefix, l.limit.Rate, l.limit.Burst))
+		}
+	}
+
+	if cfg.SaramaKafka.Config != nil {
+		if saramaErr := cfg.SaramaKafka.Config.Validate(); saramaErr != nil {
+			problems = append(problems, fmt.Sprintf("Kafka client configuration is invalid: %v", saramaErr))
+		}
+	}
+
+	if len(problems) > 0 {
+		err = fmt.Errorf("Invalid configuration:\n - %s", strings.Join(problems, "\n - "))
+	}
+	return
+}
+
+func (cfg *Config) walletRuleProblems() (problems []string) {
+	if cfg.Wallet.Threshold <= 0 {
+		problems = append(problems, fmt.Sprintf("THRESHOLD must be greater than 0, got %d", cfg.Wallet.Threshold))
+	}
+	if cfg.Wallet.RollingPeriod <= 0 {
+		problems = append(problems, fmt.Sprintf("ROLLING_PERIOD must be greater than 0, got %d", cfg.Wallet.RollingPeriod))
+	}
+	return
+}
+
+func validCleanupPolicy(policy string) bool {
+	switch policy {
+	case "delete", "compact", "compact,delete", "delete,compact":
+		return true
+	}
+	return false
+}
+
 func (cfg *Config) sarama() {
-	brokers := os.Getenv("KAFKA_BROKERS")
-	// sslEnable, _ := strconv.ParseBool(os.Getenv("KAFKA_SSL_ENABLE"))
-	// username := os.Getenv("KAFKA_USERNAME")
-	// password := os.Getenv("KAFKA_PASSWORD")
-
-	// sc := sarama.NewConfig()
-	// sc.Version = sarama.V2_1_0_0
-	// if username != "" {
-	// 	sc.Net.SASL.User = username
-	// 	sc.Net.SASL.Password = password
-	// 	sc.Net.SASL.Enable = true
-	// }
-	// sc.Net.TLS.Enable = sslEnable
-
-	// // consumer config
-	// sc.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRoundRobin
-	// sc.Consumer.Offsets.Initial = sarama.OffsetOldest
-
-	// // producer config
-	// sc.Producer.Retry.Backoff = time.Millisecond * 500
+	brokers := cfg.value("KAFKA_BROKERS", defaultBrokers)
+	username := cfg.value("KAFKA_USERNAME", "")
+	password := cfg.value("KAFKA_PASSWORD", "")
+	mechanism := cfg.value("KAFKA_SASL_MECHANISM", sarama.SASLTypePlaintext)
+	tlsEnable := cfg.boolValue("KAFKA_TLS_ENABLE", false)
+	tlsInsecureSkipVerify := cfg.boolValue("KAFKA_TLS_INSECURE_SKIP_VERIFY", false)
+
+	// goka default config keeps the copartitioning rebalance strategy required by the processors.
+	sc := goka.DefaultConfig()
+	if version := cfg.value("KAFKA_VERSION", ""); version != "" {
+		v, err := sarama.ParseKafkaVersion(version)
+		if err != nil {
+			cfg.problems = append(cfg.problems, fmt.Sprintf("KAFKA_VERSION is invalid: %v", err))
+		}
+		sc.Version = v
+	}
+
+	if username != "" {
+		sc.Net.SASL.Enable = true
+		sc.Net.SASL.User = username
+		sc.Net.SASL.Password = password
+		sc.Net.SASL.Handshake = true
+		switch mechanism {
+		case sarama.SASLTypePlaintext:
+			sc.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+		case sarama.SASLTypeSCRAMSHA256:
+			sc.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+			sc.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+				return &scramClient{HashGeneratorFcn: sha256Generator}
+			}
+		case sarama.SASLTypeSCRAMSHA512:
+			sc.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+			sc.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+				return &scramClient{HashGeneratorFcn: sha512Generator}
+			}
+		default:
+			cfg.problems = append(cfg.problems, fmt.Sprintf("KAFKA_SASL_MECHANISM must be PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512, got '%s'", mechanism))
+		}
+	}
+
+	if tlsEnable {
+		tlsConfig, err := loadTLSConfig(
+			cfg.value("KAFKA_TLS_CA_FILE", ""),
+			cfg.value("KAFKA_TLS_CERT_FILE", ""),
+			cfg.value("KAFKA_TLS_KEY_FILE", ""),
+		)
+		if err != nil {
+			cfg.problems = append(cfg.problems, err.Error())
+		}
+		tlsConfig.InsecureSkipVerify = tlsInsecureSkipVerify
+		sc.Net.TLS.Enable = true
+		sc.Net.TLS.Config = tlsConfig
+	}
+
+	// producer config
+	sc.Producer.Retry.Backoff = time.Millisecond * 500
 
 	cfg.SaramaKafka.Addresses = strings.Split(brokers, ",")
+	cfg.SaramaKafka.Config = sc
+}
+
+// loadTLSConfig will build the tls config from the given pem files, every file is optional.
+func loadTLSConfig(caFile, certFile, keyFile string) (tlsConfig *tls.Config, err error) {
+	tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if caFile != "" {
+		ca, readErr := ioutil.ReadFile(caFile)
+		if readErr != nil {
+			err = fmt.Errorf("KAFKA_TLS_CA_FILE can not be read: %v", readErr)
+			return
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			err = fmt.Errorf("KAFKA_TLS_CA_FILE does not contain any pem certificate")
+			return
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, loadErr := tls.LoadX509KeyPair(certFile, keyFile)
+		if loadErr != nil {
+			err = fmt.Errorf("KAFKA_TLS_CERT_FILE and KAFKA_TLS_KEY_FILE can not be loaded: %v", loadErr)
+			return
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return
+}
+
+func (cfg *Config) topic() {
+	cfg.Topic.Deposit = cfg.value("KAFKA_DEPOSIT_TOPIC", defaultDepositTopic)
+	cfg.Topic.Correction = cfg.value("KAFKA_CORRECTION_TOPIC", defaultCorrectionTopic)
+	cfg.Topic.BalanceGroup = cfg.value("KAFKA_BALANCE_GROUP", defaultBalanceGroup)
+	cfg.Topic.ThresholdGroup = cfg.value("KAFKA_THRESHOLD_GROUP", defaultThresholdGroup)
+	cfg.Topic.StatementGroup = cfg.value("KAFKA_STATEMENT_GROUP", defaultStatementGroup)
+	cfg.Topic.Partitions = cfg.intValue("KAFKA_PARTITIONS", defaultPartitions)
+	cfg.Topic.StreamReplication = cfg.intValue("KAFKA_STREAM_REPLICATION", defaultReplication)
+	cfg.Topic.TableReplication = cfg.intValue("KAFKA_TABLE_REPLICATION", defaultReplication)
+	cfg.Topic.StreamRetention = cfg.durationValue("KAFKA_STREAM_RETENTION", defaultStreamRetention)
+	cfg.Topic.StreamCleanupPolicy = cfg.value("KAFKA_STREAM_CLEANUP_POLICY", defaultStreamCleanupPolicy)
+	cfg.Topic.TableCleanupPolicy = cfg.value("KAFKA_TABLE_CLEANUP_POLICY", defaultTableCleanupPolicy)
+	cfg.Topic.MismatchBehavior = cfg.value("KAFKA_TOPIC_MISMATCH", defaultMismatchBehavior)
 }
 
 func (cfg *Config) logFormatter() {
@@ -80,17 +474,109 @@ func (cfg *Config) logFormatter() {
 }
 
 func (cfg *Config) app() {
-	appName := os.Getenv("APP_NAME")
-	port := os.Getenv("PORT")
+	cfg.Application.Port = cfg.value("PORT", defaultPort)
+	cfg.Application.GRPCPort = cfg.value("GRPC_PORT", defaultGRPCPort)
+	cfg.Application.Name = cfg.value("APP_NAME", defaultAppName)
+	cfg.Application.ConfigWatchInterval = cfg.durationValue("CONFIG_WATCH_INTERVAL", defaultConfigWatchInterval)
+}
 
-	cfg.Application.Port = port
-	cfg.Application.Name = appName
+func (cfg *Config) auth() {
+	cfg.Auth.APIKeys = nil
+	for _, key := range strings.Split(cfg.value("AUTH_API_KEYS", ""), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			cfg.Auth.APIKeys = append(cfg.Auth.APIKeys, key)
+		}
+	}
+	cfg.Auth.JWTIssuer = cfg.value("AUTH_JWT_ISSUER", "")
+	cfg.Auth.JWTAudience = cfg.value("AUTH_JWT_AUDIENCE", "")
+	cfg.Auth.JWTWalletClaim = cfg.value("AUTH_JWT_WALLET_CLAIM", defaultJWTWalletClaim)
+
+	keys, err := loadJWTKeys(cfg.value("AUTH_JWKS_FILE", ""), cfg.value("AUTH_JWT_PUBLIC_KEY_FILE", ""))
+	if err != nil {
+		cfg.problems = append(cfg.problems, err.Error())
+	}
+	cfg.Auth.JWTKeys = keys
 }
 
-func (cfg *Config) wallet() {
-	threshold, _ := strconv.ParseInt(os.Getenv("THRESHOLD"), 10, 64)
-	rollingPeriod, _ := strconv.Atoi(os.Getenv("ROLLING_PERIOD"))
+// loadJWTKeys will read the keys of the jwks file and the pem public key file, every file is optional.
+func loadJWTKeys(jwksFile, publicKeyFile string) (keys map[string]crypto.PublicKey, err error) {
+	keys = map[string]crypto.PublicKey{}
+
+	if jwksFile != "" {
+		content, readErr := ioutil.ReadFile(jwksFile)
+		if readErr != nil {
+			err = fmt.Errorf("AUTH_JWKS_FILE can not be read: %v", readErr)
+			return
+		}
+		set, parseErr := auth.ParseJWKS(content)
+		if parseErr != nil {
+			err = fmt.Errorf("AUTH_JWKS_FILE is invalid: %v", parseErr)
+			return
+		}
+		for kid, key := range set {
+			keys[kid] = key
+		}
+	}
+
+	if publicKeyFile != "" {
+		content, readErr := ioutil.ReadFile(publicKeyFile)
+		if readErr != nil {
+			err = fmt.Errorf("AUTH_JWT_PUBLIC_KEY_FILE can not be read: %v", readErr)
+			return
+		}
+		key, parseErr := auth.ParsePublicKeyPEM(content)
+		if parseErr != nil {
+			err = fmt.Errorf("AUTH_JWT_PUBLIC_KEY_FILE is invalid: %v", parseErr)
+			return
+		}
+		keys[""] = key
+	}
+
+	return
+}
 
-	cfg.Wallet.RollingPeriod = rollingPeriod
-	cfg.Wallet.Threshold = threshold
+func (cfg *Config) stream() {
+	cfg.Stream.MaxConnections = cfg.intValue("STREAM_MAX_CONNECTIONS", defaultStreamMaxConns)
+	cfg.Stream.KeepaliveInterval = cfg.durationValue("STREAM_KEEPALIVE_INTERVAL", defaultStreamKeepalive)
+	cfg.Stream.MaxDuration = cfg.durationValue("STREAM_MAX_DURATION", defaultStreamMaxDuration)
+	cfg.Stream.MaxWalletsPerConnection = cfg.intValue("STREAM_MAX_WALLETS_PER_CONNECTION", defaultStreamMaxWallets)
+}
+
+func (cfg *Config) rateLimit() {
+	cfg.RateLimit.IP = ratelimit.Limit{
+		Rate:  cfg.floatValue("RATE_LIMIT_IP_RATE", defaultIPRateLimit.Rate),
+		Burst: cfg.intValue("RATE_LIMIT_IP_BURST", defaultIPRateLimit.Burst),
+	}
+	cfg.RateLimit.Deposit = cfg.rateLimitPolicy("DEPOSIT", defaultDepositRateLimit)
+	cfg.RateLimit.Details = cfg.rateLimitPolicy("DETAILS", defaultDetailsRateLimit)
+	cfg.RateLimit.List = cfg.rateLimitPolicy("LIST", defaultListRateLimit)
+	cfg.RateLimit.Stream = cfg.rateLimitPolicy("STREAM", defaultStreamRateLimit)
+}
+
+func (cfg *Config) rateLimitPolicy(route string, defaultPolicy ratelimit.Policy) ratelimit.Policy {
+	return ratelimit.Policy{
+		Name: strings.ToLower(route),
+		Client: ratelimit.Limit{
+			Rate:  cfg.floatValue("RATE_LIMIT_"+route+"_CLIENT_RATE", defaultPolicy.Client.Rate),
+			Burst: cfg.intValue("RATE_LIMIT_"+route+"_CLIENT_BURST", defaultPolicy.Client.Burst),
+		},
+		Wallet: ratelimit.Limit{
+			Rate:  cfg.floatValue("RATE_LIMIT_"+route+"_WALLET_RATE", defaultPolicy.Wallet.Rate),
+			Burst: cfg.intValue("RATE_LIMIT_"+route+"_WALLET_BURST", defaultPolicy.Wallet.Burst),
+		},
+	}
+}
+
+func (cfg *Config) walletRule() {
+	cfg.Wallet.RollingPeriod = cfg.intValue("ROLLING_PERIOD", defaultRollingPeriod)
+	cfg.Wallet.Threshold = cfg.int64Value("THRESHOLD", defaultThreshold)
+}
+
+func (cfg *Config) wallet() {
+	cfg.walletRule()
+	cfg.Wallet.ConsistencyTimeout = cfg.durationValue("CONSISTENCY_TIMEOUT", defaultConsistencyTimeout)
+	cfg.Wallet.BatchLookupWorkers = cfg.intValue("BATCH_LOOKUP_WORKERS", defaultBatchLookupWorkers)
+	cfg.Wallet.ReplayTimeout = cfg.durationValue("REPLAY_TIMEOUT", defaultReplayTimeout)
+	cfg.Wallet.StatementDays = cfg.intValue("STATEMENT_DAYS", defaultStatementDays)
+	cfg.Wallet.StatementMonths = cfg.intValue("STATEMENT_MONTHS", defaultStatementMonths)
 }
@@ -1,16 +1,23 @@
 package config_test
 
 import (
+	"bytes"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/Shopify/sarama"
 	"github.com/ijalalfrz/coinbit-test/config"
+	"github.com/ijalalfrz/coinbit-test/ratelimit"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestConfig(t *testing.T) {
 	os.Setenv("KAFKA_USERNAME", "test_username")
+	defer os.Unsetenv("KAFKA_USERNAME")
 	cfg := config.Load()
 
 	assert.NotNil(t, cfg)
@@ -24,3 +31,315 @@ func TestConfig(t *testing.T) {
 	})
 
 }
+
+func TestConfig_Topic_Default(t *testing.T) {
+	cfg := config.Load()
+
+	assert.Equal(t, "deposits", cfg.Topic.Deposit)
+	assert.Equal(t, "balance", cfg.Topic.BalanceGroup)
+	assert.Equal(t, "aboveThreshold", cfg.Topic.ThresholdGroup)
+	assert.Equal(t, 1, cfg.Topic.Partitions)
+	assert.Equal(t, 1, cfg.Topic.StreamReplication)
+	assert.Equal(t, 1, cfg.Topic.TableReplication)
+	assert.Equal(t, time.Duration(0), cfg.Topic.StreamRetention)
+	assert.Nil(t, cfg.Validate(), "default configuration should be valid")
+}
+
+func TestConfig_Topic_FromEnv(t *testing.T) {
+	os.Setenv("KAFKA_DEPOSIT_TOPIC", "wallet.deposits")
+	os.Setenv("KAFKA_PARTITIONS", "12")
+	os.Setenv("KAFKA_STREAM_REPLICATION", "3")
+	os.Setenv("KAFKA_TABLE_REPLICATION", "3")
+	os.Setenv("KAFKA_STREAM_RETENTION", "168h")
+	defer os.Unsetenv("KAFKA_DEPOSIT_TOPIC")
+	defer os.Unsetenv("KAFKA_PARTITIONS")
+	defer os.Unsetenv("KAFKA_STREAM_REPLICATION")
+	defer os.Unsetenv("KAFKA_TABLE_REPLICATION")
+	defer os.Unsetenv("KAFKA_STREAM_RETENTION")
+
+	cfg := config.Load()
+
+	assert.Equal(t, "wallet.deposits", cfg.Topic.Deposit)
+	assert.Equal(t, 12, cfg.Topic.Partitions)
+	assert.Equal(t, 3, cfg.Topic.StreamReplication)
+	assert.Equal(t, 3, cfg.Topic.TableReplication)
+	assert.Equal(t, 168*time.Hour, cfg.Topic.StreamRetention)
+	assert.Nil(t, cfg.Validate())
+}
+
+func TestConfig_Validate_Error(t *testing.T) {
+	os.Setenv("KAFKA_PARTITIONS", "many")
+	os.Setenv("KAFKA_THRESHOLD_GROUP", "balance")
+	os.Setenv("KAFKA_TABLE_CLEANUP_POLICY", "delete")
+	defer os.Unsetenv("KAFKA_PARTITIONS")
+	defer os.Unsetenv("KAFKA_THRESHOLD_GROUP")
+	defer os.Unsetenv("KAFKA_TABLE_CLEANUP_POLICY")
+
+	cfg := config.Load()
+	err := cfg.Validate()
+
+	assert.Error(t, err, "should be error")
+	assert.Contains(t, err.Error(), "KAFKA_PARTITIONS")
+	assert.Contains(t, err.Error(), "KAFKA_THRESHOLD_GROUP")
+	assert.Contains(t, err.Error(), "KAFKA_TABLE_CLEANUP_POLICY")
+}
+
+func TestConfig_Validate_GRPCPort(t *testing.T) {
+	os.Setenv("GRPC_PORT", "9000")
+	defer os.Unsetenv("GRPC_PORT")
+
+	err := config.Load().Validate()
+
+	assert.Error(t, err, "should be error")
+	assert.Contains(t, err.Error(), "GRPC_PORT must not be the same as PORT")
+}
+
+func TestConfig_Sarama_SASL(t *testing.T) {
+	t.Run("when mechanism is scram", func(t *testing.T) {
+		os.Setenv("KAFKA_USERNAME", "wallet")
+		os.Setenv("KAFKA_PASSWORD", "secret")
+		os.Setenv("KAFKA_SASL_MECHANISM", "SCRAM-SHA-512")
+		defer os.Unsetenv("KAFKA_USERNAME")
+		defer os.Unsetenv("KAFKA_PASSWORD")
+		defer os.Unsetenv("KAFKA_SASL_MECHANISM")
+
+		cfg := config.Load()
+		sc := cfg.SaramaKafka.Config
+
+		assert.True(t, sc.Net.SASL.Enable)
+		assert.Equal(t, "wallet", sc.Net.SASL.User)
+		assert.Equal(t, "secret", sc.Net.SASL.Password)
+		assert.Equal(t, sarama.SASLMechanism(sarama.SASLTypeSCRAMSHA512), sc.Net.SASL.Mechanism)
+		assert.NotNil(t, sc.Net.SASL.SCRAMClientGeneratorFunc())
+		assert.Nil(t, cfg.Validate())
+	})
+
+	t.Run("when mechanism is unknown", func(t *testing.T) {
+		os.Setenv("KAFKA_USERNAME", "wallet")
+		os.Setenv("KAFKA_SASL_MECHANISM", "KERBEROS")
+		defer os.Unsetenv("KAFKA_USERNAME")
+		defer os.Unsetenv("KAFKA_SASL_MECHANISM")
+
+		err := config.Load().Validate()
+
+		assert.Error(t, err, "should be error")
+		assert.Contains(t, err.Error(), "KAFKA_SASL_MECHANISM")
+	})
+}
+
+func TestConfig_Sarama_TLS(t *testing.T) {
+	t.Run("when tls is enabled without certificates", func(t *testing.T) {
+		os.Setenv("KAFKA_TLS_ENABLE", "true")
+		defer os.Unsetenv("KAFKA_TLS_ENABLE")
+
+		cfg := config.Load()
+
+		assert.True(t, cfg.SaramaKafka.Config.Net.TLS.Enable)
+		assert.NotNil(t, cfg.SaramaKafka.Config.Net.TLS.Config)
+		assert.Nil(t, cfg.Validate())
+	})
+
+	t.Run("when ca file does not exist", func(t *testing.T) {
+		os.Setenv("KAFKA_TLS_ENABLE", "true")
+		os.Setenv("KAFKA_TLS_CA_FILE", "/not/exist/ca.pem")
+		defer os.Unsetenv("KAFKA_TLS_ENABLE")
+		defer os.Unsetenv("KAFKA_TLS_CA_FILE")
+
+		err := config.Load().Validate()
+
+		assert.Error(t, err, "should be error")
+		assert.Contains(t, err.Error(), "KAFKA_TLS_CA_FILE")
+	})
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := ioutil.WriteFile(path, []byte(content), 0600)
+	assert.Nil(t, err)
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	t.Run("when example file is loaded", func(t *testing.T) {
+		cfg := config.LoadFile("../config.example.yaml")
+
+		assert.Equal(t, "wallet-service", cfg.Application.Name)
+		assert.Equal(t, []string{"localhost:9092"}, cfg.SaramaKafka.Addresses)
+		assert.Equal(t, int64(10000), cfg.Wallet.Threshold)
+		assert.Nil(t, cfg.Validate())
+	})
+
+	t.Run("when environment variable overrides the file", func(t *testing.T) {
+		path := writeConfigFile(t, "wallet:\n  threshold: 500\n  rolling_period: 60\n")
+		os.Setenv("THRESHOLD", "700")
+		defer os.Unsetenv("THRESHOLD")
+
+		cfg := config.LoadFile(path)
+
+		assert.Equal(t, int64(700), cfg.Wallet.Threshold)
+		assert.Equal(t, 60, cfg.Wallet.RollingPeriod)
+	})
+
+	t.Run("when file has unknown key and invalid value", func(t *testing.T) {
+		path := writeConfigFile(t, "wallet:\n  treshold: 500\n  rolling_period: soon\n")
+
+		err := config.LoadFile(path).Validate()
+
+		assert.Error(t, err, "should be error")
+		assert.Contains(t, err.Error(), "unknown key 'wallet.treshold'")
+		assert.Contains(t, err.Error(), "ROLLING_PERIOD must be an integer")
+	})
+
+	t.Run("when file does not exist", func(t *testing.T) {
+		err := config.LoadFile("/not/exist/config.yaml").Validate()
+
+		assert.Error(t, err, "should be error")
+		assert.Contains(t, err.Error(), "Config file can not be read")
+	})
+}
+
+func TestLoadWalletRule(t *testing.T) {
+	t.Run("when wallet rule is valid", func(t *testing.T) {
+		path := writeConfigFile(t, "wallet:\n  threshold: 500\n  rolling_period: 60\n")
+
+		rule, err := config.LoadWalletRule(path)
+
+		assert.Nil(t, err)
+		assert.Equal(t, config.WalletRule{Threshold: 500, RollingPeriod: 60}, rule)
+	})
+
+	t.Run("when other settings are invalid", func(t *testing.T) {
+		path := writeConfigFile(t, "wallet:\n  threshold: 500\nauth:\n  jwt:\n    jwks_file: /not/exist/jwks.json\napplication:\n  port: abc\n")
+
+		rule, err := config.LoadWalletRule(path)
+
+		assert.Nil(t, err, "should not load the other settings")
+		assert.Equal(t, int64(500), rule.Threshold)
+	})
+
+	t.Run("when wallet rule is invalid", func(t *testing.T) {
+		path := writeConfigFile(t, "wallet:\n  threshold: -1\n  rolling_period: 60\n")
+
+		_, err := config.LoadWalletRule(path)
+
+		assert.Error(t, err, "should be error")
+		assert.Contains(t, err.Error(), "THRESHOLD must be greater than 0, got -1")
+		assert.NotContains(t, err.Error(), "ROLLING_PERIOD")
+	})
+}
+
+func TestConfig_Validate_Threshold(t *testing.T) {
+	os.Setenv("THRESHOLD", "abc")
+	defer os.Unsetenv("THRESHOLD")
+
+	err := config.Load().Validate()
+
+	assert.Error(t, err, "should be error")
+	assert.Contains(t, err.Error(), "THRESHOLD must be an integer, got 'abc'")
+}
+
+func TestConfig_Print(t *testing.T) {
+	os.Setenv("KAFKA_USERNAME", "wallet")
+	os.Setenv("KAFKA_PASSWORD", "secret")
+	defer os.Unsetenv("KAFKA_USERNAME")
+	defer os.Unsetenv("KAFKA_PASSWORD")
+
+	var buff bytes.Buffer
+	err := config.Load().Print(&buff)
+
+	assert.Nil(t, err)
+	assert.Contains(t, buff.String(), "username: wallet")
+	assert.Contains(t, buff.String(), "threshold: 10000")
+	assert.NotContains(t, buff.String(), "secret")
+}
+
+func TestConfig_Auth(t *testing.T) {
+	t.Run("when nothing is configured", func(t *testing.T) {
+		cfg := config.Load()
+
+		assert.Empty(t, cfg.Auth.APIKeys)
+		assert.Empty(t, cfg.Auth.JWTKeys)
+		assert.Equal(t, "wallets", cfg.Auth.JWTWalletClaim)
+	})
+
+	t.Run("when api keys and jwks file are configured", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "jwks.json")
+		jwks := `{"keys":[{"kty":"EC","kid":"user-key","use":"sig","crv":"P-256",` +
+			`"x":"MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4","y":"4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyM"}]}`
+		assert.Nil(t, ioutil.WriteFile(path, []byte(jwks), 0600))
+		os.Setenv("AUTH_API_KEYS", "key-1, key-2")
+		os.Setenv("AUTH_JWKS_FILE", path)
+		defer os.Unsetenv("AUTH_API_KEYS")
+		defer os.Unsetenv("AUTH_JWKS_FILE")
+
+		cfg := config.Load()
+
+		assert.Nil(t, cfg.Validate())
+		assert.Equal(t, []string{"key-1", "key-2"}, cfg.Auth.APIKeys)
+		assert.Contains(t, cfg.Auth.JWTKeys, "user-key")
+
+		var buff bytes.Buffer
+		assert.Nil(t, cfg.Print(&buff))
+		assert.NotContains(t, buff.String(), "key-1")
+	})
+
+	t.Run("when public key file is invalid", func(t *testing.T) {
+		path := writeConfigFile(t, "not a pem")
+		os.Setenv("AUTH_JWT_PUBLIC_KEY_FILE", path)
+		defer os.Unsetenv("AUTH_JWT_PUBLIC_KEY_FILE")
+
+		err := config.Load().Validate()
+
+		assert.Error(t, err, "should be error")
+		assert.Contains(t, err.Error(), "AUTH_JWT_PUBLIC_KEY_FILE is invalid")
+	})
+}
+
+func TestConfig_RateLimit(t *testing.T) {
+	t.Run("when default limits are used", func(t *testing.T) {
+		cfg := config.Load()
+
+		assert.Equal(t, ratelimit.Limit{Rate: 10, Burst: 20}, cfg.RateLimit.Deposit.Client)
+		assert.Equal(t, ratelimit.Limit{Rate: 5, Burst: 10}, cfg.RateLimit.Deposit.Wallet)
+		assert.False(t, cfg.RateLimit.Details.Wallet.Enabled())
+		assert.Equal(t, ratelimit.Limit{Rate: 1, Burst: 5}, cfg.RateLimit.List.Client)
+		assert.Equal(t, ratelimit.Limit{Rate: 1, Burst: 10}, cfg.RateLimit.Stream.Client)
+		assert.Equal(t, "deposit", cfg.RateLimit.Deposit.Name)
+		assert.Equal(t, "stream", cfg.RateLimit.Stream.Name)
+		assert.Equal(t, ratelimit.Limit{Rate: 50, Burst: 100}, cfg.RateLimit.IP)
+	})
+
+	t.Run("when ip limit is set from env", func(t *testing.T) {
+		os.Setenv("RATE_LIMIT_IP_RATE", "5")
+		os.Setenv("RATE_LIMIT_IP_BURST", "0")
+		defer os.Unsetenv("RATE_LIMIT_IP_RATE")
+		defer os.Unsetenv("RATE_LIMIT_IP_BURST")
+
+		err := config.Load().Validate()
+
+		assert.Error(t, err, "should be error")
+		assert.Contains(t, err.Error(), "RATE_LIMIT_IP_BURST must be at least 1")
+	})
+
+	t.Run("when limit is set from env", func(t *testing.T) {
+		os.Setenv("RATE_LIMIT_DETAILS_WALLET_RATE", "0.5")
+		os.Setenv("RATE_LIMIT_DETAILS_WALLET_BURST", "2")
+		defer os.Unsetenv("RATE_LIMIT_DETAILS_WALLET_RATE")
+		defer os.Unsetenv("RATE_LIMIT_DETAILS_WALLET_BURST")
+
+		cfg := config.Load()
+
+		assert.Nil(t, cfg.Validate())
+		assert.Equal(t, ratelimit.Limit{Rate: 0.5, Burst: 2}, cfg.RateLimit.Details.Wallet)
+	})
+
+	t.Run("when burst is missing", func(t *testing.T) {
+		os.Setenv("RATE_LIMIT_DEPOSIT_CLIENT_BURST", "0")
+		defer os.Unsetenv("RATE_LIMIT_DEPOSIT_CLIENT_BURST")
+
+		err := config.Load().Validate()
+
+		assert.Error(t, err, "should be error")
+		assert.Contains(t, err.Error(), "RATE_LIMIT_DEPOSIT_CLIENT_BURST must be at least 1")
+	})
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+
+	"github.com/xdg-go/scram"
+)
+
+var (
+	sha256Generator scram.HashGeneratorFcn = sha256.New
+	sha512Generator scram.HashGeneratorFcn = sha512.New
+)
+
+// scramClient is a sarama.SCRAMClient implementation for SASL/SCRAM authentication.
+type scramClient struct {
+	*scram.Client
+	*scram.ClientConversation
+	scram.HashGeneratorFcn
+}
+
+// Begin will start the SCRAM conversation.
+func (sc *scramClient) Begin(userName, password, authzID string) (err error) {
+	sc.Client, err = sc.HashGeneratorFcn.NewClient(userName, password, authzID)
+	if err != nil {
+		return
+	}
+	sc.ClientConversation = sc.Client.NewConversation()
+	return
+}
+
+// Step will answer the server challenge.
+func (sc *scramClient) Step(challenge string) (response string, err error) {
+	response, err = sc.ClientConversation.Step(challenge)
+	return
+}
+
+// Done returns true when the conversation is completed.
+func (sc *scramClient) Done() bool {
+	return sc.ClientConversation.Done()
+}
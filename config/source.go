@@ -0,0 +1,268 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redactedValue = "******"
+
+// setting is a single configuration value, it can be set by the environment
+// variable key or by the path inside the configuration file.
+type setting struct {
+	key    string
+	path   string
+	list   bool
+	secret bool
+}
+
+// settings is every supported configuration value.
+var settings = []setting{
+	{key: "APP_NAME", path: "application.name"},
+	{key: "PORT", path: "application.port"},
+	{key: "GRPC_PORT", path: "application.grpc_port"},
+	{key: "CONFIG_WATCH_INTERVAL", path: "application.config_watch_interval"},
+	{key: "KAFKA_BROKERS", path: "kafka.brokers", list: true},
+	{key: "KAFKA_VERSION", path: "kafka.version"},
+	{key: "KAFKA_USERNAME", path: "kafka.sasl.username"},
+	{key: "KAFKA_PASSWORD", path: "kafka.sasl.password", secret: true},
+	{key: "KAFKA_SASL_MECHANISM", path: "kafka.sasl.mechanism"},
+	{key: "KAFKA_TLS_ENABLE", path: "kafka.tls.enable"},
+	{key: "KAFKA_TLS_CA_FILE", path: "kafka.tls.ca_file"},
+	{key: "KAFKA_TLS_CERT_FILE", path: "kafka.tls.cert_file"},
+	{key: "KAFKA_TLS_KEY_FILE", path: "kafka.tls.key_file"},
+	{key: "KAFKA_TLS_INSECURE_SKIP_VERIFY", path: "kafka.tls.insecure_skip_verify"},
+	{key: "KAFKA_DEPOSIT_TOPIC", path: "topic.deposit"},
+	{key: "KAFKA_CORRECTION_TOPIC", path: "topic.correction"},
+	{key: "KAFKA_BALANCE_GROUP", path: "topic.balance_group"},
+	{key: "KAFKA_THRESHOLD_GROUP", path: "topic.threshold_group"},
+	{key: "KAFKA_STATEMENT_GROUP", path: "topic.statement_group"},
+	{key: "KAFKA_PARTITIONS", path: "topic.partitions"},
+	{key: "KAFKA_STREAM_REPLICATION", path: "topic.stream_replication"},
+	{key: "KAFKA_TABLE_REPLICATION", path: "topic.table_replication"},
+	{key: "KAFKA_STREAM_RETENTION", path: "topic.stream_retention"},
+	{key: "KAFKA_STREAM_CLEANUP_POLICY", path: "topic.stream_cleanup_policy"},
+	{key: "KAFKA_TABLE_CLEANUP_POLICY", path: "topic.table_cleanup_policy"},
+	{key: "KAFKA_TOPIC_MISMATCH", path: "topic.mismatch_behavior"},
+	{key: "AUTH_API_KEYS", path: "auth.api_keys", list: true, secret: true},
+	{key: "AUTH_JWKS_FILE", path: "auth.jwt.jwks_file"},
+	{key: "AUTH_JWT_PUBLIC_KEY_FILE", path: "auth.jwt.public_key_file"},
+	{key: "AUTH_JWT_ISSUER", path: "auth.jwt.issuer"},
+	{key: "AUTH_JWT_AUDIENCE", path: "auth.jwt.audience"},
+	{key: "AUTH_JWT_WALLET_CLAIM", path: "auth.jwt.wallet_claim"},
+	{key: "RATE_LIMIT_DEPOSIT_CLIENT_RATE", path: "rate_limit.deposit.client_rate"},
+	{key: "RATE_LIMIT_DEPOSIT_CLIENT_BURST", path: "rate_limit.deposit.client_burst"},
+	{key: "RATE_LIMIT_DEPOSIT_WALLET_RATE", path: "rate_limit.deposit.wallet_rate"},
+	{key: "RATE_LIMIT_DEPOSIT_WALLET_BURST", path: "rate_limit.deposit.wallet_burst"},
+	{key: "RATE_LIMIT_DETAILS_CLIENT_RATE", path: "rate_limit.details.client_rate"},
+	{key: "RATE_LIMIT_DETAILS_CLIENT_BURST", path: "rate_limit.details.client_burst"},
+	{key: "RATE_LIMIT_DETAILS_WALLET_RATE", path: "rate_limit.details.wallet_rate"},
+	{key: "RATE_LIMIT_DETAILS_WALLET_BURST", path: "rate_limit.details.wallet_burst"},
+	{key: "RATE_LIMIT_LIST_CLIENT_RATE", path: "rate_limit.list.client_rate"},
+	{key: "RATE_LIMIT_LIST_CLIENT_BURST", path: "rate_limit.list.client_burst"},
+	{key: "RATE_LIMIT_LIST_WALLET_RATE", path: "rate_limit.list.wallet_rate"},
+	{key: "RATE_LIMIT_LIST_WALLET_BURST", path: "rate_limit.list.wallet_burst"},
+	{key: "RATE_LIMIT_STREAM_CLIENT_RATE", path: "rate_limit.stream.client_rate"},
+	{key: "RATE_LIMIT_STREAM_CLIENT_BURST", path: "rate_limit.stream.client_burst"},
+	{key: "RATE_LIMIT_STREAM_WALLET_RATE", path: "rate_limit.stream.wallet_rate"},
+	{key: "RATE_LIMIT_STREAM_WALLET_BURST", path: "rate_limit.stream.wallet_burst"},
+	{key: "STREAM_MAX_CONNECTIONS", path: "stream.max_connections"},
+	{key: "STREAM_KEEPALIVE_INTERVAL", path: "stream.keepalive_interval"},
+	{key: "STREAM_MAX_DURATION", path: "stream.max_duration"},
+	{key: "STREAM_MAX_WALLETS_PER_CONNECTION", path: "stream.max_wallets_per_connection"},
+	{key: "THRESHOLD", path: "wallet.threshold"},
+	{key: "ROLLING_PERIOD", path: "wallet.rolling_period"},
+	{key: "CONSISTENCY_TIMEOUT", path: "wallet.consistency_timeout"},
+	{key: "BATCH_LOOKUP_WORKERS", path: "wallet.batch_lookup_workers"},
+	{key: "REPLAY_TIMEOUT", path: "wallet.replay_timeout"},
+	{key: "STATEMENT_DAYS", path: "wallet.statement_days"},
+	{key: "STATEMENT_MONTHS", path: "wallet.statement_months"},
+}
+
+// readFile will read the yaml configuration file into a flat map keyed by the environment variable name.
+func (cfg *Config) readFile(path string) {
+	cfg.file = map[string]string{}
+	if path == "" {
+		return
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		cfg.problems = append(cfg.problems, fmt.Sprintf("Config file can not be read: %v", err))
+		return
+	}
+
+	var doc map[string]interface{}
+	if err = yaml.Unmarshal(content, &doc); err != nil {
+		cfg.problems = append(cfg.problems, fmt.Sprintf("Config file %s is not a valid yaml: %v", path, err))
+		return
+	}
+
+	keys := map[string]setting{}
+	for _, s := range settings {
+		keys[s.path] = s
+	}
+	flat := map[string]interface{}{}
+	flatten("", doc, flat)
+
+	paths := make([]string, 0, len(flat))
+	for p := range flat {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		s, ok := keys[p]
+		if !ok {
+			cfg.problems = append(cfg.problems, fmt.Sprintf("Config file %s has unknown key '%s'", path, p))
+			continue
+		}
+		cfg.file[s.key] = scalar(flat[p])
+	}
+}
+
+// flatten will turn nested yaml maps into dotted paths.
+func flatten(prefix string, doc map[string]interface{}, flat map[string]interface{}) {
+	for k, v := range doc {
+		p := k
+		if prefix != "" {
+			p = prefix + "." + k
+		}
+		if child, ok := v.(map[string]interface{}); ok {
+			flatten(p, child, flat)
+			continue
+		}
+		flat[p] = v
+	}
+}
+
+func scalar(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			items = append(items, scalar(item))
+		}
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// value returns the value of key from the environment, then the config file, then the default value.
+// The typed variants record a problem and fall back to the default value when the value can not be parsed.
+func (cfg *Config) value(key, defaultValue string) string {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		value, ok = cfg.file[key]
+	}
+	if !ok || value == "" {
+		value = defaultValue
+	}
+	cfg.effective[key] = value
+	return value
+}
+
+func (cfg *Config) intValue(key string, defaultValue int) int {
+	value := cfg.value(key, strconv.Itoa(defaultValue))
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		cfg.problems = append(cfg.problems, fmt.Sprintf("%s must be an integer, got '%s'", key, value))
+		return defaultValue
+	}
+	return i
+}
+
+func (cfg *Config) int64Value(key string, defaultValue int64) int64 {
+	value := cfg.value(key, strconv.FormatInt(defaultValue, 10))
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		cfg.problems = append(cfg.problems, fmt.Sprintf("%s must be an integer, got '%s'", key, value))
+		return defaultValue
+	}
+	return i
+}
+
+func (cfg *Config) boolValue(key string, defaultValue bool) bool {
+	value := cfg.value(key, strconv.FormatBool(defaultValue))
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		cfg.problems = append(cfg.problems, fmt.Sprintf("%s must be true or false, got '%s'", key, value))
+		return defaultValue
+	}
+	return b
+}
+
+func (cfg *Config) floatValue(key string, defaultValue float64) float64 {
+	value := cfg.value(key, strconv.FormatFloat(defaultValue, 'f', -1, 64))
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		cfg.problems = append(cfg.problems, fmt.Sprintf("%s must be a number, got '%s'", key, value))
+		return defaultValue
+	}
+	return f
+}
+
+func (cfg *Config) durationValue(key string, defaultValue time.Duration) time.Duration {
+	value := cfg.value(key, defaultValue.String())
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		cfg.problems = append(cfg.problems, fmt.Sprintf("%s must be a duration (e.g. 1h30m), got '%s'", key, value))
+		return defaultValue
+	}
+	return d
+}
+
+// Print will write the effective configuration as yaml, secrets are redacted.
+func (cfg *Config) Print(w io.Writer) (err error) {
+	doc := map[string]interface{}{}
+	for _, s := range settings {
+		value, ok := cfg.effective[s.key]
+		if !ok {
+			continue
+		}
+
+		var v interface{} = value
+		switch {
+		case s.secret && value != "":
+			v = redactedValue
+		case s.list:
+			v = strings.Split(value, ",")
+		default:
+			if i, convErr := strconv.Atoi(value); convErr == nil {
+				v = i
+			} else if b, convErr := strconv.ParseBool(value); convErr == nil {
+				v = b
+			}
+		}
+
+		node := doc
+		parts := strings.Split(s.path, ".")
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := node[part].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{}
+				node[part] = child
+			}
+			node = child
+		}
+		node[parts[len(parts)-1]] = v
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err = enc.Encode(doc); err != nil {
+		return
+	}
+	err = enc.Close()
+	return
+}
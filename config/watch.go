@@ -0,0 +1,54 @@
+package config
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// Watch will notify the returned channel every time the file on path is modified.
+// The file is polled on every interval so replaced files (e.g. mounted config maps) are detected as well.
+func Watch(ctx context.Context, path string, interval time.Duration) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		last := fileVersion(path)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				current := fileVersion(path)
+				if current == last {
+					continue
+				}
+				last = current
+				select {
+				case changed <- struct{}{}:
+				default:
+					// a notification is already pending.
+				}
+			}
+		}
+	}()
+	return changed
+}
+
+// fileStamp identifies a version of a file.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// fileVersion returns modification time and size of the file, it is empty when the file can not be read.
+func fileVersion(path string) (version fileStamp) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	version.modTime = info.ModTime()
+	version.size = info.Size()
+	return
+}
@@ -0,0 +1,41 @@
+package config_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ijalalfrz/coinbit-test/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatch(t *testing.T) {
+	path := writeConfigFile(t, "wallet:\n  threshold: 500\n")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changed := config.Watch(ctx, path, 10*time.Millisecond)
+
+	t.Run("when file is not modified", func(t *testing.T) {
+		select {
+		case <-changed:
+			assert.Fail(t, "should not be notified")
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("when file is modified", func(t *testing.T) {
+		err := ioutil.WriteFile(path, []byte("wallet:\n  threshold: 1000\n"), 0600)
+		assert.Nil(t, err)
+		future := time.Now().Add(time.Minute)
+		os.Chtimes(path, future, future)
+
+		select {
+		case <-changed:
+		case <-time.After(time.Second):
+			assert.Fail(t, "should be notified")
+		}
+	})
+}
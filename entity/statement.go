@@ -0,0 +1,24 @@
+package entity
+
+// Statement is an entity to record the deposits of a wallet aggregated by day and by month
+type Statement struct {
+	WalletId string  `json:"wallet_id"`
+	Balance  float64 `json:"balance"`
+	// Daily and Monthly are sorted by period, the oldest buckets are dropped once the limit is reached.
+	Daily   []StatementBucket `json:"daily"`
+	Monthly []StatementBucket `json:"monthly"`
+	// LastDepositPartition and LastDepositOffset are the position of the last applied deposit.
+	LastDepositPartition int32 `json:"last_deposit_partition"`
+	LastDepositOffset    int64 `json:"last_deposit_offset"`
+}
+
+// StatementBucket is the summary of the deposits of a period, Period is 2006-01-02 for a day and 2006-01 for a month.
+// Credits are the sum of the positive deposits and Debits the sum of the negative deposits as a positive number.
+type StatementBucket struct {
+	Period         string  `json:"period"`
+	OpeningBalance float64 `json:"opening_balance"`
+	Credits        float64 `json:"credits"`
+	Debits         float64 `json:"debits"`
+	ClosingBalance float64 `json:"closing_balance"`
+	Deposits       int     `json:"deposits"`
+}
@@ -8,4 +8,7 @@ type Threshold struct {
 	StartWindowTime          int64   `json:"start_window_time"`
 	CreatedTime              int64   `json:"created_time"`
 	AboveThreshold           bool    `json:"above_threshold"`
+	// LastDepositPartition and LastDepositOffset are the position of the last applied deposit.
+	LastDepositPartition int32 `json:"last_deposit_partition"`
+	LastDepositOffset    int64 `json:"last_deposit_offset"`
 }
@@ -4,4 +4,9 @@ package entity
 type Wallet struct {
 	WalletId string  `json:"wallet_id"`
 	Balance  float64 `json:"balance"`
+	// LastDepositPartition and LastDepositOffset are the position of the last applied deposit.
+	LastDepositPartition int32 `json:"last_deposit_partition"`
+	LastDepositOffset    int64 `json:"last_deposit_offset"`
+	// CorrectionOffset is the offset following the last applied correction, 0 when none is applied.
+	CorrectionOffset int64 `json:"correction_offset,omitempty"`
 }
@@ -0,0 +1,57 @@
+package exception
+
+import "errors"
+
+// Stable machine readable error codes, clients may rely on them unlike the messages.
+const (
+	CodeUnauthorized        = "unauthorized"
+	CodeForbidden           = "forbidden"
+	CodeNotFound            = "not_found"
+	CodeInternalServer      = "internal_error"
+	CodeConflict            = "conflict"
+	CodeUnprocessableEntity = "unprocessable_entity"
+	CodeBadRequest          = "bad_request"
+	CodeGatewayTimeout      = "gateway_timeout"
+	CodeTimeout             = "timeout"
+	CodeLocked              = "locked"
+	CodeServiceUnavailable  = "service_unavailable"
+	CodeTooManyRequests     = "too_many_requests"
+)
+
+// Codes of domain errors which are more precise than the code of their kind.
+const (
+	CodeHistoryNotRetained = "history_not_retained"
+)
+
+// catalogue maps the exceptions to their code.
+var catalogue = []struct {
+	err  error
+	code string
+}{
+	{ErrUnauthorized, CodeUnauthorized},
+	{ErrForbidden, CodeForbidden},
+	{ErrNotFound, CodeNotFound},
+	{ErrInternalServer, CodeInternalServer},
+	{ErrConflict, CodeConflict},
+	{ErrUnprocessableEntity, CodeUnprocessableEntity},
+	{ErrBadRequest, CodeBadRequest},
+	{ErrGatewayTimeout, CodeGatewayTimeout},
+	{ErrTimeout, CodeTimeout},
+	{ErrLocked, CodeLocked},
+	{ErrServiceUnavailable, CodeServiceUnavailable},
+	{ErrTooManyRequests, CodeTooManyRequests},
+}
+
+// Code returns the code of the domain error or the exception wrapped by err, it is empty when err is neither.
+func Code(err error) string {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr.ErrorCode()
+	}
+	for _, entry := range catalogue {
+		if errors.Is(err, entry.err) {
+			return entry.code
+		}
+	}
+	return ""
+}
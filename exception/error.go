@@ -0,0 +1,139 @@
+package exception
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kind is the category of a domain error, it decides how the error is answered to the clients.
+type Kind int
+
+// Collection of kind, the zero value is an internal error.
+const (
+	KindInternal Kind = iota
+	KindBadRequest
+	KindUnprocessableEntity
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+	KindLocked
+	KindTooManyRequests
+	KindTimeout
+	KindGatewayTimeout
+	KindServiceUnavailable
+)
+
+// kinds is the exception of every kind indexed by the kind, it is ordered so KindOf is deterministic.
+var kinds = []error{
+	KindInternal:            ErrInternalServer,
+	KindBadRequest:          ErrBadRequest,
+	KindUnprocessableEntity: ErrUnprocessableEntity,
+	KindUnauthorized:        ErrUnauthorized,
+	KindForbidden:           ErrForbidden,
+	KindNotFound:            ErrNotFound,
+	KindConflict:            ErrConflict,
+	KindLocked:              ErrLocked,
+	KindTooManyRequests:     ErrTooManyRequests,
+	KindTimeout:             ErrTimeout,
+	KindGatewayTimeout:      ErrGatewayTimeout,
+	KindServiceUnavailable:  ErrServiceUnavailable,
+}
+
+// Exception returns the exception of the kind, an unknown kind is an internal server error.
+func (k Kind) Exception() error {
+	if k >= 0 && int(k) < len(kinds) {
+		return kinds[k]
+	}
+	return ErrInternalServer
+}
+
+// Code returns the stable code of the kind.
+func (k Kind) Code() string {
+	return Code(k.Exception())
+}
+
+// String returns the code of the kind.
+func (k Kind) String() string {
+	return k.Code()
+}
+
+// Error is a domain error, the message is safe to answer to the clients unlike the cause.
+type Error struct {
+	Kind Kind
+	// Code overrides the code of the kind when it is not empty.
+	Code    string
+	Message string
+	Cause   error
+	// Details are extra data about the error which are answered to the clients.
+	Details map[string]interface{}
+}
+
+// New is a constructor of a domain error without cause.
+func New(kind Kind, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// Wrap is a constructor of a domain error caused by err.
+func Wrap(kind Kind, err error, message string) *Error {
+	return &Error{Kind: kind, Message: message, Cause: err}
+}
+
+// WithCode returns a copy of the error with the code.
+func (e *Error) WithCode(code string) *Error {
+	copied := *e
+	copied.Code = code
+	return &copied
+}
+
+// WithDetails returns a copy of the error with the details.
+func (e *Error) WithDetails(details map[string]interface{}) *Error {
+	copied := *e
+	copied.Details = details
+	return &copied
+}
+
+// Error returns the message and the cause.
+func (e *Error) Error() string {
+	message := e.Message
+	if message == "" {
+		message = e.Kind.Exception().Error()
+	}
+	if e.Cause == nil {
+		return message
+	}
+	return fmt.Sprintf("%s: %v", message, e.Cause)
+}
+
+// Unwrap returns the cause.
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// Is reports whether target is the exception of the kind, so errors.Is works with the exceptions.
+func (e *Error) Is(target error) bool {
+	return target == e.Kind.Exception()
+}
+
+// ErrorCode returns the code of the error.
+func (e *Error) ErrorCode() string {
+	if e.Code != "" {
+		return e.Code
+	}
+	return e.Kind.Code()
+}
+
+// KindOf returns the kind of err, it is found from the domain error or the exception wrapped by err.
+// Any other error is an internal error.
+func KindOf(err error) Kind {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Kind
+	}
+	for kind, exception := range kinds {
+		if errors.Is(err, exception) {
+			return Kind(kind)
+		}
+	}
+	return KindInternal
+}
@@ -7,6 +7,7 @@ import (
 // Exceptions.
 var (
 	ErrUnauthorized        error = fmt.Errorf("Unauthorized")
+	ErrForbidden           error = fmt.Errorf("Forbidden")
 	ErrNotFound            error = fmt.Errorf("Not found")
 	ErrInternalServer      error = fmt.Errorf("Internal server error")
 	ErrConflict            error = fmt.Errorf("Conflict")
@@ -15,4 +16,6 @@ var (
 	ErrGatewayTimeout      error = fmt.Errorf("Gateway timeout")
 	ErrTimeout             error = fmt.Errorf("Request time out")
 	ErrLocked              error = fmt.Errorf("Locked")
+	ErrServiceUnavailable  error = fmt.Errorf("Service unavailable")
+	ErrTooManyRequests     error = fmt.Errorf("Too many requests")
 )
@@ -6,24 +6,33 @@ require (
 	github.com/Shopify/sarama v1.33.0
 	github.com/elastic/go-sysinfo v1.7.0 // indirect
 	github.com/elastic/go-windows v1.0.1 // indirect
-	github.com/felixge/httpsnoop v1.0.2 // indirect
+	github.com/felixge/httpsnoop v1.0.2
+	github.com/getkin/kin-openapi v0.94.0
 	github.com/go-playground/validator/v10 v10.9.0
 	github.com/go-sql-driver/mysql v1.6.0 // indirect
 	github.com/go-stack/stack v1.8.1 // indirect
+	github.com/golang-jwt/jwt/v4 v4.5.2
 	github.com/gorilla/context v1.1.1
 	github.com/gorilla/handlers v1.5.1
 	github.com/gorilla/mux v1.8.0
+	github.com/gorilla/websocket v1.5.0
 	github.com/joho/godotenv v1.3.0
 	github.com/lovoo/goka v1.1.6
 	github.com/onsi/gomega v1.15.0 // indirect
+	github.com/prometheus/client_golang v1.12.1
+	github.com/prometheus/client_model v0.2.0
 	github.com/sirupsen/logrus v1.8.1
 	github.com/stretchr/testify v1.8.0
+	github.com/xdg-go/scram v1.1.1
 	go.elastic.co/apm v1.13.1
+	go.elastic.co/apm/module/apmhttp v1.13.1
 	go.elastic.co/apm/module/apmlogrus v1.13.1
 	go.elastic.co/apm/module/apmsql v1.13.1
 	golang.org/x/lint v0.0.0-20210508222113-6edffad5e616 // indirect
 	golang.org/x/mod v0.5.0 // indirect
 	golang.org/x/tools v0.1.5 // indirect
+	google.golang.org/grpc v1.43.0
 	google.golang.org/protobuf v1.26.0
+	gopkg.in/yaml.v3 v3.0.1
 	howett.net/plist v0.0.0-20201203080718-1454fab16a06 // indirect
 )
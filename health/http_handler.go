@@ -0,0 +1,106 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ijalalfrz/coinbit-test/exception"
+	"github.com/ijalalfrz/coinbit-test/pubsub"
+	"github.com/ijalalfrz/coinbit-test/response"
+	"github.com/ijalalfrz/coinbit-test/webmodel"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	livenessPath  = "/healthz"
+	readinessPath = "/readyz"
+	checkTimeout  = time.Second * 2
+)
+
+// collection of message
+const (
+	liveMessage     = "Application is live"
+	notLiveMessage  = "Application is not live"
+	readyMessage    = "Application is ready"
+	notReadyMessage = "Application is not ready"
+)
+
+// HTTPHandler is a concrete struct of health http handler.
+type HTTPHandler struct {
+	Logger *logrus.Logger
+	// Checkers are the processors and views, they are checked by the liveness and the readiness.
+	Checkers []pubsub.HealthChecker
+	// ReadinessCheckers are only checked by the readiness, e.g. the broker connectivity which is not fixed by a restart.
+	ReadinessCheckers []pubsub.HealthChecker
+}
+
+// NewHealthHTTPHandler will register liveness and readiness endpoint
+func NewHealthHTTPHandler(logger *logrus.Logger, router *mux.Router, readinessCheckers []pubsub.HealthChecker, checkers ...pubsub.HealthChecker) {
+	handler := &HTTPHandler{
+		Logger:            logger,
+		Checkers:          checkers,
+		ReadinessCheckers: readinessCheckers,
+	}
+	router.HandleFunc(livenessPath, handler.Liveness).Methods(http.MethodGet)
+	router.HandleFunc(readinessPath, handler.Readiness).Methods(http.MethodGet)
+}
+
+// Liveness is a function to handle liveness check, it fails when a kafka client crashed
+func (handler HTTPHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	checks := handler.check(r.Context(), handler.Checkers)
+	for _, check := range checks {
+		if !check.Live {
+			err := exception.New(exception.KindServiceUnavailable, notLiveMessage).
+				WithDetails(map[string]interface{}{"checks": checks})
+			response.Negotiate(w, r, response.FromError(err))
+			return
+		}
+	}
+
+	response.Negotiate(w, r, response.NewSuccessResponse(webmodel.HealthResponse{Checks: checks}, response.StatOK, liveMessage))
+}
+
+// Readiness is a function to handle readiness check, it fails until every kafka client is ready
+// (e.g. views are fully recovered)
+func (handler HTTPHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	checkers := make([]pubsub.HealthChecker, 0, len(handler.ReadinessCheckers)+len(handler.Checkers))
+	checkers = append(append(checkers, handler.ReadinessCheckers...), handler.Checkers...)
+	checks := handler.check(r.Context(), checkers)
+	for _, check := range checks {
+		if !check.Ready {
+			err := exception.New(exception.KindServiceUnavailable, notReadyMessage).
+				WithDetails(map[string]interface{}{"checks": checks})
+			response.Negotiate(w, r, response.FromError(err))
+			return
+		}
+	}
+
+	response.Negotiate(w, r, response.NewSuccessResponse(webmodel.HealthResponse{Checks: checks}, response.StatOK, readyMessage))
+}
+
+// check will run every checker concurrently, the errors are logged and only the states are answered
+func (handler HTTPHandler) check(ctx context.Context, checkers []pubsub.HealthChecker) (checks []pubsub.Health) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	checks = make([]pubsub.Health, len(checkers))
+	var wg sync.WaitGroup
+	for i, checker := range checkers {
+		wg.Add(1)
+		go func(i int, checker pubsub.HealthChecker) {
+			defer wg.Done()
+			checks[i] = checker.Health(ctx)
+		}(i, checker)
+	}
+	wg.Wait()
+
+	for _, check := range checks {
+		if check.Error != "" {
+			handler.Logger.WithContext(ctx).Warnf("Health check %s is %s: %s", check.Name, check.State, check.Error)
+		}
+	}
+	return
+}
@@ -0,0 +1,138 @@
+package health_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/ijalalfrz/coinbit-test/exception"
+	"github.com/ijalalfrz/coinbit-test/health"
+	"github.com/ijalalfrz/coinbit-test/pubsub"
+	"github.com/ijalalfrz/coinbit-test/pubsub/mocks"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNewHealthHTTPHandlerConstruct(t *testing.T) {
+	t.Run("should construct the health http handler", func(t *testing.T) {
+		health.NewHealthHTTPHandler(logrus.New(), mux.NewRouter(), nil, &mocks.ViewTable{})
+	})
+}
+
+func TestReadiness_Error_ViewRecovering(t *testing.T) {
+	balanceView := &mocks.ViewTable{}
+	thresholdView := &mocks.ViewTable{}
+	balanceView.On("Health", mock.Anything).Return(pubsub.Health{Name: "view-balance", State: pubsub.HealthStateRunning, Live: true, Ready: true})
+	thresholdView.On("Health", mock.Anything).Return(pubsub.Health{Name: "view-aboveThreshold", State: pubsub.HealthStateRecovering, Live: true})
+	hh := health.HTTPHandler{
+		Logger:   logrus.New(),
+		Checkers: []pubsub.HealthChecker{balanceView, thresholdView},
+	}
+	r := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	recorder := httptest.NewRecorder()
+
+	http.HandlerFunc(hh.Readiness).ServeHTTP(recorder, r)
+
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), pubsub.HealthStateRecovering)
+	balanceView.AssertExpectations(t)
+	thresholdView.AssertExpectations(t)
+}
+
+func TestReadiness_Success(t *testing.T) {
+	balanceView := &mocks.ViewTable{}
+	processor := &mocks.Subscriber{}
+	balanceView.On("Health", mock.Anything).Return(pubsub.Health{Name: "view-balance", State: pubsub.HealthStateRunning, Live: true, Ready: true})
+	processor.On("Health", mock.Anything).Return(pubsub.Health{Name: "processor-balance", State: pubsub.HealthStateRunning, Live: true, Ready: true})
+	hh := health.HTTPHandler{
+		Logger:   logrus.New(),
+		Checkers: []pubsub.HealthChecker{balanceView, processor},
+	}
+	r := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	recorder := httptest.NewRecorder()
+
+	http.HandlerFunc(hh.Readiness).ServeHTTP(recorder, r)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	balanceView.AssertExpectations(t)
+	processor.AssertExpectations(t)
+}
+
+func TestReadiness_Error_BrokerUnreachable(t *testing.T) {
+	broker := &mocks.Subscriber{}
+	broker.On("Health", mock.Anything).Return(pubsub.Health{Name: "kafka-brokers", State: pubsub.HealthStateDisconnected, Live: true, Error: "dial tcp 10.0.0.1:9092: connection refused"})
+	logger, hook := test.NewNullLogger()
+	hh := health.HTTPHandler{
+		Logger:            logger,
+		ReadinessCheckers: []pubsub.HealthChecker{broker},
+	}
+	r := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	recorder := httptest.NewRecorder()
+
+	http.HandlerFunc(hh.Readiness).ServeHTTP(recorder, r)
+
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), pubsub.HealthStateDisconnected)
+	assert.NotContains(t, recorder.Body.String(), "10.0.0.1", "should not answer the cause")
+	if assert.NotNil(t, hook.LastEntry()) {
+		assert.Contains(t, hook.LastEntry().Message, "connection refused")
+	}
+	broker.AssertExpectations(t)
+}
+
+func TestLiveness_Success_BrokerNotChecked(t *testing.T) {
+	broker := &mocks.Subscriber{}
+	balanceView := &mocks.ViewTable{}
+	balanceView.On("Health", mock.Anything).Return(pubsub.Health{Name: "view-balance", State: pubsub.HealthStateRunning, Live: true, Ready: true})
+	hh := health.HTTPHandler{
+		Logger:            logrus.New(),
+		Checkers:          []pubsub.HealthChecker{balanceView},
+		ReadinessCheckers: []pubsub.HealthChecker{broker},
+	}
+	r := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	recorder := httptest.NewRecorder()
+
+	http.HandlerFunc(hh.Liveness).ServeHTTP(recorder, r)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	broker.AssertNotCalled(t, "Health", mock.Anything)
+	balanceView.AssertExpectations(t)
+}
+
+func TestLiveness_Error_ProcessorFailed(t *testing.T) {
+	processor := &mocks.Subscriber{}
+	processor.On("Health", mock.Anything).Return(pubsub.Health{Name: "processor-balance", State: pubsub.HealthStateFailed, Error: "crashed"})
+	hh := health.HTTPHandler{
+		Logger:   logrus.New(),
+		Checkers: []pubsub.HealthChecker{processor},
+	}
+	r := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	recorder := httptest.NewRecorder()
+
+	http.HandlerFunc(hh.Liveness).ServeHTTP(recorder, r)
+
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), exception.CodeServiceUnavailable)
+	assert.Contains(t, recorder.Body.String(), pubsub.HealthStateFailed)
+	assert.NotContains(t, recorder.Body.String(), "crashed", "should not answer the cause")
+	processor.AssertExpectations(t)
+}
+
+func TestLiveness_Success_WhileRecovering(t *testing.T) {
+	balanceView := &mocks.ViewTable{}
+	balanceView.On("Health", mock.Anything).Return(pubsub.Health{Name: "view-balance", State: pubsub.HealthStateRecovering, Live: true})
+	hh := health.HTTPHandler{
+		Logger:   logrus.New(),
+		Checkers: []pubsub.HealthChecker{balanceView},
+	}
+	r := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	recorder := httptest.NewRecorder()
+
+	http.HandlerFunc(hh.Liveness).ServeHTTP(recorder, r)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	balanceView.AssertExpectations(t)
+}
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
@@ -10,9 +13,15 @@ import (
 	"go.elastic.co/apm"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/ijalalfrz/coinbit-test/health"
+	"github.com/ijalalfrz/coinbit-test/openapi"
 	"github.com/ijalalfrz/coinbit-test/pubsub"
+	"github.com/ijalalfrz/coinbit-test/ratelimit"
 	"github.com/ijalalfrz/coinbit-test/wallet"
 	"github.com/lovoo/goka"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
 
 	"github.com/ijalalfrz/coinbit-test/middleware"
 
@@ -24,6 +33,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/ijalalfrz/coinbit-test/auth"
 	"github.com/ijalalfrz/coinbit-test/config"
 
 	_ "github.com/joho/godotenv/autoload" // for development
@@ -32,26 +42,39 @@ import (
 )
 
 var (
-	tracer         *apm.Tracer
-	cfg            *config.Config
-	location       *time.Location
-	tmc            *goka.TopicManagerConfig
-	depositTopic   string = "deposits"
-	balanceGroup   string = "balance"
-	thresholdGroup string = "aboveThreshold"
-	indexMessage   string = "Application is running properly"
+	tracer       *apm.Tracer
+	cfg          *config.Config
+	location     *time.Location
+	tmc          *goka.TopicManagerConfig
+	indexMessage string = "Application is running properly"
+	configFile          = flag.String("config", os.Getenv("CONFIG_FILE"), "path of the yaml configuration file")
+	printConfig         = flag.Bool("print-config", false, "print the effective configuration (secrets are redacted) and exit")
 )
 
 func init() {
 	tracer = apm.DefaultTracer
-	tmc = goka.NewTopicManagerConfig()
-	// if multiple broker we can configure above 1
-	tmc.Table.Replication = 1
-	tmc.Stream.Replication = 1
-	cfg = config.Load()
 }
 
 func main() {
+	flag.Parse()
+	cfg = config.LoadFile(*configFile)
+	if *printConfig {
+		cfg.Print(os.Stdout)
+		if err := cfg.Validate(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	tmc = goka.NewTopicManagerConfig()
+	tmc.Table.Replication = cfg.Topic.TableReplication
+	tmc.Table.CleanupPolicy = cfg.Topic.TableCleanupPolicy
+	tmc.Stream.Replication = cfg.Topic.StreamReplication
+	tmc.Stream.Retention = cfg.Topic.StreamRetention
+	tmc.Stream.CleanupPolicy = cfg.Topic.StreamCleanupPolicy
+	tmc.MismatchBehavior = mismatchBehavior(cfg.Topic.MismatchBehavior)
+
 	// init logger
 	logger := logrus.New()
 	logger.SetFormatter(cfg.Logger.Formatter)
@@ -59,21 +82,38 @@ func main() {
 	logger.AddHook(&apmlogrus.Hook{
 		LogLevels: logrus.AllLevels,
 	})
+	logger.AddHook(middleware.RequestIDHook{})
+
+	if err := cfg.Validate(); err != nil {
+		logger.Fatal(err)
+	}
+
+	// the commands only use the existing topics, they are dispatched before the topics are ensured
+	switch flag.Arg(0) {
+	case "reconcile":
+		os.Exit(runReconcile(logger, flag.Args()[1:]))
+	case "admin":
+		os.Exit(runAdmin(logger, flag.Args()[1:]))
+	}
 
 	// init validator
 	vld := validator.New()
 
 	// init router object
 	router := mux.NewRouter()
+	router.Use(middleware.AccessLogRoute)
+	router.Use(middleware.Metrics)
+	router.Use(middleware.Tracing(tracer))
 	router.HandleFunc("/wallet", index)
+	router.Handle("/metrics", promhttp.Handler()).Methods(http.MethodGet)
 
 	// init topic event
-	tm, err := goka.NewTopicManager(cfg.SaramaKafka.Addresses, goka.DefaultConfig(), tmc)
+	tm, err := goka.NewTopicManager(cfg.SaramaKafka.Addresses, cfg.SaramaKafka.Config, tmc)
 	if err != nil {
 		logger.Fatalf("Error creating topic manager: %v", err)
 	}
 	defer tm.Close()
-	err = tm.EnsureStreamExists(depositTopic, 1)
+	err = ensureTopics(tm)
 	if err != nil {
 		logger.Fatal(err)
 	}
@@ -81,19 +121,34 @@ func main() {
 	depositWalletCodec := wallet.NewDepositCodec()
 	walletCodec := wallet.NewWalletCodec()
 	thresholdCodec := wallet.NewThresholdCodec()
+	statementCodec := wallet.NewStatementCodec()
 
 	// init view table
-	balanceVt, err := pubsub.NewGokaViewTableAdapter(logger, balanceGroup, cfg.SaramaKafka.Addresses, walletCodec)
+	balanceVt, err := pubsub.NewGokaViewTableAdapter(logger, cfg.Topic.BalanceGroup, cfg.SaramaKafka.Addresses, cfg.SaramaKafka.Config, walletCodec)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	thresholdVt, err := pubsub.NewGokaViewTableAdapter(logger, cfg.Topic.ThresholdGroup, cfg.SaramaKafka.Addresses, cfg.SaramaKafka.Config, thresholdCodec)
 	if err != nil {
 		logger.Fatal(err)
 	}
-	thresholdVt, err := pubsub.NewGokaViewTableAdapter(logger, thresholdGroup, cfg.SaramaKafka.Addresses, thresholdCodec)
+	statementVt, err := pubsub.NewGokaViewTableAdapter(logger, cfg.Topic.StatementGroup, cfg.SaramaKafka.Addresses, cfg.SaramaKafka.Config, statementCodec)
 	if err != nil {
 		logger.Fatal(err)
 	}
+	for group, vt := range map[string]pubsub.ViewTable{cfg.Topic.BalanceGroup: balanceVt, cfg.Topic.ThresholdGroup: thresholdVt, cfg.Topic.StatementGroup: statementVt} {
+		group := group
+		vt.OnStateChange(func(state string) {
+			logger.WithField("view", group).Infof("View is %s", state)
+		})
+	}
 
 	// init publisher
-	depositTopicPublisher, err := pubsub.NewGokaProducerAdapter(logger, cfg.SaramaKafka.Addresses, depositTopic, depositWalletCodec)
+	depositTopicPublisher, err := pubsub.NewGokaProducerAdapter(logger, cfg.SaramaKafka.Addresses, cfg.SaramaKafka.Config, cfg.Topic.Deposit, depositWalletCodec)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	depositReplayer, err := pubsub.NewGokaStreamReplayer(logger, cfg.SaramaKafka.Addresses, cfg.SaramaKafka.Config, cfg.Topic.Deposit, depositWalletCodec)
 	if err != nil {
 		logger.Fatal(err)
 	}
@@ -106,56 +161,213 @@ func main() {
 		Threshold:             cfg.Wallet.Threshold,
 		BalanceViewTable:      balanceVt,
 		ThresholdViewTable:    thresholdVt,
+		StatementViewTable:    statementVt,
+		ConsistencyTimeout:    cfg.Wallet.ConsistencyTimeout,
+		BatchLookupWorkers:    cfg.Wallet.BatchLookupWorkers,
+		DepositReplayer:       depositReplayer,
+		ReplayTimeout:         cfg.Wallet.ReplayTimeout,
+		StatementDays:         cfg.Wallet.StatementDays,
+		StatementMonths:       cfg.Wallet.StatementMonths,
 	})
 
+	prometheus.MustRegister(wallet.NewAboveThresholdGauge(logger, thresholdVt))
+
 	// init pub sub event
-	depositWalletEventHandler := wallet.NewDepositWalletEventHandler(logger, walletUsecase)
-	processThresholdEventHandler := wallet.NewProcessThresholdEventHandler(logger, walletUsecase)
+	depositWalletEventHandler := wallet.NewDepositWalletEventHandler(logger, tracer, walletUsecase)
+	processThresholdEventHandler := wallet.NewProcessThresholdEventHandler(logger, tracer, walletUsecase)
+	statementEventHandler := wallet.NewStatementEventHandler(logger, tracer, walletUsecase)
+	correctionEventHandler := wallet.NewCorrectionEventHandler(logger, tracer, walletUsecase)
+
+	depositWalletBalanceGroup, err := pubsub.NewGokaConsumerGroupFullConfigAdapter(logger, cfg.SaramaKafka.Addresses, cfg.SaramaKafka.Config,
+		cfg.Topic.BalanceGroup, cfg.Topic.Deposit, depositWalletEventHandler, tmc, depositWalletCodec, walletCodec,
+		pubsub.GokaInput{Topic: cfg.Topic.Correction, Codec: depositWalletCodec, Handler: correctionEventHandler})
 
-	depositWalletBalanceGroup, err := pubsub.NewGokaConsumerGroupFullConfigAdapter(logger, cfg.SaramaKafka.Addresses,
-		balanceGroup, depositTopic, depositWalletEventHandler, tmc, depositWalletCodec, walletCodec)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	processThresholdGroup, err := pubsub.NewGokaConsumerGroupFullConfigAdapter(logger, cfg.SaramaKafka.Addresses, cfg.SaramaKafka.Config,
+		cfg.Topic.ThresholdGroup, cfg.Topic.Deposit, processThresholdEventHandler, tmc, depositWalletCodec, thresholdCodec)
 
 	if err != nil {
 		logger.Fatal(err)
 	}
 
-	processThresholdGroup, err := pubsub.NewGokaConsumerGroupFullConfigAdapter(logger, cfg.SaramaKafka.Addresses,
-		thresholdGroup, depositTopic, processThresholdEventHandler, tmc, depositWalletCodec, thresholdCodec)
+	statementGroup, err := pubsub.NewGokaConsumerGroupFullConfigAdapter(logger, cfg.SaramaKafka.Addresses, cfg.SaramaKafka.Config,
+		cfg.Topic.StatementGroup, cfg.Topic.Deposit, statementEventHandler, tmc, depositWalletCodec, statementCodec)
+
+	if err != nil {
+		logger.Fatal(err)
+	}
 
+	// init http handler, the wallet routes require authentication when it is configured
+	walletRouter := router.NewRoute().Subrouter()
+	// the http routes and the grpc methods share the store, the buckets of a policy are shared by both,
+	// the ip limit is taken before the authentication so the failed ones are limited as well
+	rateLimitStore := ratelimit.NewMemoryStore()
+	walletRouter.Use(middleware.RateLimitIP(logger, rateLimitStore, cfg.RateLimit.IP))
+	authenticator := auth.NewAuthenticator(cfg.Auth.APIKeys, cfg.Auth.JWTKeys, cfg.Auth.JWTIssuer, cfg.Auth.JWTAudience, cfg.Auth.JWTWalletClaim)
+	if authenticator.Enabled() {
+		walletRouter.Use(middleware.Auth(logger, authenticator))
+	} else {
+		logger.Warn("Authentication is disabled, configure AUTH_API_KEYS, AUTH_JWKS_FILE or AUTH_JWT_PUBLIC_KEY_FILE to enable it")
+	}
+	walletRouter.Use(middleware.RateLimit(logger, rateLimitStore, map[string]ratelimit.Policy{
+		wallet.DepositPath: cfg.RateLimit.Deposit,
+		wallet.DetailsPath: cfg.RateLimit.Details,
+		// a batch takes one token of the client, the wallets of the body are not limited
+		wallet.BatchDetailsPath:  cfg.RateLimit.Details,
+		wallet.BalanceAtPath:     cfg.RateLimit.Details,
+		wallet.StatementsPath:    cfg.RateLimit.Details,
+		wallet.WalletsPath:       cfg.RateLimit.List,
+		wallet.StreamPath:        cfg.RateLimit.Stream,
+		wallet.SubscriptionsPath: cfg.RateLimit.Stream,
+	}))
+	specRouter, err := openapi.NewRouter()
 	if err != nil {
 		logger.Fatal(err)
 	}
+	walletRouter.Use(middleware.RequestValidation(logger, specRouter))
+	wallet.NewWalletHTTPHandler(logger, vld, walletRouter, walletUsecase)
+	streamHub := wallet.NewStreamHub(balanceVt, thresholdVt, cfg.Stream.MaxConnections)
+	wallet.NewWalletStreamHTTPHandler(logger, walletRouter, streamHub, cfg.Stream.KeepaliveInterval, cfg.Stream.MaxDuration)
+	wallet.NewWalletSubscriptionHTTPHandler(logger, walletRouter, streamHub, cfg.Stream.KeepaliveInterval, cfg.Stream.MaxWalletsPerConnection)
 
-	// init http handler
-	wallet.NewWalletHTTPHandler(logger, vld, router, walletUsecase)
+	// init grpc handler, it is authenticated and limited the same as the wallet routes
+	unaryInterceptors := []grpc.UnaryServerInterceptor{middleware.RateLimitIPUnaryInterceptor(logger, rateLimitStore, cfg.RateLimit.IP)}
+	streamInterceptors := []grpc.StreamServerInterceptor{middleware.RateLimitIPStreamInterceptor(logger, rateLimitStore, cfg.RateLimit.IP)}
+	if authenticator.Enabled() {
+		unaryInterceptors = append(unaryInterceptors, middleware.AuthUnaryInterceptor(logger, authenticator))
+		streamInterceptors = append(streamInterceptors, middleware.AuthStreamInterceptor(logger, authenticator))
+	}
+	grpcPolicies := map[string]ratelimit.Policy{
+		wallet.DepositMethod:     cfg.RateLimit.Deposit,
+		wallet.GetDetailMethod:   cfg.RateLimit.Details,
+		wallet.WatchWalletMethod: cfg.RateLimit.Stream,
+	}
+	unaryInterceptors = append(unaryInterceptors, middleware.RateLimitUnaryInterceptor(logger, rateLimitStore, grpcPolicies))
+	streamInterceptors = append(streamInterceptors, middleware.RateLimitStreamInterceptor(logger, rateLimitStore, grpcPolicies))
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(unaryInterceptors...), grpc.ChainStreamInterceptor(streamInterceptors...))
+	wallet.NewWalletGRPCHandler(logger, vld, grpcServer, walletUsecase, streamHub)
+
+	openapi.NewOpenAPIHTTPHandler(logger, router)
+	brokerHealthChecker := pubsub.NewBrokerHealthChecker(cfg.SaramaKafka.Addresses, cfg.SaramaKafka.Config)
+	health.NewHealthHTTPHandler(logger, router, []pubsub.HealthChecker{brokerHealthChecker}, depositTopicPublisher,
+		depositWalletBalanceGroup, processThresholdGroup, statementGroup, balanceVt, thresholdVt, statementVt)
 
 	// middleware]
-	httpHandler := gctx.ClearHandler(router)
-	httpHandler = middleware.Recovery(logger, httpHandler)
+	// the request id and the access log wrap the router so the unmatched requests get them as well,
+	// the recovery is innermost so a panic is logged with its request id and its 500 status
+	httpHandler := gctx.ClearHandler(middleware.RequestID(middleware.AccessLog(logger, middleware.Recovery(logger, router))))
 	httpHandler = middleware.CORS(httpHandler)
 
 	// initiate server
 	srv := server.NewServer(logger, httpHandler, cfg.Application.Port)
-	srv.Start()
+	if err = srv.Start(); err != nil {
+		logger.Fatal(err)
+	}
+	grpcSrv := server.NewGRPCServer(logger, grpcServer, cfg.Application.GRPCPort)
+	if err = grpcSrv.Start(); err != nil {
+		logger.Fatal(err)
+	}
 	depositWalletBalanceGroup.Subscribe()
 	processThresholdGroup.Subscribe()
+	statementGroup.Subscribe()
 	balanceVt.Open()
 	thresholdVt.Open()
+	statementVt.Open()
+
+	// reload wallet rule on SIGHUP or when the config file is modified
+	reloadCtx, stopReload := context.WithCancel(context.Background())
+	go reloadWalletRule(reloadCtx, logger, walletUsecase)
 
 	sigterm := make(chan os.Signal, 1)
 	signal.Notify(sigterm, syscall.SIGINT, syscall.SIGTERM, syscall.SIGKILL, os.Interrupt)
 	<-sigterm
 
 	// closing service for a gracefull shutdown.
+	stopReload()
 	srv.Close()
+	grpcSrv.Close()
 	depositWalletBalanceGroup.Close()
 	processThresholdGroup.Close()
+	statementGroup.Close()
 	depositTopicPublisher.Close()
+	depositReplayer.Close()
 	balanceVt.Close()
 	thresholdVt.Close()
+	statementVt.Close()
+	brokerHealthChecker.Close()
+}
+
+// reloadWalletRule will load the wallet rule again on every SIGHUP or config file change
+// and swap it without restarting the processors.
+func reloadWalletRule(ctx context.Context, logger *logrus.Logger, usecase wallet.Usecase) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+
+	var fileChanged <-chan struct{}
+	if *configFile != "" && cfg.Application.ConfigWatchInterval > 0 {
+		fileChanged = config.Watch(ctx, *configFile, cfg.Application.ConfigWatchInterval)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sighup:
+		case <-fileChanged:
+		}
+
+		rule, err := config.LoadWalletRule(*configFile)
+		if err != nil {
+			logger.Errorf("Wallet rule is not reloaded, the current one is kept: %v", err)
+			continue
+		}
+		usecase.ReloadRule(wallet.Rule{
+			Threshold:     rule.Threshold,
+			RollingPeriod: rule.RollingPeriod,
+		})
+	}
+}
+
+// ensureTopics will create or verify the deposit stream and the group tables
+// with the configured partitions, so all of them stay copartitioned.
+func ensureTopics(tm goka.TopicManager) (err error) {
+	for _, stream := range []string{cfg.Topic.Deposit, cfg.Topic.Correction} {
+		if cfg.Topic.StreamRetention > 0 {
+			err = tm.EnsureStreamExists(stream, cfg.Topic.Partitions)
+		} else {
+			// the retention of the broker is kept, EnsureStreamExists always sets retention.ms
+			err = tm.EnsureTopicExists(stream, cfg.Topic.Partitions, cfg.Topic.StreamReplication,
+				map[string]string{"cleanup.policy": cfg.Topic.StreamCleanupPolicy})
+		}
+		if err != nil {
+			return
+		}
+	}
+	for _, group := range []string{cfg.Topic.BalanceGroup, cfg.Topic.ThresholdGroup, cfg.Topic.StatementGroup} {
+		err = tm.EnsureTableExists(string(goka.GroupTable(goka.Group(group))), cfg.Topic.Partitions)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func mismatchBehavior(behavior string) goka.TMConfigMismatchBehavior {
+	switch behavior {
+	case "fail":
+		return goka.TMConfigMismatchBehaviorFail
+	case "ignore":
+		return goka.TMConfigMismatchBehaviorIgnore
+	default:
+		return goka.TMConfigMismatchBehaviorWarn
+	}
 }
 
 func index(w http.ResponseWriter, r *http.Request) {
 	resp := response.NewSuccessResponse(nil, response.StatOK, indexMessage)
-	response.JSON(w, resp)
+	response.Negotiate(w, r, resp)
 }
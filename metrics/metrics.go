@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// HTTP metrics.
+var (
+	HTTPRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Number of http requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of http requests by method and route template.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+// Kafka metrics.
+var (
+	PublishDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kafka_publish_duration_seconds",
+		Help:    "Latency of publishing a message by topic.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"topic"})
+
+	PublishErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_publish_errors_total",
+		Help: "Number of messages failed to be published by topic.",
+	}, []string{"topic"})
+
+	ProcessedMessagesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_processed_messages_total",
+		Help: "Number of messages processed by goka group and topic.",
+	}, []string{"group", "topic"})
+
+	ProcessingDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kafka_processing_duration_seconds",
+		Help:    "Latency of processing a message by goka group and topic.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"group", "topic"})
+
+	ConsumerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_consumer_lag",
+		Help: "Number of messages not processed yet by goka group, topic and partition.",
+	}, []string{"group", "topic", "partition"})
+
+	ViewRecovered = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_view_recovered",
+		Help: "1 when every partition of the view is recovered, 0 otherwise.",
+	}, []string{"table"})
+
+	ViewRecoveryLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_view_recovery_lag",
+		Help: "Number of messages not recovered yet by view table and partition.",
+	}, []string{"table", "partition"})
+)
+
+// Wallet metrics.
+var (
+	DepositedAmountTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "wallet_deposited_amount_total",
+		Help: "Total amount deposited to wallets by direction, credit or debit.",
+	}, []string{"direction"})
+
+	CorrectedAmountTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "wallet_corrected_amount_total",
+		Help: "Total amount of the balance corrections applied to wallets by direction, credit or debit.",
+	}, []string{"direction"})
+)
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/sirupsen/logrus"
+)
+
+type accessRouteKey struct{}
+
+// accessRoute holds the path template of the matched route of a request, it is recorded by AccessLogRoute.
+type accessRoute struct {
+	template string
+}
+
+// AccessLog wraps handler with a structured access log entry for every request, the unmatched ones included.
+// It must be wrapped by RequestID so the request id is known, the route is recorded by AccessLogRoute.
+func AccessLog(logger *logrus.Logger, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := &accessRoute{template: "unmatched"}
+		m := httpsnoop.CaptureMetrics(handler, w, r.WithContext(context.WithValue(r.Context(), accessRouteKey{}, route)))
+
+		logger.WithContext(r.Context()).WithFields(logrus.Fields{
+			"method":     r.Method,
+			"route":      route.template,
+			"status":     m.Code,
+			"latency_ms": float64(m.Duration.Microseconds()) / 1000,
+			"bytes":      m.Written,
+		}).Info("access")
+	})
+}
+
+// AccessLogRoute returns middleware which records the route template for AccessLog.
+// It must be registered with router.Use so the matched route is known.
+func AccessLogRoute(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route, ok := r.Context().Value(accessRouteKey{}).(*accessRoute); ok {
+			route.template = routeTemplate(r)
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,64 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/ijalalfrz/coinbit-test/middleware"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessLog(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	logger.AddHook(middleware.RequestIDHook{})
+	router := mux.NewRouter()
+	router.Use(middleware.AccessLogRoute)
+	router.HandleFunc("/wallet/v1/details/{walletId}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("done"))
+	}).Methods(http.MethodGet)
+	router.HandleFunc("/wallet/v1/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("broken handler")
+	})
+	handler := middleware.RequestID(middleware.AccessLog(logger, middleware.Recovery(logger, router)))
+
+	t.Run("when route is matched", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/wallet/v1/details/1", nil)
+		r.Header.Set(middleware.RequestIDHeader, "req-1")
+
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+
+		entry := hook.LastEntry()
+		assert.Equal(t, "access", entry.Message)
+		assert.Equal(t, http.MethodGet, entry.Data["method"])
+		assert.Equal(t, "/wallet/v1/details/{walletId}", entry.Data["route"])
+		assert.Equal(t, http.StatusCreated, entry.Data["status"])
+		assert.Equal(t, int64(4), entry.Data["bytes"])
+		assert.Equal(t, "req-1", entry.Data["request_id"])
+	})
+
+	t.Run("when route is not matched", func(t *testing.T) {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/wallet/v1/unknown", nil))
+
+		entry := hook.LastEntry()
+		assert.Equal(t, "unmatched", entry.Data["route"])
+		assert.Equal(t, http.StatusNotFound, entry.Data["status"])
+		assert.NotEmpty(t, entry.Data["request_id"])
+	})
+
+	t.Run("when handler panics", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/wallet/v1/panic", nil))
+
+		entry := hook.LastEntry()
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+		assert.Equal(t, "access", entry.Message)
+		assert.Equal(t, "/wallet/v1/panic", entry.Data["route"])
+		assert.Equal(t, http.StatusInternalServerError, entry.Data["status"])
+		assert.NotEmpty(t, entry.Data["request_id"])
+	})
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/ijalalfrz/coinbit-test/auth"
+	"github.com/ijalalfrz/coinbit-test/exception"
+	"github.com/ijalalfrz/coinbit-test/response"
+	"github.com/sirupsen/logrus"
+)
+
+const unauthorizedMessage = "Valid X-API-Key header or bearer token is required"
+
+// Auth returns middleware which rejects unauthenticated requests with 401 and stores the principal
+// in the request context, the per-wallet authorization is done by the handlers.
+func Auth(logger *logrus.Logger, authenticator *auth.Authenticator) mux.MiddlewareFunc {
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := authenticator.Authenticate(r)
+			if err != nil {
+				logger.WithContext(r.Context()).Warnf("Request is not authenticated: %v", err)
+				w.Header().Set("WWW-Authenticate", `Bearer realm="wallet"`)
+				response.Negotiate(w, r, response.FromError(exception.Wrap(exception.KindUnauthorized, err, unauthorizedMessage)))
+				return
+			}
+
+			handler.ServeHTTP(w, r.WithContext(auth.ContextWithPrincipal(r.Context(), principal)))
+		})
+	}
+}
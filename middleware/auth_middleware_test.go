@@ -0,0 +1,64 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/ijalalfrz/coinbit-test/auth"
+	"github.com/ijalalfrz/coinbit-test/exception"
+	"github.com/ijalalfrz/coinbit-test/middleware"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuth(t *testing.T) {
+	var principal *auth.Principal
+	router := mux.NewRouter()
+	router.Use(middleware.Auth(logrus.New(), auth.NewAuthenticator([]string{"secret"}, nil, "", "", "")))
+	router.HandleFunc("/wallet/v1/details/{walletId}", func(w http.ResponseWriter, r *http.Request) {
+		if p, ok := auth.PrincipalFromContext(r.Context()); ok {
+			principal = &p
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	t.Run("when credentials are missing", func(t *testing.T) {
+		principal = nil
+		recorder := httptest.NewRecorder()
+
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/wallet/v1/details/1", nil))
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		assert.Equal(t, `Bearer realm="wallet"`, recorder.Header().Get("WWW-Authenticate"))
+		assert.Contains(t, recorder.Body.String(), exception.CodeUnauthorized)
+		assert.Nil(t, principal, "should not reach the handler")
+	})
+
+	t.Run("when api key is invalid", func(t *testing.T) {
+		principal = nil
+		recorder := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/wallet/v1/details/1", nil)
+		r.Header.Set(auth.APIKeyHeader, "guess")
+
+		router.ServeHTTP(recorder, r)
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		assert.Nil(t, principal, "should not reach the handler")
+	})
+
+	t.Run("when api key is valid", func(t *testing.T) {
+		principal = nil
+		recorder := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/wallet/v1/details/1", nil)
+		r.Header.Set(auth.APIKeyHeader, "secret")
+
+		router.ServeHTTP(recorder, r)
+
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+		if assert.NotNil(t, principal) {
+			assert.Equal(t, auth.Principal{Kind: auth.KindService, Subject: "api-key-1"}, *principal)
+		}
+	})
+}
@@ -4,13 +4,15 @@ import (
 	"net/http"
 
 	"github.com/gorilla/handlers"
+	"github.com/ijalalfrz/coinbit-test/auth"
 )
 
 // CORS returns cors middleware.
 func CORS(handler http.Handler) http.Handler {
 	return handlers.CORS(
 		handlers.AllowedOrigins([]string{"*"}),
-		handlers.AllowedHeaders([]string{"X-Requested-With", "Origin", "Content-Type", "Authorization"}),
+		handlers.AllowedHeaders([]string{"X-Requested-With", "Origin", "Content-Type", "Authorization", auth.APIKeyHeader, RequestIDHeader}),
+		handlers.ExposedHeaders([]string{RequestIDHeader, "Retry-After"}),
 		handlers.AllowedMethods([]string{http.MethodPost, http.MethodGet, http.MethodPut, http.MethodDelete}),
 	)(handler)
 }
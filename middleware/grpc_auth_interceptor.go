@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ijalalfrz/coinbit-test/auth"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const unauthenticatedMessage = "Valid x-api-key or authorization bearer token metadata is required"
+
+// AuthUnaryInterceptor returns interceptor which rejects unauthenticated calls with Unauthenticated and stores
+// the principal in the call context, the same as the Auth middleware.
+func AuthUnaryInterceptor(logger *logrus.Logger, authenticator *auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticateCall(ctx, logger, authenticator, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// AuthStreamInterceptor returns the streaming counterpart of AuthUnaryInterceptor.
+func AuthStreamInterceptor(logger *logrus.Logger, authenticator *auth.Authenticator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticateCall(ss.Context(), logger, authenticator, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticateCall will authenticate the metadata of the call as http headers.
+func authenticateCall(ctx context.Context, logger *logrus.Logger, authenticator *auth.Authenticator, method string) (context.Context, error) {
+	header := http.Header{}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for key, values := range md {
+		for _, value := range values {
+			header.Add(key, value)
+		}
+	}
+
+	principal, err := authenticator.AuthenticateHeader(header)
+	if err != nil {
+		logger.WithContext(ctx).Warnf("Call of %s is not authenticated: %v", method, err)
+		return ctx, status.Error(codes.Unauthenticated, unauthenticatedMessage)
+	}
+	return auth.ContextWithPrincipal(ctx, principal), nil
+}
+
+// authenticatedStream is a server stream carrying the context with the principal.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context with the principal.
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
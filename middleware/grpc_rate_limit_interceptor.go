@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"github.com/ijalalfrz/coinbit-test/auth"
+	"github.com/ijalalfrz/coinbit-test/ratelimit"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// retryAfterMetadata is the header metadata of a limited call holding the seconds to wait.
+const retryAfterMetadata = "retry-after"
+
+// walletRequest is a request targeting a wallet.
+type walletRequest interface {
+	GetWalletId() string
+}
+
+// RateLimitIPUnaryInterceptor returns interceptor which limits the calls of every peer ip address with limit, the same
+// as the RateLimitIP middleware. It must be chained before AuthUnaryInterceptor.
+func RateLimitIPUnaryInterceptor(logger *logrus.Logger, store ratelimit.Store, limit ratelimit.Limit) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := limitPeer(ctx, logger, store, limit); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// RateLimitIPStreamInterceptor returns the streaming counterpart of RateLimitIPUnaryInterceptor, the call is limited
+// when it is opened. It must be chained before AuthStreamInterceptor.
+func RateLimitIPStreamInterceptor(logger *logrus.Logger, store ratelimit.Store, limit ratelimit.Limit) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := limitPeer(ss.Context(), logger, store, limit); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// RateLimitUnaryInterceptor returns interceptor which limits the calls of every client and to every wallet with the
+// policy of the full method name, the same as the RateLimit middleware. The wallet is the wallet_id of the request.
+// A limited call fails with ResourceExhausted and the retry-after header metadata.
+// It must be chained after AuthUnaryInterceptor so the principal is known.
+func RateLimitUnaryInterceptor(logger *logrus.Logger, store ratelimit.Store, policies map[string]ratelimit.Policy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := limitCall(ctx, logger, store, policies, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// RateLimitStreamInterceptor returns the streaming counterpart of RateLimitUnaryInterceptor, the call is limited
+// when its first request is received because the wallet is only known then.
+func RateLimitStreamInterceptor(logger *logrus.Logger, store ratelimit.Store, policies map[string]ratelimit.Policy) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if _, ok := policies[info.FullMethod]; !ok {
+			return handler(srv, ss)
+		}
+		return handler(srv, &rateLimitedStream{
+			ServerStream: ss,
+			limit: func(req interface{}) error {
+				return limitCall(ss.Context(), logger, store, policies, info.FullMethod, req)
+			},
+		})
+	}
+}
+
+// limitCall will take the tokens of the call and return ResourceExhausted when it is limited.
+func limitCall(ctx context.Context, logger *logrus.Logger, store ratelimit.Store, policies map[string]ratelimit.Policy, method string, req interface{}) error {
+	policy, ok := policies[method]
+	if !ok {
+		return nil
+	}
+
+	walletId := ""
+	if r, ok := req.(walletRequest); ok {
+		walletId = r.GetWalletId()
+	}
+	retryAfter, limited := takeRateLimit(ctx, logger, store, rateLimitBuckets(method, policy, callClientKey(ctx), walletId))
+	if !limited {
+		return nil
+	}
+	return rateLimitedCall(ctx, retryAfter)
+}
+
+// limitPeer will take the token of the peer ip address and return ResourceExhausted when it is limited.
+func limitPeer(ctx context.Context, logger *logrus.Logger, store ratelimit.Store, limit ratelimit.Limit) error {
+	policy := ratelimit.Policy{Name: ipRateLimitName, Client: limit}
+	retryAfter, limited := takeRateLimit(ctx, logger, store, rateLimitBuckets(ipRateLimitName, policy, "ip:"+peerIP(ctx), ""))
+	if !limited {
+		return nil
+	}
+	return rateLimitedCall(ctx, retryAfter)
+}
+
+// rateLimitedCall will set the retry-after header metadata and return ResourceExhausted.
+func rateLimitedCall(ctx context.Context, retryAfter int) error {
+	grpc.SetHeader(ctx, metadata.Pairs(retryAfterMetadata, strconv.Itoa(retryAfter)))
+	return status.Error(codes.ResourceExhausted, rateLimitedMessage)
+}
+
+// callClientKey returns the client of the call, the authenticated principal or the peer ip address, the same as
+// the http client.
+func callClientKey(ctx context.Context) string {
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		return principal.Kind + ":" + principal.Subject
+	}
+	return "ip:" + peerIP(ctx)
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// rateLimitedStream is a server stream whose first received request is limited.
+type rateLimitedStream struct {
+	grpc.ServerStream
+	limit    func(req interface{}) error
+	received bool
+}
+
+// RecvMsg will receive the request and limit the first one.
+func (s *rateLimitedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if s.received {
+		return nil
+	}
+	s.received = true
+	return s.limit(m)
+}
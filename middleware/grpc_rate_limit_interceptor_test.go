@@ -0,0 +1,132 @@
+package middleware_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/ijalalfrz/coinbit-test/auth"
+	"github.com/ijalalfrz/coinbit-test/middleware"
+	"github.com/ijalalfrz/coinbit-test/model"
+	"github.com/ijalalfrz/coinbit-test/ratelimit"
+	"github.com/ijalalfrz/coinbit-test/wallet"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// watchStream is a server stream receiving a watch request of walletId.
+type watchStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	walletId string
+}
+
+func (s watchStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s watchStream) RecvMsg(m interface{}) error {
+	m.(*model.WatchWalletRequest).WalletId = s.walletId
+	return nil
+}
+
+func TestRateLimitUnaryInterceptor(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	policy := ratelimit.Policy{Name: "details", Client: ratelimit.Limit{Rate: 0.001, Burst: 2}}
+	interceptor := middleware.RateLimitUnaryInterceptor(logrus.New(), store, map[string]ratelimit.Policy{wallet.GetDetailMethod: policy})
+	ctx := auth.ContextWithPrincipal(context.TODO(), auth.Principal{Kind: auth.KindService, Subject: "api-key-1"})
+	info := &grpc.UnaryServerInfo{FullMethod: wallet.GetDetailMethod}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &model.WalletDetail{}, nil
+	}
+
+	t.Run("when call is within the limit", func(t *testing.T) {
+		resp, err := interceptor(ctx, &model.GetDetailRequest{WalletId: "1"}, info, handler)
+
+		assert.Nil(t, err)
+		assert.NotNil(t, resp)
+	})
+
+	t.Run("when limit is shared with the http route", func(t *testing.T) {
+		router := mux.NewRouter()
+		router.Use(middleware.RateLimit(logrus.New(), store, map[string]ratelimit.Policy{"/wallet/v1/details/{walletId}": policy}))
+		router.HandleFunc("/wallet/v1/details/{walletId}", func(w http.ResponseWriter, r *http.Request) {})
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/wallet/v1/details/1", nil).WithContext(ctx))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		_, err := interceptor(ctx, &model.GetDetailRequest{WalletId: "1"}, info, handler)
+
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	})
+
+	t.Run("when method has no policy", func(t *testing.T) {
+		_, err := interceptor(ctx, &model.DepositRequest{WalletId: "1"}, &grpc.UnaryServerInfo{FullMethod: wallet.DepositMethod}, handler)
+
+		assert.Nil(t, err)
+	})
+}
+
+func TestRateLimitStreamInterceptor(t *testing.T) {
+	interceptor := middleware.RateLimitStreamInterceptor(logrus.New(), ratelimit.NewMemoryStore(), map[string]ratelimit.Policy{
+		wallet.WatchWalletMethod: {Wallet: ratelimit.Limit{Rate: 0.001, Burst: 1}},
+	})
+	info := &grpc.StreamServerInfo{FullMethod: wallet.WatchWalletMethod, IsServerStream: true}
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		return stream.RecvMsg(new(model.WatchWalletRequest))
+	}
+
+	t.Run("when first watch of wallet is received", func(t *testing.T) {
+		err := interceptor(nil, watchStream{ctx: context.TODO(), walletId: "1"}, info, handler)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("when wallet limit is reached", func(t *testing.T) {
+		err := interceptor(nil, watchStream{ctx: context.TODO(), walletId: "1"}, info, handler)
+
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	})
+
+	t.Run("when another wallet is watched", func(t *testing.T) {
+		err := interceptor(nil, watchStream{ctx: context.TODO(), walletId: "2"}, info, handler)
+
+		assert.Nil(t, err)
+	})
+}
+
+func TestRateLimitIPInterceptors(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	limit := ratelimit.Limit{Rate: 0.001, Burst: 1}
+	unary := middleware.RateLimitIPUnaryInterceptor(logrus.New(), store, limit)
+	stream := middleware.RateLimitIPStreamInterceptor(logrus.New(), store, limit)
+	ctx := peer.NewContext(context.TODO(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5000}})
+	info := &grpc.UnaryServerInfo{FullMethod: wallet.GetDetailMethod}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &model.WalletDetail{}, nil
+	}
+
+	t.Run("when first call of the ip address is received", func(t *testing.T) {
+		_, err := unary(ctx, &model.GetDetailRequest{WalletId: "1"}, info, handler)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("when ip limit is reached by a stream", func(t *testing.T) {
+		reached := false
+		err := stream(nil, watchStream{ctx: ctx, walletId: "1"}, &grpc.StreamServerInfo{FullMethod: wallet.WatchWalletMethod}, func(srv interface{}, stream grpc.ServerStream) error {
+			reached = true
+			return nil
+		})
+
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		assert.False(t, reached, "should not open the stream")
+	})
+}
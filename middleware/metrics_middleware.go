@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/ijalalfrz/coinbit-test/metrics"
+)
+
+// Metrics returns middleware which records request count and latency per route template.
+// It must be registered with router.Use so the matched route is known.
+func Metrics(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(r)
+
+		m := httpsnoop.CaptureMetrics(handler, w, r)
+
+		metrics.HTTPRequestsTotal.WithLabelValues(r.Method, route, strconv.Itoa(m.Code)).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(r.Method, route).Observe(m.Duration.Seconds())
+	})
+}
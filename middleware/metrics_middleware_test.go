@@ -0,0 +1,34 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/ijalalfrz/coinbit-test/metrics"
+	"github.com/ijalalfrz/coinbit-test/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(middleware.Metrics)
+	router.HandleFunc("/wallet/v1/metrics-test/{walletId}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	counter := metrics.HTTPRequestsTotal.WithLabelValues(http.MethodGet, "/wallet/v1/metrics-test/{walletId}", "202")
+	histogram := metrics.HTTPRequestDuration.WithLabelValues(http.MethodGet, "/wallet/v1/metrics-test/{walletId}").(prometheus.Histogram)
+	before := testutil.ToFloat64(counter)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/wallet/v1/metrics-test/1", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/wallet/v1/metrics-test/2", nil))
+
+	assert.Equal(t, before+2, testutil.ToFloat64(counter), "should count the requests by route template")
+	observed := &dto.Metric{}
+	assert.Nil(t, histogram.Write(observed))
+	assert.Equal(t, uint64(2), observed.GetHistogram().GetSampleCount(), "should observe the latency by route template")
+}
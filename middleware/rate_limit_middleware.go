@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/ijalalfrz/coinbit-test/auth"
+	"github.com/ijalalfrz/coinbit-test/exception"
+	"github.com/ijalalfrz/coinbit-test/ratelimit"
+	"github.com/ijalalfrz/coinbit-test/response"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	rateLimitedMessage = "Too many requests, retry later"
+	// maxWalletBodySize is how much of the body is read to find the target wallet id.
+	maxWalletBodySize = 1 << 20
+	// ipRateLimitName is the name of the buckets of the ip limit.
+	ipRateLimitName = "ip"
+)
+
+// RateLimitIP returns middleware which limits the requests of every remote ip address with limit. The requests
+// are limited before they are authenticated so a flood of failed authentications is limited as well.
+// It must be registered with router.Use before Auth. When the store fails the request is let through.
+func RateLimitIP(logger *logrus.Logger, store ratelimit.Store, limit ratelimit.Limit) mux.MiddlewareFunc {
+	policy := ratelimit.Policy{Name: ipRateLimitName, Client: limit}
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			retryAfter, limited := takeRateLimit(r.Context(), logger, store, rateLimitBuckets(ipRateLimitName, policy, "ip:"+remoteIP(r), ""))
+			if limited {
+				rejectRateLimited(w, r, retryAfter)
+				return
+			}
+
+			handler.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RateLimit returns middleware which limits the requests of every client and to every wallet with the
+// policy of the route path template. Routes without policy are not limited. The client is the authenticated
+// principal or the remote ip address, the wallet is the walletId path variable or the wallet_id of the json body.
+// It must be registered with router.Use after Auth so the principal is known.
+// When the store fails the request is let through.
+func RateLimit(logger *logrus.Logger, store ratelimit.Store, policies map[string]ratelimit.Policy) mux.MiddlewareFunc {
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := routeTemplate(r)
+			policy, ok := policies[route]
+			if !ok {
+				handler.ServeHTTP(w, r)
+				return
+			}
+
+			walletId := ""
+			if policy.Wallet.Enabled() {
+				walletId = targetWallet(r)
+			}
+			retryAfter, limited := takeRateLimit(r.Context(), logger, store, rateLimitBuckets(route, policy, clientKey(r), walletId))
+			if limited {
+				rejectRateLimited(w, r, retryAfter)
+				return
+			}
+
+			handler.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rejectRateLimited will answer 429 with the seconds to wait.
+func rejectRateLimited(w http.ResponseWriter, r *http.Request, retryAfter int) {
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	response.Negotiate(w, r, response.FromError(exception.New(exception.KindTooManyRequests, rateLimitedMessage)))
+}
+
+// rateLimitBuckets returns the buckets of policy for the client and the wallet, they are named after the policy
+// so the routes and grpc methods sharing a policy share the buckets.
+func rateLimitBuckets(route string, policy ratelimit.Policy, client, walletId string) (buckets []ratelimit.Bucket) {
+	name := policy.Name
+	if name == "" {
+		name = route
+	}
+	if policy.Client.Enabled() {
+		buckets = append(buckets, ratelimit.Bucket{Key: name + ":client:" + client, Limit: policy.Client})
+	}
+	if policy.Wallet.Enabled() && walletId != "" {
+		buckets = append(buckets, ratelimit.Bucket{Key: name + ":wallet:" + walletId, Limit: policy.Wallet})
+	}
+	return
+}
+
+// takeRateLimit will take a token of every bucket and return the seconds to wait when the request is limited,
+// the request is not limited when the store fails.
+func takeRateLimit(ctx context.Context, logger *logrus.Logger, store ratelimit.Store, buckets []ratelimit.Bucket) (retryAfter int, limited bool) {
+	if len(buckets) == 0 {
+		return
+	}
+	result, err := store.Take(ctx, buckets...)
+	if err != nil {
+		logger.WithContext(ctx).Errorf("Rate limit store failed, request is not limited: %v", err)
+		return
+	}
+	if result.Allowed {
+		return
+	}
+
+	retryAfter = int(math.Ceil(result.RetryAfter.Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	return retryAfter, true
+}
+
+func clientKey(r *http.Request) string {
+	if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+		return principal.Kind + ":" + principal.Subject
+	}
+	return "ip:" + remoteIP(r)
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// targetWallet will find the wallet id of the request, the body is restored for the handler.
+func targetWallet(r *http.Request) string {
+	if walletId, ok := mux.Vars(r)["walletId"]; ok {
+		return walletId
+	}
+	if r.Body == nil {
+		return ""
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxWalletBodySize))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+	if err != nil {
+		return ""
+	}
+
+	var payload struct {
+		WalletId string `json:"wallet_id"`
+	}
+	if json.Unmarshal(body, &payload) != nil {
+		return ""
+	}
+	return payload.WalletId
+}
@@ -0,0 +1,152 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/ijalalfrz/coinbit-test/auth"
+	"github.com/ijalalfrz/coinbit-test/exception"
+	"github.com/ijalalfrz/coinbit-test/middleware"
+	"github.com/ijalalfrz/coinbit-test/ratelimit"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingStore is a rate limit store which is not reachable.
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, buckets ...ratelimit.Bucket) (result ratelimit.Result, err error) {
+	err = errors.New("store is not reachable")
+	return
+}
+
+// newRateLimitedRouter returns a router limiting the deposit route with policy, the bodies read by the handler
+// are recorded.
+func newRateLimitedRouter(store ratelimit.Store, policy ratelimit.Policy, bodies *[]string) *mux.Router {
+	router := mux.NewRouter()
+	router.Use(middleware.RateLimit(logrus.New(), store, map[string]ratelimit.Policy{"/wallet/v1/deposit": policy}))
+	router.HandleFunc("/wallet/v1/deposit", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		*bodies = append(*bodies, string(body))
+		w.WriteHeader(http.StatusAccepted)
+	})
+	router.HandleFunc("/wallet/v1/unlimited", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	return router
+}
+
+func newDepositRequest(walletId string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/wallet/v1/deposit", strings.NewReader(`{"wallet_id":"`+walletId+`","amount":100}`))
+	r.RemoteAddr = "10.0.0.1:5000"
+	return r
+}
+
+func TestRateLimit(t *testing.T) {
+	policy := ratelimit.Policy{
+		Client: ratelimit.Limit{Rate: 0.001, Burst: 2},
+		Wallet: ratelimit.Limit{Rate: 0.001, Burst: 1},
+	}
+
+	t.Run("when request is within the limits", func(t *testing.T) {
+		var bodies []string
+		router := newRateLimitedRouter(ratelimit.NewMemoryStore(), policy, &bodies)
+		recorder := httptest.NewRecorder()
+
+		router.ServeHTTP(recorder, newDepositRequest("1"))
+
+		assert.Equal(t, http.StatusAccepted, recorder.Code)
+		assert.Equal(t, []string{`{"wallet_id":"1","amount":100}`}, bodies, "should restore the body for the handler")
+	})
+
+	t.Run("when wallet limit is reached", func(t *testing.T) {
+		var bodies []string
+		router := newRateLimitedRouter(ratelimit.NewMemoryStore(), policy, &bodies)
+		router.ServeHTTP(httptest.NewRecorder(), newDepositRequest("1"))
+		recorder := httptest.NewRecorder()
+
+		router.ServeHTTP(recorder, newDepositRequest("1"))
+
+		assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+		retryAfter, err := strconv.Atoi(recorder.Header().Get("Retry-After"))
+		assert.Nil(t, err)
+		assert.GreaterOrEqual(t, retryAfter, 1)
+		assert.Contains(t, recorder.Body.String(), exception.CodeTooManyRequests)
+		assert.Len(t, bodies, 1, "should not reach the handler")
+	})
+
+	t.Run("when a limited request does not take the client tokens", func(t *testing.T) {
+		var bodies []string
+		router := newRateLimitedRouter(ratelimit.NewMemoryStore(), policy, &bodies)
+		codes := []int{}
+		for _, walletId := range []string{"1", "1", "2", "3"} {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, newDepositRequest(walletId))
+			codes = append(codes, recorder.Code)
+		}
+
+		assert.Equal(t, []int{http.StatusAccepted, http.StatusTooManyRequests, http.StatusAccepted, http.StatusTooManyRequests}, codes)
+	})
+
+	t.Run("when route has no policy", func(t *testing.T) {
+		var bodies []string
+		router := newRateLimitedRouter(ratelimit.NewMemoryStore(), policy, &bodies)
+		for i := 0; i < 3; i++ {
+			recorder := httptest.NewRecorder()
+
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/wallet/v1/unlimited", nil))
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+		}
+	})
+
+	t.Run("when store fails", func(t *testing.T) {
+		var bodies []string
+		router := newRateLimitedRouter(failingStore{}, policy, &bodies)
+		recorder := httptest.NewRecorder()
+
+		router.ServeHTTP(recorder, newDepositRequest("1"))
+
+		assert.Equal(t, http.StatusAccepted, recorder.Code)
+	})
+}
+
+func TestRateLimitIP(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(middleware.RateLimitIP(logrus.New(), ratelimit.NewMemoryStore(), ratelimit.Limit{Rate: 0.001, Burst: 2}))
+	router.Use(middleware.Auth(logrus.New(), auth.NewAuthenticator([]string{"secret"}, nil, "", "", "")))
+	router.HandleFunc("/wallet/v1/deposit", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	t.Run("when failed authentications reach the limit", func(t *testing.T) {
+		codes := []int{}
+		for i := 0; i < 3; i++ {
+			recorder := httptest.NewRecorder()
+			r := newDepositRequest("1")
+			r.Header.Set(auth.APIKeyHeader, "guess")
+			router.ServeHTTP(recorder, r)
+			codes = append(codes, recorder.Code)
+		}
+
+		assert.Equal(t, []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}, codes)
+	})
+
+	t.Run("when request comes from another ip address", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		r := newDepositRequest("1")
+		r.RemoteAddr = "10.0.0.2:5000"
+		r.Header.Set(auth.APIKeyHeader, "secret")
+
+		router.ServeHTTP(recorder, r)
+
+		assert.Equal(t, http.StatusAccepted, recorder.Code)
+	})
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RequestIDHeader is the header used to propagate the request id.
+const RequestIDHeader = "X-Request-ID"
+
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// RequestID returns middleware which reuses the X-Request-ID header of the request or assigns a new one,
+// stores it in the request context and echoes it in the response.
+func RequestID(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		r.Header.Set(RequestIDHeader, id)
+		handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// RequestIDFromContext returns the request id stored by RequestID, empty when there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestIDHook is a logrus hook which adds the request id to every entry logged with WithContext.
+type RequestIDHook struct{}
+
+// Levels returns all levels.
+func (RequestIDHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire will add the request_id field when the entry context holds a request id.
+func (RequestIDHook) Fire(entry *logrus.Entry) error {
+	if id := RequestIDFromContext(entry.Context); id != "" {
+		entry.Data["request_id"] = id
+	}
+	return nil
+}
+
+// validRequestID accepts ids up to 128 characters of letters, digits, '-', '_' and '.'.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,73 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ijalalfrz/coinbit-test/middleware"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	var id string
+	handler := middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id = middleware.RequestIDFromContext(r.Context())
+	}))
+
+	t.Run("when request has a valid id", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/wallet", nil)
+		r.Header.Set(middleware.RequestIDHeader, "req-1.a_b")
+
+		handler.ServeHTTP(recorder, r)
+
+		assert.Equal(t, "req-1.a_b", id)
+		assert.Equal(t, "req-1.a_b", recorder.Header().Get(middleware.RequestIDHeader))
+	})
+
+	invalidIds := map[string]string{
+		"when request has no id":         "",
+		"when id has invalid characters": "id with spaces",
+		"when id is too long":            strings.Repeat("a", 129),
+	}
+	for name, invalidId := range invalidIds {
+		t.Run(name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/wallet", nil)
+			r.Header.Set(middleware.RequestIDHeader, invalidId)
+
+			handler.ServeHTTP(recorder, r)
+
+			assert.Regexp(t, regexp.MustCompile("^[0-9a-f]{32}$"), id)
+			assert.Equal(t, id, recorder.Header().Get(middleware.RequestIDHeader))
+		})
+	}
+}
+
+func TestRequestIDHook(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	logger.AddHook(middleware.RequestIDHook{})
+	handler := middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.WithContext(r.Context()).Info("handled")
+	}))
+
+	t.Run("when entry is logged with the request context", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/wallet", nil)
+		r.Header.Set(middleware.RequestIDHeader, "req-1")
+
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+
+		assert.Equal(t, "req-1", hook.LastEntry().Data["request_id"])
+	})
+
+	t.Run("when entry is logged without request id", func(t *testing.T) {
+		logger.WithContext(context.TODO()).Info("background")
+
+		assert.NotContains(t, hook.LastEntry().Data, "request_id")
+	})
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gorilla/mux"
+	"github.com/ijalalfrz/coinbit-test/exception"
+	"github.com/ijalalfrz/coinbit-test/response"
+	"github.com/sirupsen/logrus"
+)
+
+// collection of validation message, the decoder errors are not sent to the clients
+const (
+	invalidRequestMessage = "Request does not match the api specification"
+	invalidBodyMessage    = "Request body must be valid json"
+)
+
+// RequestValidation returns middleware which checks the parameters and the body of the requests described
+// by the OpenAPI specification. Invalid requests get 400, or 422 when the body is not json like the handlers,
+// the other requests are passed as is. Authentication is left to the Auth middleware.
+func RequestValidation(logger *logrus.Logger, router routers.Router) mux.MiddlewareFunc {
+	options := &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc}
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, pathParams, err := router.FindRoute(r)
+			if err != nil {
+				handler.ServeHTTP(w, r)
+				return
+			}
+			// the handlers decode json whatever the content type is, so a missing one is json too
+			if route.Operation.RequestBody != nil && r.Header.Get("Content-Type") == "" {
+				r.Header.Set("Content-Type", "application/json")
+			}
+
+			err = openapi3filter.ValidateRequest(r.Context(), &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: pathParams,
+				Route:      route,
+				Options:    options,
+			})
+			if err != nil {
+				logger.WithContext(r.Context()).Infof("Request does not match the specification: %v", err)
+				response.Negotiate(w, r, response.FromError(validationError(err)))
+				return
+			}
+
+			handler.ServeHTTP(w, r)
+		})
+	}
+}
+
+// validationError returns the domain error of the validation error with a short message.
+func validationError(err error) *exception.Error {
+	var requestErr *openapi3filter.RequestError
+	if !errors.As(err, &requestErr) {
+		return exception.Wrap(exception.KindBadRequest, err, invalidRequestMessage)
+	}
+
+	var parseErr *openapi3filter.ParseError
+	if requestErr.RequestBody != nil && errors.As(err, &parseErr) {
+		return exception.Wrap(exception.KindUnprocessableEntity, err, invalidBodyMessage)
+	}
+
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		field := strings.Join(schemaErr.JSONPointer(), ".")
+		if requestErr.Parameter != nil {
+			field = requestErr.Parameter.Name
+		}
+		return exception.Wrap(exception.KindBadRequest, err, fmt.Sprintf("Invalid '%s': %s", field, schemaErr.Reason))
+	}
+	if requestErr.Parameter != nil {
+		reason := requestErr.Reason
+		if errors.As(err, &parseErr) && requestErr.Parameter.Schema != nil {
+			reason = fmt.Sprintf("must be a %s", requestErr.Parameter.Schema.Value.Type)
+		}
+		return exception.Wrap(exception.KindBadRequest, err, fmt.Sprintf("Invalid '%s': %s", requestErr.Parameter.Name, reason))
+	}
+	return exception.Wrap(exception.KindBadRequest, err, invalidRequestMessage)
+}
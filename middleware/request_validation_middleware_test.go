@@ -0,0 +1,84 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/ijalalfrz/coinbit-test/exception"
+	"github.com/ijalalfrz/coinbit-test/middleware"
+	"github.com/ijalalfrz/coinbit-test/openapi"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestValidation(t *testing.T) {
+	specRouter, err := openapi.NewRouter()
+	assert.Nil(t, err)
+	reached := false
+	router := mux.NewRouter()
+	router.Use(middleware.RequestValidation(logrus.New(), specRouter))
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		w.WriteHeader(http.StatusOK)
+	}
+	router.HandleFunc("/wallet/v1/deposit", handler)
+	router.HandleFunc("/wallet/v1/wallets", handler)
+	router.HandleFunc("/wallet/v1/undocumented", handler)
+
+	requests := map[string]struct {
+		request    func() *http.Request
+		statusCode int
+		body       []string
+	}{
+		"when body is valid without content type": {
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/wallet/v1/deposit", strings.NewReader(`{"wallet_id":"1","amount":-100}`))
+			},
+			statusCode: http.StatusOK,
+		},
+		"when body does not match the schema": {
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/wallet/v1/deposit", strings.NewReader(`{"wallet_id":"1","amount":0}`))
+			},
+			statusCode: http.StatusBadRequest,
+			body:       []string{exception.CodeBadRequest, "Invalid 'amount'"},
+		},
+		"when body is not json": {
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/wallet/v1/deposit", strings.NewReader(`{"wallet_id":`))
+			},
+			statusCode: http.StatusUnprocessableEntity,
+			body:       []string{exception.CodeUnprocessableEntity, "Request body must be valid json"},
+		},
+		"when parameter is invalid": {
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/wallet/v1/wallets?limit=0", nil)
+			},
+			statusCode: http.StatusBadRequest,
+			body:       []string{exception.CodeBadRequest, "Invalid 'limit'"},
+		},
+		"when route is not described": {
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/wallet/v1/undocumented", nil)
+			},
+			statusCode: http.StatusOK,
+		},
+	}
+	for name, tc := range requests {
+		t.Run(name, func(t *testing.T) {
+			reached = false
+			recorder := httptest.NewRecorder()
+
+			router.ServeHTTP(recorder, tc.request())
+
+			assert.Equal(t, tc.statusCode, recorder.Code)
+			assert.Equal(t, tc.statusCode == http.StatusOK, reached)
+			for _, part := range tc.body {
+				assert.Contains(t, recorder.Body.String(), part)
+			}
+		})
+	}
+}
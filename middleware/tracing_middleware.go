@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.elastic.co/apm"
+	"go.elastic.co/apm/module/apmhttp"
+)
+
+// Tracing returns middleware which starts an apm transaction named after the route template,
+// the transaction is stored in the request context so it is propagated to the published messages.
+// It must be registered with router.Use so the matched route is known.
+func Tracing(tracer *apm.Tracer) mux.MiddlewareFunc {
+	return func(handler http.Handler) http.Handler {
+		return apmhttp.Wrap(handler,
+			apmhttp.WithTracer(tracer),
+			apmhttp.WithServerRequestName(func(r *http.Request) string {
+				return r.Method + " " + routeTemplate(r)
+			}),
+		)
+	}
+}
+
+// routeTemplate returns the path template of the matched route.
+func routeTemplate(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return "unknown"
+}
@@ -0,0 +1,61 @@
+package openapi
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	specPath = "/wallet/openapi.json"
+	docsPath = "/wallet/docs"
+)
+
+// redocScript is the pinned redoc bundle, a new version is only used when it is changed here.
+const redocScript = "https://cdn.redoc.ly/redoc/v2.1.5/bundles/redoc.standalone.js"
+
+// docsPage renders the specification with redoc.
+const docsPage = `<!DOCTYPE html>
+<html>
+  <head>
+    <title>Wallet service api</title>
+    <meta charset="utf-8"/>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+  </head>
+  <body>
+    <redoc spec-url="` + specPath + `"></redoc>
+    <script src="` + redocScript + `" crossorigin="anonymous"></script>
+  </body>
+</html>
+`
+
+// HTTPHandler is a concrete struct of api docs http handler.
+type HTTPHandler struct {
+	Logger *logrus.Logger
+}
+
+// NewOpenAPIHTTPHandler will register the specification and the docs page endpoint
+func NewOpenAPIHTTPHandler(logger *logrus.Logger, router *mux.Router) {
+	handler := &HTTPHandler{
+		Logger: logger,
+	}
+	router.HandleFunc(specPath, handler.Spec).Methods(http.MethodGet)
+	router.HandleFunc(docsPath, handler.Docs).Methods(http.MethodGet)
+}
+
+// Spec is a function to serve the OpenAPI specification
+func (handler HTTPHandler) Spec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(spec); err != nil {
+		handler.Logger.WithContext(r.Context()).Warnf("OpenAPI specification is not sent: %v", err)
+	}
+}
+
+// Docs is a function to serve the api docs page
+func (handler HTTPHandler) Docs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := w.Write([]byte(docsPage)); err != nil {
+		handler.Logger.WithContext(r.Context()).Warnf("Docs page is not sent: %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package openapi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/ijalalfrz/coinbit-test/openapi"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenAPIHTTPHandler(t *testing.T) {
+	router := mux.NewRouter()
+	openapi.NewOpenAPIHTTPHandler(logrus.New(), router)
+
+	t.Run("when specification is requested", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/wallet/openapi.json", nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, openapi.Spec(), recorder.Body.Bytes())
+	})
+
+	t.Run("when docs page is requested", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/wallet/docs", nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `spec-url="/wallet/openapi.json"`)
+		assert.NotContains(t, recorder.Body.String(), "/latest/", "should load a pinned redoc version")
+	})
+}
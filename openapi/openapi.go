@@ -0,0 +1,42 @@
+// Package openapi holds the OpenAPI specification of the wallet http api.
+package openapi
+
+import (
+	"context"
+	_ "embed" // for the specification
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+// Spec returns the OpenAPI specification as json.
+func Spec() []byte {
+	return spec
+}
+
+// Load will parse and validate the specification.
+func Load() (doc *openapi3.T, err error) {
+	doc, err = openapi3.NewLoader().LoadFromData(spec)
+	if err != nil {
+		err = fmt.Errorf("openapi specification cannot be loaded: %w", err)
+		return
+	}
+	if err = doc.Validate(context.Background()); err != nil {
+		err = fmt.Errorf("openapi specification is invalid: %w", err)
+	}
+	return
+}
+
+// NewRouter returns the router finding the operation of a request in the specification.
+func NewRouter() (router routers.Router, err error) {
+	doc, err := Load()
+	if err != nil {
+		return
+	}
+	return gorillamux.NewRouter(doc)
+}
@@ -0,0 +1,244 @@
+package openapi_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/ijalalfrz/coinbit-test/entity"
+	"github.com/ijalalfrz/coinbit-test/exception"
+	"github.com/ijalalfrz/coinbit-test/openapi"
+	"github.com/ijalalfrz/coinbit-test/response"
+	"github.com/ijalalfrz/coinbit-test/wallet"
+	"github.com/ijalalfrz/coinbit-test/wallet/mocks"
+	"github.com/ijalalfrz/coinbit-test/webmodel"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLoad(t *testing.T) {
+	doc, err := openapi.Load()
+
+	assert.Nil(t, err)
+	for _, path := range []string{wallet.DepositPath, wallet.DetailsPath, wallet.BatchDetailsPath, wallet.WalletsPath, wallet.BalanceAtPath, wallet.StatementsPath, wallet.StreamPath} {
+		assert.NotNil(t, doc.Paths.Find(path), "%s should be described", path)
+	}
+}
+
+// jsonFields returns the json names of the struct fields.
+func jsonFields(value interface{}) (fields []string) {
+	typ := reflect.TypeOf(value)
+	for i := 0; i < typ.NumField(); i++ {
+		name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return
+}
+
+func schemaFields(schema *openapi3.Schema) (fields []string) {
+	for name := range schema.Properties {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	return
+}
+
+func TestSpec_Schemas(t *testing.T) {
+	doc, err := openapi.Load()
+	assert.Nil(t, err)
+
+	models := map[string]interface{}{
+		"DepositWalletPayload":     webmodel.DepositWalletPayload{},
+		"BatchDetailWalletPayload": webmodel.BatchDetailWalletPayload{},
+		"BatchDetailWalletItem":    webmodel.BatchDetailWalletItem{},
+		"BalanceAtResponse":        webmodel.BalanceAtResponse{},
+		"StatementResponse":        webmodel.StatementResponse{},
+		"StatementBucket":          entity.StatementBucket{},
+		"DepositWalletResponse":    webmodel.DepositWalletResponse{},
+		"DetailWalletResponse":     webmodel.DetailWalletResponse{},
+		"PageMeta":                 webmodel.PageMeta{},
+		"Wallet":                   entity.Wallet{},
+	}
+	for name, model := range models {
+		t.Run(name, func(t *testing.T) {
+			schema := doc.Components.Schemas[name]
+			if assert.NotNil(t, schema) {
+				assert.Equal(t, jsonFields(model), schemaFields(schema.Value))
+			}
+		})
+	}
+}
+
+func TestSpec_ErrorCodes(t *testing.T) {
+	doc, err := openapi.Load()
+	assert.Nil(t, err)
+
+	codes := []interface{}{
+		exception.CodeUnauthorized, exception.CodeForbidden, exception.CodeNotFound, exception.CodeInternalServer,
+		exception.CodeConflict, exception.CodeUnprocessableEntity, exception.CodeBadRequest, exception.CodeGatewayTimeout,
+		exception.CodeTimeout, exception.CodeLocked, exception.CodeServiceUnavailable, exception.CodeTooManyRequests,
+		exception.CodeHistoryNotRetained,
+	}
+	assert.ElementsMatch(t, codes, doc.Components.Schemas["ErrorCode"].Value.Enum)
+}
+
+func TestSpec_DepositAmount(t *testing.T) {
+	router, err := openapi.NewRouter()
+	assert.Nil(t, err)
+	amounts := map[string]bool{"100": true, "-50": true, "0": false}
+	for amount, valid := range amounts {
+		t.Run(amount, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/wallet/v1/deposit", strings.NewReader(`{"wallet_id":"1","amount":`+amount+`}`))
+			r.Header.Set("Content-Type", "application/json")
+			route, pathParams, err := router.FindRoute(r)
+			assert.Nil(t, err)
+
+			err = openapi3filter.ValidateRequest(context.TODO(), &openapi3filter.RequestValidationInput{
+				Request: r, PathParams: pathParams, Route: route,
+				Options: &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+			})
+
+			assert.Equal(t, valid, err == nil, "%v", err)
+		})
+	}
+}
+
+// decodeCSV reads a text/csv body as a string, kin-openapi has no decoder of it.
+func decodeCSV(body io.Reader, _ http.Header, _ *openapi3.SchemaRef, _ openapi3filter.EncodingFn) (interface{}, error) {
+	data, err := ioutil.ReadAll(body)
+	return string(data), err
+}
+
+// validateResponse will check the recorded response of the request against the specification.
+func validateResponse(t *testing.T, r *http.Request, recorder *httptest.ResponseRecorder) {
+	openapi3filter.RegisterBodyDecoder(response.CSVContentType, decodeCSV)
+	router, err := openapi.NewRouter()
+	assert.Nil(t, err)
+	route, pathParams, err := router.FindRoute(r)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	err = openapi3filter.ValidateResponse(context.TODO(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{Request: r, PathParams: pathParams, Route: route},
+		Status:                 recorder.Code,
+		Header:                 recorder.Header(),
+		Body:                   ioutil.NopCloser(recorder.Body),
+		Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+	})
+	assert.Nil(t, err)
+}
+
+func TestSpec_Responses(t *testing.T) {
+	usecase := &mocks.Usecase{}
+	router := mux.NewRouter()
+	wallet.NewWalletHTTPHandler(logrus.New(), validator.New(), router, usecase)
+	aboveThreshold := false
+	usecase.On("DepositAndWait", mock.Anything, mock.Anything).Return(webmodel.DepositWalletResponse{
+		Partition:        0,
+		Offset:           3,
+		ConsistencyToken: "0:3",
+		Wallet:           &entity.Wallet{WalletId: "1", Balance: 100, LastDepositOffset: 3},
+		AboveThreshold:   &aboveThreshold,
+	}, nil)
+	usecase.On("GetDetail", mock.Anything, "1", mock.Anything).Return(webmodel.DetailWalletResponse{
+		WalletId: "1",
+		Balance:  100,
+	}, nil)
+	usecase.On("GetDetail", mock.Anything, "2", mock.Anything).Return(webmodel.DetailWalletResponse{},
+		exception.New(exception.KindNotFound, "Wallet is not found"))
+
+	usecase.On("ListWallets", mock.Anything, mock.Anything).Return([]webmodel.DetailWalletResponse{
+		{WalletId: "1", Balance: 100},
+	}, webmodel.PageMeta{Limit: 1, Count: 1, Total: 2, Sort: wallet.SortByBalance, NextCursor: "eyJzIjoiYmFsYW5jZSJ9"}, nil)
+
+	usecase.On("GetDetails", mock.Anything, []string{"1", "2"}).Return([]webmodel.BatchDetailWalletItem{
+		{WalletId: "1", Found: true, Wallet: &webmodel.DetailWalletResponse{WalletId: "1", Balance: 100}},
+		{WalletId: "2", ErrorCode: exception.CodeNotFound},
+	}, nil)
+
+	usecase.On("GetBalanceAt", mock.Anything, "1", mock.Anything).Return(webmodel.BalanceAtResponse{
+		WalletId: "1", Balance: 100, Deposits: 1, LastDepositTime: time.Date(2022, 8, 1, 10, 0, 0, 0, time.UTC), ConsistencyToken: "0:3",
+	}, nil)
+	usecase.On("GetBalanceAt", mock.Anything, "2", mock.Anything).Return(webmodel.BalanceAtResponse{},
+		exception.New(exception.KindNotFound, "Wallet has no deposit at the point in time"))
+
+	usecase.On("GetStatements", mock.Anything, "1", mock.Anything).Return(webmodel.StatementResponse{
+		WalletId:   "1",
+		Period:     wallet.StatementPeriodMonthly,
+		Statements: []entity.StatementBucket{{Period: "2022-08", Credits: 11000, ClosingBalance: 11000, Deposits: 2}},
+	}, nil)
+
+	requests := map[string]func() *http.Request{
+		"deposit": func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/wallet/v1/deposit?wait=true", strings.NewReader(`{"wallet_id":"1","amount":100}`))
+		},
+		"details": func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/wallet/v1/details/1?min_offset=0:3", nil)
+		},
+		"details not found": func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/wallet/v1/details/2", nil)
+		},
+		"details not found as problem": func() *http.Request {
+			r := httptest.NewRequest(http.MethodGet, "/wallet/v1/details/2", nil)
+			r.Header.Set("Accept", response.ProblemContentType)
+			return r
+		},
+		"details batch": func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/wallet/v1/details:batch", strings.NewReader(`{"wallet_ids":["1","2"]}`))
+		},
+		"details batch invalid": func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/wallet/v1/details:batch", strings.NewReader(`{"wallet_ids":[]}`))
+		},
+		"wallets": func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/wallet/v1/wallets?sort=balance&limit=1&above_threshold=false", nil)
+		},
+		"wallets invalid": func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/wallet/v1/wallets?limit=0", nil)
+		},
+		"balance at": func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/wallet/v1/wallets/1/balance?at=2022-08-01T23:59:59Z", nil)
+		},
+		"balance at not found": func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/wallet/v1/wallets/2/balance?at=0:3", nil)
+		},
+		"balance at invalid": func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/wallet/v1/wallets/1/balance?at=yesterday", nil)
+		},
+		"statements": func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/wallet/v1/wallets/1/statements?period=monthly", nil)
+		},
+		"statements as csv": func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/wallet/v1/wallets/1/statements?format=csv", nil)
+		},
+		"statements invalid": func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/wallet/v1/wallets/1/statements?period=weekly", nil)
+		},
+		"deposit invalid": func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/wallet/v1/deposit", strings.NewReader(`{"wallet_id":"1"}`))
+		},
+	}
+	for name, request := range requests {
+		t.Run(name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request())
+
+			validateResponse(t, request(), recorder)
+		})
+	}
+}
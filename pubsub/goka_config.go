@@ -0,0 +1,21 @@
+package pubsub
+
+import (
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/lovoo/goka"
+)
+
+// statsInterval is how often goka stats are recorded as metrics
+const statsInterval = time.Second * 10
+
+// copySaramaConfig returns a copy of the sarama config for a single goka builder,
+// goka builders modify the config they are given (e.g. client id and partitioner).
+func copySaramaConfig(config *sarama.Config) *sarama.Config {
+	if config == nil {
+		return goka.DefaultConfig()
+	}
+	c := *config
+	return &c
+}
@@ -2,56 +2,127 @@ package pubsub
 
 import (
 	"context"
+	"strconv"
+	"time"
 
+	"github.com/Shopify/sarama"
+	"github.com/ijalalfrz/coinbit-test/metrics"
 	"github.com/lovoo/goka"
 	"github.com/sirupsen/logrus"
 )
 
 type GokaConsumserGroupAdapter struct {
 	logger    *logrus.Logger
+	groupID   string
 	processor *goka.Processor
+	runErr    runError
+	cancel    context.CancelFunc
 }
 
-// NewGokaConsumerGroupFullConfigAdapter will create consumer group and group table
+// NewGokaConsumerGroupFullConfigAdapter will create consumer group and group table, the extra inputs are consumed
+// by the same group and must have as many partitions as topic
 func NewGokaConsumerGroupFullConfigAdapter(
-	logger *logrus.Logger, addresses []string, groupID string, topic string, handler GokaEventHandler,
-	topicManagerConfig *goka.TopicManagerConfig, inputCodec GokaCodec, tableCodec GokaCodec,
+	logger *logrus.Logger, addresses []string, saramaConfig *sarama.Config, groupID string, topic string, handler GokaEventHandler,
+	topicManagerConfig *goka.TopicManagerConfig, inputCodec GokaCodec, tableCodec GokaCodec, extraInputs ...GokaInput,
 ) (subscriber Subscriber, err error) {
-	g := goka.DefineGroup(goka.Group(groupID),
-		goka.Input(goka.Stream(topic), inputCodec, handler.Handle),
+	edges := []goka.Edge{
+		goka.Input(goka.Stream(topic), inputCodec, instrument(groupID, topic, handler.Handle)),
 		goka.Persist(tableCodec),
-	)
+	}
+	for _, input := range extraInputs {
+		edges = append(edges, goka.Input(goka.Stream(input.Topic), input.Codec, instrument(groupID, input.Topic, input.Handler.Handle)))
+	}
+	g := goka.DefineGroup(goka.Group(groupID), edges...)
 	p, err := goka.NewProcessor(addresses,
 		g,
-		goka.WithTopicManagerBuilder(goka.TopicManagerBuilderWithTopicManagerConfig(topicManagerConfig)),
-		goka.WithConsumerGroupBuilder(goka.DefaultConsumerGroupBuilder),
+		goka.WithTopicManagerBuilder(goka.TopicManagerBuilderWithConfig(copySaramaConfig(saramaConfig), topicManagerConfig)),
+		goka.WithConsumerGroupBuilder(goka.ConsumerGroupBuilderWithConfig(copySaramaConfig(saramaConfig))),
+		goka.WithConsumerSaramaBuilder(goka.SaramaConsumerBuilderWithConfig(copySaramaConfig(saramaConfig))),
+		goka.WithProducerBuilder(goka.ProducerBuilderWithConfig(copySaramaConfig(saramaConfig))),
 	)
 	if err != nil {
 		return
 	}
 	subscriber = &GokaConsumserGroupAdapter{
 		logger:    logger,
+		groupID:   groupID,
 		processor: p,
 	}
 
 	return
 }
 
+// instrument will record processed message count and latency of the callback
+func instrument(groupID string, topic string, callback goka.ProcessCallback) goka.ProcessCallback {
+	return func(ctx goka.Context, message interface{}) {
+		start := time.Now()
+		callback(ctx, message)
+		metrics.ProcessedMessagesTotal.WithLabelValues(groupID, topic).Inc()
+		metrics.ProcessingDuration.WithLabelValues(groupID, topic).Observe(time.Since(start).Seconds())
+	}
+}
+
 // Subscribe will consume the published message
 func (gk *GokaConsumserGroupAdapter) Subscribe() {
-	ctx := context.Background()
+	ctx, cancel := context.WithCancel(context.Background())
 	go func() {
 		if err := gk.processor.Run(ctx); err != nil {
 			gk.logger.Errorf("Error running processor: %v", err)
+			gk.runErr.set(err)
 		}
 	}()
+	go gk.reportStats(ctx)
+	gk.cancel = cancel
 
 	return
 }
 
+// reportStats will record the consumer lag of every partition periodically
+func (gk *GokaConsumserGroupAdapter) reportStats(ctx context.Context) {
+	ticker := time.NewTicker(statsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stats := gk.processor.StatsWithContext(ctx)
+			if stats == nil {
+				continue
+			}
+			for partition, partitionStats := range stats.Group {
+				for topic, input := range partitionStats.Input {
+					metrics.ConsumerLag.WithLabelValues(gk.groupID, topic, strconv.Itoa(int(partition))).Set(float64(input.OffsetLag))
+				}
+			}
+		}
+	}
+}
+
+// Health will report the processor state, a crashed processor is not live anymore
+func (gk *GokaConsumserGroupAdapter) Health(ctx context.Context) (health Health) {
+	state := gk.processor.StateReader().State()
+	health = Health{
+		Name:  "processor-" + gk.groupID,
+		State: processorStates[state],
+		Live:  true,
+	}
+	if err := gk.runErr.get(); err != nil {
+		health.State = HealthStateFailed
+		health.Live = false
+		health.Error = err.Error()
+		return
+	}
+	health.Ready = state == goka.ProcStateRunning
+	return
+}
+
 // Close will stop the kafka consumer
 func (gk *GokaConsumserGroupAdapter) Close() (err error) {
 	gk.processor.Stop()
+	if gk.cancel != nil {
+		gk.cancel()
+	}
 	gk.logger.Info("[Goka] Consumer is gracefully shut down.")
 	return
 }
@@ -2,25 +2,36 @@ package pubsub
 
 import (
 	"context"
+	"sync/atomic"
+	"time"
 
+	"github.com/Shopify/sarama"
+	"github.com/ijalalfrz/coinbit-test/metrics"
 	"github.com/lovoo/goka"
 	"github.com/sirupsen/logrus"
+	"go.elastic.co/apm"
 )
 
 // GokaProducerAdapter is a concrete struct of goka kafka adapter.
 type GokaProducerAdapter struct {
 	logger  *logrus.Logger
+	topic   string
 	emitter *goka.Emitter
+	sendErr runError
+	closed  int32
 }
 
 // NewGokaProducerAdapter will create producer for produce message to kafka
-func NewGokaProducerAdapter(logger *logrus.Logger, brokers []string, topic string, codec GokaCodec) (publisher Publisher, err error) {
-	emitter, err := goka.NewEmitter(brokers, goka.Stream(topic), codec)
+func NewGokaProducerAdapter(logger *logrus.Logger, brokers []string, saramaConfig *sarama.Config, topic string, codec GokaCodec) (publisher Publisher, err error) {
+	emitter, err := goka.NewEmitter(brokers, goka.Stream(topic), codec,
+		goka.WithEmitterProducerBuilder(goka.ProducerBuilderWithConfig(copySaramaConfig(saramaConfig))),
+	)
 	if err != nil {
 		return
 	}
 	publisher = &GokaProducerAdapter{
 		logger:  logger,
+		topic:   topic,
 		emitter: emitter,
 	}
 	return
@@ -28,6 +39,7 @@ func NewGokaProducerAdapter(logger *logrus.Logger, brokers []string, topic strin
 
 // Close will close the producer
 func (gk *GokaProducerAdapter) Close() (err error) {
+	atomic.StoreInt32(&gk.closed, 1)
 	err = gk.emitter.Finish()
 	if err == nil {
 		gk.logger.Info("[Goka] Producer is gracefully shutdown")
@@ -35,8 +47,52 @@ func (gk *GokaProducerAdapter) Close() (err error) {
 	return
 }
 
-// Send will send kafka message
-func (gk *GokaProducerAdapter) Send(ctx context.Context, key string, message interface{}) (err error) {
-	err = gk.emitter.EmitSync(key, message)
+// Send will send kafka message and wait until it is written, the trace context of ctx is propagated
+// through the message headers
+func (gk *GokaProducerAdapter) Send(ctx context.Context, key string, message interface{}) (position Position, err error) {
+	headers := MessageHeaders{}
+	span := startSendSpan(ctx, gk.topic, headers)
+	defer span.End()
+
+	start := time.Now()
+	promise, err := gk.emitter.EmitWithHeaders(key, message, headers.gokaHeaders())
+	if err == nil {
+		done := make(chan struct{})
+		promise.ThenWithMessage(func(msg *sarama.ProducerMessage, asyncErr error) {
+			err = asyncErr
+			if msg != nil {
+				position = Position{Partition: msg.Partition, Offset: msg.Offset}
+			}
+			close(done)
+		})
+		<-done
+	}
+	if err != nil {
+		apm.CaptureError(ctx, err).Send()
+	}
+	metrics.PublishDuration.WithLabelValues(gk.topic).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.PublishErrorsTotal.WithLabelValues(gk.topic).Inc()
+	}
+	gk.sendErr.set(err)
+	return
+}
+
+// Health will report the emitter state and the error of the last sent message if any,
+// the broker connectivity is reported by BrokerHealthChecker
+func (gk *GokaProducerAdapter) Health(ctx context.Context) (health Health) {
+	health = Health{
+		Name:  "emitter-" + gk.topic,
+		State: HealthStateRunning,
+		Live:  true,
+		Ready: true,
+	}
+	if atomic.LoadInt32(&gk.closed) == 1 {
+		health.State = HealthStateStopped
+		health.Ready = false
+	}
+	if err := gk.sendErr.get(); err != nil {
+		health.Error = err.Error()
+	}
 	return
 }
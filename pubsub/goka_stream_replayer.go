@@ -0,0 +1,189 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/lovoo/goka"
+	"github.com/sirupsen/logrus"
+)
+
+// quietReplayFetches is how many fetch waits a replayed partition may deliver nothing before it is done.
+const quietReplayFetches = 4
+
+// GokaStreamReplayer is a concrete struct of a replayer of a goka stream, it reads the partition of a key
+// directly with sarama.
+type GokaStreamReplayer struct {
+	logger      *logrus.Logger
+	topic       string
+	codec       GokaCodec
+	client      sarama.Client
+	partitioner sarama.Partitioner
+}
+
+// NewGokaStreamReplayer will create a replayer of the topic, the messages are decoded with codec
+func NewGokaStreamReplayer(logger *logrus.Logger, brokers []string, saramaConfig *sarama.Config, topic string, codec GokaCodec) (replayer Replayer, err error) {
+	client, err := sarama.NewClient(brokers, copySaramaConfig(saramaConfig))
+	if err != nil {
+		return
+	}
+	replayer = &GokaStreamReplayer{
+		logger: logger,
+		topic:  topic,
+		codec:  codec,
+		client: client,
+		// the emitter partitions the keys with the same hasher
+		partitioner: sarama.NewCustomHashPartitioner(goka.DefaultHasher())(topic),
+	}
+	return
+}
+
+// Partition will compute the partition of key the same way the goka emitter does
+func (gr *GokaStreamReplayer) Partition(key string) (partition int32, err error) {
+	partitions, err := gr.client.Partitions(gr.topic)
+	if err != nil {
+		return
+	}
+	return gr.partitioner.Partition(&sarama.ProducerMessage{Key: sarama.StringEncoder(key)}, int32(len(partitions)))
+}
+
+// Replay will read the partition of key from the oldest offset up to the high water mark at the time of the call,
+// the messages of the other keys are skipped, the partition is truncated when oldest is not 0
+func (gr *GokaStreamReplayer) Replay(ctx context.Context, key string, fn func(message ReplayedMessage) bool) (oldest int64, err error) {
+	partition, err := gr.Partition(key)
+	if err != nil {
+		return
+	}
+	consumer, err := sarama.NewConsumerFromClient(gr.client)
+	if err != nil {
+		return
+	}
+	defer consumer.Close()
+
+	oldest, _, err = gr.replayPartition(ctx, consumer, partition, sarama.OffsetOldest, key, fn)
+	return
+}
+
+// ReplayFrom will read the partition of from starting at its offset, or at the oldest retained one when it is
+// not retained anymore, up to the high water mark at the time of the call
+func (gr *GokaStreamReplayer) ReplayFrom(ctx context.Context, from Position, fn func(message ReplayedMessage) bool) (err error) {
+	consumer, err := sarama.NewConsumerFromClient(gr.client)
+	if err != nil {
+		return
+	}
+	defer consumer.Close()
+
+	_, _, err = gr.replayPartition(ctx, consumer, from.Partition, from.Offset, "", fn)
+	return
+}
+
+// ReplayAll will read every partition from the oldest offset up to its high water mark at the time the partition
+// is started, a partition whose oldest offset is not 0 is truncated
+func (gr *GokaStreamReplayer) ReplayAll(ctx context.Context, fn func(message ReplayedMessage) bool) (truncated []int32, err error) {
+	partitions, err := gr.client.Partitions(gr.topic)
+	if err != nil {
+		return
+	}
+	consumer, err := sarama.NewConsumerFromClient(gr.client)
+	if err != nil {
+		return
+	}
+	defer consumer.Close()
+
+	for _, partition := range partitions {
+		oldest, stopped, replayErr := gr.replayPartition(ctx, consumer, partition, sarama.OffsetOldest, "", fn)
+		if oldest > 0 {
+			truncated = append(truncated, partition)
+		}
+		if replayErr != nil || stopped {
+			return truncated, replayErr
+		}
+	}
+	return
+}
+
+// replayPartition will call fn with the decoded messages of partition from offset, or the oldest retained one, up to
+// the high water mark, only the messages of key are decoded unless it is empty. stopped is true when fn returns false.
+// The offsets before the high water mark may never be delivered (transaction markers, aborted or compacted messages),
+// the partition is done as well once a fetch has reached the high water mark and nothing is delivered for a while
+func (gr *GokaStreamReplayer) replayPartition(
+	ctx context.Context, consumer sarama.Consumer, partition int32, offset int64, key string, fn func(message ReplayedMessage) bool,
+) (oldest int64, stopped bool, err error) {
+	oldest, err = gr.client.GetOffset(gr.topic, partition, sarama.OffsetOldest)
+	if err != nil {
+		return
+	}
+	newest, err := gr.client.GetOffset(gr.topic, partition, sarama.OffsetNewest)
+	if offset < oldest {
+		offset = oldest
+	}
+	if err != nil || newest <= offset {
+		return
+	}
+
+	pc, err := consumer.ConsumePartition(gr.topic, partition, offset)
+	if err != nil {
+		return
+	}
+	defer pc.AsyncClose()
+
+	quiet := time.NewTicker(quietReplayFetches * gr.client.Config().Consumer.MaxWaitTime)
+	defer quiet.Stop()
+	received := false
+	for {
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		case consumerErr, ok := <-pc.Errors():
+			if !ok {
+				err = fmt.Errorf("partition %d of %s is closed while replaying", partition, gr.topic)
+				return
+			}
+			err = consumerErr
+			return
+		case <-quiet.C:
+			if !received && pc.HighWaterMarkOffset() >= newest {
+				return
+			}
+			received = false
+		case msg, ok := <-pc.Messages():
+			if !ok {
+				err = fmt.Errorf("partition %d of %s is closed while replaying", partition, gr.topic)
+				return
+			}
+			received = true
+			if key == "" || string(msg.Key) == key {
+				value, decodeErr := gr.codec.Decode(msg.Value)
+				if decodeErr != nil {
+					err = decodeErr
+					return
+				}
+				message := ReplayedMessage{
+					Key:       string(msg.Key),
+					Position:  Position{Partition: msg.Partition, Offset: msg.Offset},
+					Timestamp: msg.Timestamp,
+					Value:     value,
+				}
+				if !fn(message) {
+					stopped = true
+					return
+				}
+			}
+			if msg.Offset+1 >= newest {
+				return
+			}
+		}
+	}
+}
+
+// Close will close the kafka client
+func (gr *GokaStreamReplayer) Close() (err error) {
+	err = gr.client.Close()
+	if err == nil {
+		gr.logger.Infof("[Goka] Replayer of %s is gracefully shutdown", gr.topic)
+	}
+	return
+}
@@ -2,54 +2,278 @@ package pubsub
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
 
+	"github.com/Shopify/sarama"
+	"github.com/ijalalfrz/coinbit-test/metrics"
 	"github.com/lovoo/goka"
+	"github.com/lovoo/goka/storage"
 	"github.com/sirupsen/logrus"
 )
 
+// ErrViewStopped is returned while waiting for a view which is stopped without error.
+var ErrViewStopped = fmt.Errorf("view is stopped")
+
 // GokaViewTableAdapter is a concrete struct of goka group table adapter.
 type GokaViewTableAdapter struct {
-	logger *logrus.Logger
-	view   *goka.View
-	cancel context.CancelFunc
+	logger    *logrus.Logger
+	group     string
+	view      *goka.View
+	codec     GokaCodec
+	cancel    context.CancelFunc
+	runErr    runError
+	stopped   chan struct{}
+	mu        sync.RWMutex
+	listeners []UpdateListener
+	// stateListeners are guarded by mu too.
+	stateListeners []StateListener
 }
 
 // NewGokaViewTableAdapter will create goka view
-func NewGokaViewTableAdapter(logger *logrus.Logger, group string, brokers []string, codec GokaCodec) (view ViewTable, err error) {
-	v, err := goka.NewView(brokers, goka.GroupTable(goka.Group(group)), codec)
+func NewGokaViewTableAdapter(logger *logrus.Logger, group string, brokers []string, saramaConfig *sarama.Config, codec GokaCodec) (view ViewTable, err error) {
+	adapter := &GokaViewTableAdapter{
+		logger:  logger,
+		group:   group,
+		codec:   codec,
+		stopped: make(chan struct{}),
+	}
+	adapter.view, err = goka.NewView(brokers, goka.GroupTable(goka.Group(group)), codec,
+		goka.WithViewTopicManagerBuilder(goka.TopicManagerBuilderWithConfig(copySaramaConfig(saramaConfig), goka.NewTopicManagerConfig())),
+		goka.WithViewConsumerSaramaBuilder(goka.SaramaConsumerBuilderWithConfig(copySaramaConfig(saramaConfig))),
+		goka.WithViewCallback(adapter.update),
+	)
 	if err != nil {
 		return
 	}
 
-	view = &GokaViewTableAdapter{
-		logger: logger,
-		view:   v,
-	}
-
+	view = adapter
 	return
 }
 
 // Open will run the view on new goroutine
 func (gk *GokaViewTableAdapter) Open() {
 	ctx, cancel := context.WithCancel(context.Background())
+	go gk.observeState(ctx)
 	go func(ctx context.Context) {
 		if gk.view.CurrentState() != goka.ViewStateRunning {
+			defer close(gk.stopped)
 			if err := gk.view.Run(ctx); err != nil {
 				gk.logger.Errorf("Error running view: %v", err)
+				gk.runErr.set(err)
+				gk.notifyState(HealthStateFailed)
 			}
 		}
 	}(ctx)
+	go gk.reportStats(ctx)
 	gk.cancel = cancel
 	return
 
 }
 
+// reportStats will record the recovery state of the view periodically
+func (gk *GokaViewTableAdapter) reportStats(ctx context.Context) {
+	ticker := time.NewTicker(statsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			recovered := 0.0
+			if gk.view.Recovered() {
+				recovered = 1
+			}
+			metrics.ViewRecovered.WithLabelValues(gk.group).Set(recovered)
+
+			stats := gk.view.Stats(ctx)
+			if stats == nil {
+				continue
+			}
+			for partition, tableStats := range stats.Partitions {
+				if tableStats.Recovery == nil {
+					continue
+				}
+				lag := tableStats.Recovery.Hwm - tableStats.Recovery.Offset - 1
+				if lag < 0 {
+					lag = 0
+				}
+				metrics.ViewRecoveryLag.WithLabelValues(gk.group, strconv.Itoa(int(partition))).Set(float64(lag))
+			}
+		}
+	}
+}
+
+// OnUpdate will register listener to be called for every update of the table changelog,
+// the updates read while the view is recovering are included.
+func (gk *GokaViewTableAdapter) OnUpdate(listener UpdateListener) {
+	gk.mu.Lock()
+	defer gk.mu.Unlock()
+	gk.listeners = append(gk.listeners, listener)
+}
+
+// OnStateChange will register listener to be called with the health state of the view every time it changes.
+func (gk *GokaViewTableAdapter) OnStateChange(listener StateListener) {
+	gk.mu.Lock()
+	defer gk.mu.Unlock()
+	gk.stateListeners = append(gk.stateListeners, listener)
+}
+
+// observeState will notify the state listeners of the view state changes until ctx is done
+func (gk *GokaViewTableAdapter) observeState(ctx context.Context) {
+	observer := gk.view.ObserveStateChanges()
+	defer observer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case state, ok := <-observer.C():
+			if !ok {
+				return
+			}
+			gk.notifyState(viewStates[goka.ViewState(state)])
+		}
+	}
+}
+
+func (gk *GokaViewTableAdapter) notifyState(state string) {
+	gk.mu.RLock()
+	listeners := gk.stateListeners
+	gk.mu.RUnlock()
+	for _, listener := range listeners {
+		listener(state)
+	}
+}
+
+// WaitRunning will block until the view is recovered, it fails when the view is stopped before or ctx is done.
+func (gk *GokaViewTableAdapter) WaitRunning(ctx context.Context) (err error) {
+	select {
+	case <-gk.view.WaitRunning():
+		return
+	case <-gk.stopped:
+		if err = gk.runErr.get(); err == nil {
+			err = ErrViewStopped
+		}
+		return
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// update will store the changelog message and notify the listeners with the decoded value
+func (gk *GokaViewTableAdapter) update(ctx goka.UpdateContext, s storage.Storage, key string, value []byte) (err error) {
+	if err = goka.DefaultUpdate(ctx, s, key, value); err != nil {
+		return
+	}
+
+	gk.mu.RLock()
+	listeners := gk.listeners
+	gk.mu.RUnlock()
+	if len(listeners) == 0 || value == nil {
+		return
+	}
+
+	decoded, decodeErr := gk.codec.Decode(value)
+	if decodeErr != nil {
+		gk.logger.Errorf("Error decoding update of %s: %v", key, decodeErr)
+		return
+	}
+	for _, listener := range listeners {
+		listener(key, decoded)
+	}
+	return
+}
+
 // Get will return data from group table based on key
 func (gk *GokaViewTableAdapter) Get(key string) (data interface{}, err error) {
 	data, err = gk.view.Get(key)
 	return
 }
 
+// Has will return true when the group table has the key
+func (gk *GokaViewTableAdapter) Has(key string) (ok bool, err error) {
+	ok, err = gk.view.Has(key)
+	return
+}
+
+// Iterate will call fn for every key and value of the table until fn returns false
+func (gk *GokaViewTableAdapter) Iterate(fn func(key string, value interface{}) bool) (err error) {
+	it, err := gk.view.Iterator()
+	if err != nil {
+		return
+	}
+	return iterate(it, fn)
+}
+
+// IteratePrefix will call fn for every key starting with prefix and its value until fn returns false
+func (gk *GokaViewTableAdapter) IteratePrefix(prefix string, fn func(key string, value interface{}) bool) (err error) {
+	if prefix == "" {
+		return gk.Iterate(fn)
+	}
+	// goka reads the keys having start as prefix when the limit is empty
+	it, err := gk.view.IteratorWithRange(prefix, "")
+	if err != nil {
+		return
+	}
+	return iterate(it, fn)
+}
+
+// IterateRange will call fn for every key from start included to limit excluded and its value until fn returns false,
+// the keys from start are read when limit is empty
+func (gk *GokaViewTableAdapter) IterateRange(start, limit string, fn func(key string, value interface{}) bool) (err error) {
+	if limit == "" {
+		it, err := gk.view.Iterator()
+		if err != nil {
+			return err
+		}
+		// the iterator of every partition seeks the first key from start, the next key of the view is the
+		// smallest of them
+		it.Seek(start)
+		return iterate(it, fn)
+	}
+	it, err := gk.view.IteratorWithRange(start, limit)
+	if err != nil {
+		return
+	}
+	return iterate(it, fn)
+}
+
+// iterate will call fn for the keys of the iterator until fn returns false and release the iterator
+func iterate(it goka.Iterator, fn func(key string, value interface{}) bool) (err error) {
+	defer it.Release()
+
+	for it.Next() {
+		value, valueErr := it.Value()
+		if valueErr != nil {
+			return valueErr
+		}
+		if !fn(it.Key(), value) {
+			return
+		}
+	}
+	err = it.Err()
+	return
+}
+
+// Health will report the view state, the view is ready once every partition is recovered
+func (gk *GokaViewTableAdapter) Health(ctx context.Context) (health Health) {
+	health = Health{
+		Name:  "view-" + gk.group,
+		State: viewStates[gk.view.CurrentState()],
+		Live:  true,
+	}
+	if err := gk.runErr.get(); err != nil {
+		health.State = HealthStateFailed
+		health.Live = false
+		health.Error = err.Error()
+		return
+	}
+	health.Ready = gk.view.CurrentState() == goka.ViewStateRunning
+	return
+}
+
 // Close will cancel view context
 func (gk *GokaViewTableAdapter) Close() {
 	gk.cancel()
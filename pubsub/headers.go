@@ -1,5 +1,7 @@
 package pubsub
 
+import "github.com/lovoo/goka"
+
 // MessageHeaders is type of message headers
 type MessageHeaders map[string]string
 
@@ -7,3 +9,12 @@ type MessageHeaders map[string]string
 func (mh MessageHeaders) Add(key, value string) {
 	mh[key] = value
 }
+
+// gokaHeaders will convert the headers to goka headers.
+func (mh MessageHeaders) gokaHeaders() goka.Headers {
+	headers := goka.Headers{}
+	for key, value := range mh {
+		headers[key] = []byte(value)
+	}
+	return headers
+}
@@ -0,0 +1,141 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Shopify/sarama"
+	"github.com/lovoo/goka"
+)
+
+// collection of health state
+const (
+	HealthStateIdle         = "idle"
+	HealthStateStarting     = "starting"
+	HealthStateInitializing = "initializing"
+	HealthStateConnecting   = "connecting"
+	HealthStateRecovering   = "recovering"
+	HealthStateRebalancing  = "rebalancing"
+	HealthStateRunning      = "running"
+	HealthStateStopping     = "stopping"
+	HealthStateStopped      = "stopped"
+	HealthStateFailed       = "failed"
+	HealthStateConnected    = "connected"
+	HealthStateDisconnected = "disconnected"
+)
+
+// Health is a health report of a kafka client.
+type Health struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+	// Live is false when the client can not recover by itself and the application should be restarted.
+	Live bool `json:"live"`
+	// Ready is false when the client can not serve traffic yet.
+	Ready bool `json:"ready"`
+	// Error is the cause of an unhealthy state, it is logged and never answered.
+	Error string `json:"-"`
+}
+
+// HealthChecker is a collection of behavior of a component which reports its health.
+type HealthChecker interface {
+	Health(ctx context.Context) Health
+}
+
+var viewStates = map[goka.ViewState]string{
+	goka.ViewStateIdle:         HealthStateIdle,
+	goka.ViewStateInitializing: HealthStateInitializing,
+	goka.ViewStateConnecting:   HealthStateConnecting,
+	goka.ViewStateCatchUp:      HealthStateRecovering,
+	goka.ViewStateRunning:      HealthStateRunning,
+}
+
+var processorStates = map[goka.State]string{
+	goka.ProcStateIdle:     HealthStateIdle,
+	goka.ProcStateStarting: HealthStateStarting,
+	goka.ProcStateSetup:    HealthStateRebalancing,
+	goka.ProcStateRunning:  HealthStateRunning,
+	goka.ProcStateStopping: HealthStateStopping,
+}
+
+// runError keeps the error returned by a goka Run loop.
+type runError struct {
+	mu  sync.RWMutex
+	err error
+}
+
+func (re *runError) set(err error) {
+	re.mu.Lock()
+	re.err = err
+	re.mu.Unlock()
+}
+
+func (re *runError) get() error {
+	re.mu.RLock()
+	defer re.mu.RUnlock()
+	return re.err
+}
+
+// BrokerHealthChecker is a concrete struct of kafka broker connectivity checker.
+type BrokerHealthChecker struct {
+	brokers []string
+	config  *sarama.Config
+	mu      sync.Mutex
+	client  sarama.Client
+}
+
+// NewBrokerHealthChecker will create checker of broker connectivity
+func NewBrokerHealthChecker(brokers []string, saramaConfig *sarama.Config) *BrokerHealthChecker {
+	return &BrokerHealthChecker{
+		brokers: brokers,
+		config:  copySaramaConfig(saramaConfig),
+	}
+}
+
+// Health will refresh the cluster metadata to check that the brokers are reachable.
+func (bc *BrokerHealthChecker) Health(ctx context.Context) (health Health) {
+	health = Health{Name: "kafka-brokers", Live: true}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- bc.refresh()
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		health.State = HealthStateDisconnected
+		health.Error = err.Error()
+		return
+	}
+	health.State = HealthStateConnected
+	health.Ready = true
+	return
+}
+
+func (bc *BrokerHealthChecker) refresh() (err error) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	if bc.client == nil {
+		bc.client, err = sarama.NewClient(bc.brokers, bc.config)
+		if err != nil {
+			return
+		}
+	}
+	err = bc.client.RefreshMetadata()
+	return
+}
+
+// Close will close the kafka client
+func (bc *BrokerHealthChecker) Close() (err error) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if bc.client != nil {
+		err = bc.client.Close()
+	}
+	return
+}
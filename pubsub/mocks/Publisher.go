@@ -5,6 +5,7 @@ package mocks
 import (
 	context "context"
 
+	pubsub "github.com/ijalalfrz/coinbit-test/pubsub"
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -27,16 +28,37 @@ func (_m *Publisher) Close() error {
 	return r0
 }
 
+// Health provides a mock function with given fields: ctx
+func (_m *Publisher) Health(ctx context.Context) pubsub.Health {
+	ret := _m.Called(ctx)
+
+	var r0 pubsub.Health
+	if rf, ok := ret.Get(0).(func(context.Context) pubsub.Health); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(pubsub.Health)
+	}
+
+	return r0
+}
+
 // Send provides a mock function with given fields: ctx, key, message
-func (_m *Publisher) Send(ctx context.Context, key string, message interface{}) error {
+func (_m *Publisher) Send(ctx context.Context, key string, message interface{}) (pubsub.Position, error) {
 	ret := _m.Called(ctx, key, message)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, interface{}) error); ok {
+	var r0 pubsub.Position
+	if rf, ok := ret.Get(0).(func(context.Context, string, interface{}) pubsub.Position); ok {
 		r0 = rf(ctx, key, message)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(pubsub.Position)
 	}
 
-	return r0
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, interface{}) error); ok {
+		r1 = rf(ctx, key, message)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
@@ -2,7 +2,12 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+
+	pubsub "github.com/ijalalfrz/coinbit-test/pubsub"
+	mock "github.com/stretchr/testify/mock"
+)
 
 // Subscriber is an autogenerated mock type for the Subscriber type
 type Subscriber struct {
@@ -23,6 +28,20 @@ func (_m *Subscriber) Close() error {
 	return r0
 }
 
+// Health provides a mock function with given fields: ctx
+func (_m *Subscriber) Health(ctx context.Context) pubsub.Health {
+	ret := _m.Called(ctx)
+
+	var r0 pubsub.Health
+	if rf, ok := ret.Get(0).(func(context.Context) pubsub.Health); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(pubsub.Health)
+	}
+
+	return r0
+}
+
 // Subscribe provides a mock function with given fields:
 func (_m *Subscriber) Subscribe() {
 	_m.Called()
@@ -2,7 +2,12 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+
+	pubsub "github.com/ijalalfrz/coinbit-test/pubsub"
+	mock "github.com/stretchr/testify/mock"
+)
 
 // ViewTable is an autogenerated mock type for the ViewTable type
 type ViewTable struct {
@@ -37,7 +42,108 @@ func (_m *ViewTable) Get(key string) (interface{}, error) {
 	return r0, r1
 }
 
+// Has provides a mock function with given fields: key
+func (_m *ViewTable) Has(key string) (bool, error) {
+	ret := _m.Called(key)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(key)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Health provides a mock function with given fields: ctx
+func (_m *ViewTable) Health(ctx context.Context) pubsub.Health {
+	ret := _m.Called(ctx)
+
+	var r0 pubsub.Health
+	if rf, ok := ret.Get(0).(func(context.Context) pubsub.Health); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(pubsub.Health)
+	}
+
+	return r0
+}
+
+// Iterate provides a mock function with given fields: fn
+func (_m *ViewTable) Iterate(fn func(string, interface{}) bool) error {
+	ret := _m.Called(fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(func(string, interface{}) bool) error); ok {
+		r0 = rf(fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// IteratePrefix provides a mock function with given fields: prefix, fn
+func (_m *ViewTable) IteratePrefix(prefix string, fn func(string, interface{}) bool) error {
+	ret := _m.Called(prefix, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, func(string, interface{}) bool) error); ok {
+		r0 = rf(prefix, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// IterateRange provides a mock function with given fields: start, limit, fn
+func (_m *ViewTable) IterateRange(start string, limit string, fn func(string, interface{}) bool) error {
+	ret := _m.Called(start, limit, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, func(string, interface{}) bool) error); ok {
+		r0 = rf(start, limit, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// OnStateChange provides a mock function with given fields: listener
+func (_m *ViewTable) OnStateChange(listener pubsub.StateListener) {
+	_m.Called(listener)
+}
+
+// OnUpdate provides a mock function with given fields: listener
+func (_m *ViewTable) OnUpdate(listener pubsub.UpdateListener) {
+	_m.Called(listener)
+}
+
 // Open provides a mock function with given fields:
 func (_m *ViewTable) Open() {
 	_m.Called()
 }
+
+// WaitRunning provides a mock function with given fields: ctx
+func (_m *ViewTable) WaitRunning(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
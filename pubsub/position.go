@@ -0,0 +1,35 @@
+package pubsub
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Position is the partition and offset of a message in its topic.
+type Position struct {
+	Partition int32 `json:"partition"`
+	Offset    int64 `json:"offset"`
+}
+
+// String returns the position as a consistency token "<partition>:<offset>".
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Partition, p.Offset)
+}
+
+// ParsePosition will parse a consistency token returned by Position.String.
+func ParsePosition(token string) (position Position, err error) {
+	parts := strings.Split(token, ":")
+	if len(parts) != 2 {
+		err = fmt.Errorf("consistency token must be <partition>:<offset>, got '%s'", token)
+		return
+	}
+	partition, partitionErr := strconv.ParseInt(parts[0], 10, 32)
+	offset, offsetErr := strconv.ParseInt(parts[1], 10, 64)
+	if partitionErr != nil || offsetErr != nil || partition < 0 || offset < 0 {
+		err = fmt.Errorf("consistency token must be <partition>:<offset>, got '%s'", token)
+		return
+	}
+	position = Position{Partition: int32(partition), Offset: offset}
+	return
+}
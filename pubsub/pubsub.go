@@ -2,6 +2,7 @@ package pubsub
 
 import (
 	"context"
+	"time"
 
 	"github.com/lovoo/goka"
 )
@@ -18,22 +19,78 @@ type GokaEventHandler interface {
 
 // Publisher is a collection of behavior of a publisher
 type Publisher interface {
-	// Will send the message to the assigned topic.
-	Send(ctx context.Context, key string, message interface{}) (err error)
+	// Will send the message to the assigned topic and return the position it is written to.
+	Send(ctx context.Context, key string, message interface{}) (position Position, err error)
 	Close() (err error)
+	HealthChecker
 }
 
 // Subscriber is a collection of behavior of a subscriber
 type Subscriber interface {
 	Subscribe()
 	Close() (err error)
+	HealthChecker
 }
 
 // ViewTable is a collection of behavior of a view table
 type ViewTable interface {
 	Open()
 	Get(key string) (data interface{}, err error)
+	Has(key string) (ok bool, err error)
+	// Iterate, IteratePrefix and IterateRange call fn until it returns false, the keys are sorted within
+	// a partition only.
+	Iterate(fn func(key string, value interface{}) bool) (err error)
+	IteratePrefix(prefix string, fn func(key string, value interface{}) bool) (err error)
+	// IterateRange reads the keys from start included to limit excluded, an empty limit reads until the last key.
+	IterateRange(start, limit string, fn func(key string, value interface{}) bool) (err error)
+	// OnUpdate will register a listener called for every applied update, it must not block.
+	OnUpdate(listener UpdateListener)
+	// OnStateChange will register a listener called with the health state of the view every time it changes,
+	// it must not block.
+	OnStateChange(listener StateListener)
+	// WaitRunning will block until the view is recovered, the view is stopped or ctx is done.
+	WaitRunning(ctx context.Context) (err error)
 	Close()
+	HealthChecker
+}
+
+// Replayer is a collection of behavior of a reader of the history of the keys of a stream
+type Replayer interface {
+	// Partition returns the partition the messages of key are written to.
+	Partition(key string) (partition int32, err error)
+	// Replay will call fn with the messages of key from the oldest retained one until fn returns false or
+	// the last message written before the call is read. Every message of the partition of key is read.
+	// oldest is the oldest retained offset of the partition, the messages before it are not retained anymore.
+	Replay(ctx context.Context, key string, fn func(message ReplayedMessage) bool) (oldest int64, err error)
+	// ReplayAll will call fn with the messages of every key, partition by partition, until fn returns false or
+	// the last messages written before the call are read. truncated lists the partitions whose oldest messages
+	// are not retained anymore.
+	ReplayAll(ctx context.Context, fn func(message ReplayedMessage) bool) (truncated []int32, err error)
+	// ReplayFrom will call fn with the messages of the partition of from, starting at its offset, until fn
+	// returns false or the last message written before the call is read.
+	ReplayFrom(ctx context.Context, from Position, fn func(message ReplayedMessage) bool) (err error)
+	Close() (err error)
+}
+
+// ReplayedMessage is a decoded message read again from a stream
+type ReplayedMessage struct {
+	Key       string
+	Position  Position
+	Timestamp time.Time
+	Value     interface{}
+}
+
+// UpdateListener is called with the key and the decoded value of an update applied to a view table
+type UpdateListener func(key string, value interface{})
+
+// StateListener is called with the health state of a view table, e.g. HealthStateRecovering or HealthStateRunning
+type StateListener func(state string)
+
+// GokaInput is an extra input stream of a goka consumer group
+type GokaInput struct {
+	Topic   string
+	Codec   GokaCodec
+	Handler GokaEventHandler
 }
 
 // GokaCodec is a collection of behavior of goka codec
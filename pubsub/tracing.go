@@ -0,0 +1,59 @@
+package pubsub
+
+import (
+	"context"
+
+	"github.com/lovoo/goka"
+	"go.elastic.co/apm"
+	"go.elastic.co/apm/module/apmhttp"
+)
+
+// TransactionTypeMessaging is the apm transaction type of a consumed kafka message.
+const TransactionTypeMessaging = "messaging"
+
+// startSendSpan will start a span for sending message to topic and inject its trace context into headers,
+// nothing is injected when ctx does not carry a transaction.
+func startSendSpan(ctx context.Context, topic string, headers MessageHeaders) (span *apm.Span) {
+	span, _ = apm.StartSpan(ctx, "Kafka SEND to "+topic, "messaging.kafka.send")
+	if span.Dropped() {
+		if tx := apm.TransactionFromContext(ctx); tx != nil {
+			injectTraceContext(tx.TraceContext(), headers)
+		}
+		return
+	}
+	injectTraceContext(span.TraceContext(), headers)
+	return
+}
+
+func injectTraceContext(traceContext apm.TraceContext, headers MessageHeaders) {
+	traceparent := apmhttp.FormatTraceparentHeader(traceContext)
+	headers.Add(apmhttp.W3CTraceparentHeader, traceparent)
+	headers.Add(apmhttp.ElasticTraceparentHeader, traceparent)
+	if tracestate := traceContext.State.String(); tracestate != "" {
+		headers.Add(apmhttp.TracestateHeader, tracestate)
+	}
+}
+
+// StartTransaction will start a messaging transaction named name with tracer for the consumed message,
+// it continues the trace carried by the message headers when there is one.
+// The returned context holds the transaction so it can be passed to apmlogrus.TraceContext.
+func StartTransaction(tracer *apm.Tracer, ctx goka.Context, name string) (tx *apm.Transaction, txCtx context.Context) {
+	opts := apm.TransactionOptions{}
+	headers := ctx.Headers()
+	traceparent, ok := headers[apmhttp.W3CTraceparentHeader]
+	if !ok {
+		traceparent, ok = headers[apmhttp.ElasticTraceparentHeader]
+	}
+	if ok {
+		if traceContext, err := apmhttp.ParseTraceparentHeader(string(traceparent)); err == nil {
+			if tracestate, ok := headers[apmhttp.TracestateHeader]; ok {
+				traceContext.State, _ = apmhttp.ParseTracestateHeader(string(tracestate))
+			}
+			opts.TraceContext = traceContext
+		}
+	}
+
+	tx = tracer.StartTransactionOptions(name, TransactionTypeMessaging, opts)
+	txCtx = apm.ContextWithTransaction(context.Background(), tx)
+	return
+}
@@ -0,0 +1,66 @@
+package pubsub
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/ijalalfrz/coinbit-test/middleware"
+	"github.com/lovoo/goka"
+	"github.com/stretchr/testify/assert"
+	"go.elastic.co/apm/apmtest"
+)
+
+// gokaContext is embedded under another name because goka.Context has a Context method.
+type gokaContext = goka.Context
+
+// headersContext is a goka context of a consumed message carrying headers.
+type headersContext struct {
+	gokaContext
+	headers goka.Headers
+}
+
+func (ctx headersContext) Headers() goka.Headers {
+	return ctx.headers
+}
+
+func TestTracing_Continues_Trace_From_HTTP_To_Processor(t *testing.T) {
+	tracer := apmtest.NewRecordingTracer()
+	defer tracer.Close()
+	headers := MessageHeaders{}
+	router := mux.NewRouter()
+	router.Use(middleware.Tracing(tracer.Tracer))
+	router.HandleFunc("/wallet/v1/deposit", func(w http.ResponseWriter, r *http.Request) {
+		span := startSendSpan(r.Context(), "deposits", headers)
+		span.End()
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/wallet/v1/deposit", nil))
+	tx, _ := StartTransaction(tracer.Tracer, headersContext{headers: headers.gokaHeaders()}, "DepositWalletEventHandler")
+	tx.End()
+	tracer.Flush(nil)
+
+	payloads := tracer.Payloads()
+	assert.Len(t, payloads.Transactions, 2)
+	assert.Len(t, payloads.Spans, 1)
+	httpTx, processorTx, sendSpan := payloads.Transactions[0], payloads.Transactions[1], payloads.Spans[0]
+	assert.Equal(t, "POST /wallet/v1/deposit", httpTx.Name)
+	assert.Equal(t, TransactionTypeMessaging, processorTx.Type)
+	assert.Equal(t, httpTx.TraceID, sendSpan.TraceID)
+	assert.Equal(t, httpTx.TraceID, processorTx.TraceID)
+	assert.Equal(t, sendSpan.ID, processorTx.ParentID)
+}
+
+func TestStartTransaction_Without_Trace_Headers(t *testing.T) {
+	tracer := apmtest.NewRecordingTracer()
+	defer tracer.Close()
+
+	tx, _ := StartTransaction(tracer.Tracer, headersContext{}, "DepositWalletEventHandler")
+	tx.End()
+	tracer.Flush(nil)
+
+	transactions := tracer.Payloads().Transactions
+	assert.Len(t, transactions, 1)
+	assert.Zero(t, transactions[0].ParentID)
+}
@@ -0,0 +1,4 @@
+package ratelimit
+
+// NewMemoryStoreWithClock is exported for tests.
+var NewMemoryStoreWithClock = newMemoryStore
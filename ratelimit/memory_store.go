@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the full buckets are removed from a MemoryStore.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+// MemoryStore keeps the token buckets in memory of a single instance.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryStore is a constructor.
+func NewMemoryStore() *MemoryStore {
+	return newMemoryStore(time.Now)
+}
+
+func newMemoryStore(now func() time.Time) *MemoryStore {
+	return &MemoryStore{
+		buckets:   map[string]*bucket{},
+		lastSweep: now(),
+		now:       now,
+	}
+}
+
+// Take will take one token from every bucket when each of them has one.
+func (s *MemoryStore) Take(ctx context.Context, buckets ...Bucket) (result Result, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	taken := make([]*bucket, 0, len(buckets))
+	for _, spec := range buckets {
+		b, ok := s.buckets[spec.Key]
+		if !ok || b.limit != spec.Limit {
+			b = &bucket{tokens: float64(spec.Limit.Burst), last: now, limit: spec.Limit}
+			s.buckets[spec.Key] = b
+		}
+		b.refill(now)
+
+		if b.tokens < 1 {
+			wait := time.Duration(math.Ceil((1 - b.tokens) / spec.Limit.Rate * float64(time.Second)))
+			if wait > result.RetryAfter {
+				result.RetryAfter = wait
+			}
+		}
+		taken = append(taken, b)
+	}
+	if result.RetryAfter > 0 {
+		return
+	}
+
+	for _, b := range taken {
+		b.tokens--
+	}
+	result.Allowed = true
+	return
+}
+
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed*b.limit.Rate)
+		b.last = now
+	}
+}
+
+// sweep will remove the buckets which are full again, they are equal to a new bucket.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// Len returns the number of buckets kept in memory.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buckets)
+}
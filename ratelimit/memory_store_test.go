@@ -0,0 +1,105 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ijalalfrz/coinbit-test/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func TestMemoryStore_Take(t *testing.T) {
+	c := &clock{now: time.Unix(0, 0)}
+	store := ratelimit.NewMemoryStoreWithClock(c.Now)
+	limit := ratelimit.Limit{Rate: 2, Burst: 3}
+	ctx := context.Background()
+
+	t.Run("when burst is used", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			result, err := store.Take(ctx, ratelimit.Bucket{Key: "client", Limit: limit})
+			assert.Nil(t, err)
+			assert.True(t, result.Allowed)
+		}
+
+		result, err := store.Take(ctx, ratelimit.Bucket{Key: "client", Limit: limit})
+
+		assert.Nil(t, err)
+		assert.False(t, result.Allowed)
+		assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+	})
+
+	t.Run("when another key is used", func(t *testing.T) {
+		result, _ := store.Take(ctx, ratelimit.Bucket{Key: "other", Limit: limit})
+
+		assert.True(t, result.Allowed)
+	})
+
+	t.Run("when bucket is refilled", func(t *testing.T) {
+		c.now = c.now.Add(500 * time.Millisecond)
+
+		first, _ := store.Take(ctx, ratelimit.Bucket{Key: "client", Limit: limit})
+		second, _ := store.Take(ctx, ratelimit.Bucket{Key: "client", Limit: limit})
+
+		assert.True(t, first.Allowed)
+		assert.False(t, second.Allowed)
+	})
+
+	t.Run("when full buckets are swept", func(t *testing.T) {
+		c.now = c.now.Add(2 * time.Minute)
+
+		_, _ = store.Take(ctx, ratelimit.Bucket{Key: "client", Limit: limit})
+
+		assert.Equal(t, 1, store.Len())
+	})
+}
+
+func TestMemoryStore_Take_Buckets(t *testing.T) {
+	c := &clock{now: time.Unix(0, 0)}
+	store := ratelimit.NewMemoryStoreWithClock(c.Now)
+	ctx := context.Background()
+	client := ratelimit.Bucket{Key: "client", Limit: ratelimit.Limit{Rate: 1, Burst: 2}}
+	wallet := ratelimit.Bucket{Key: "wallet", Limit: ratelimit.Limit{Rate: 0.5, Burst: 1}}
+
+	t.Run("when every bucket has a token", func(t *testing.T) {
+		result, err := store.Take(ctx, client, wallet)
+
+		assert.Nil(t, err)
+		assert.True(t, result.Allowed)
+	})
+
+	t.Run("when a bucket is empty the others keep their tokens", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			result, err := store.Take(ctx, client, wallet)
+
+			assert.Nil(t, err)
+			assert.False(t, result.Allowed)
+			assert.Equal(t, 2*time.Second, result.RetryAfter)
+		}
+
+		result, _ := store.Take(ctx, client)
+
+		assert.True(t, result.Allowed)
+	})
+
+	t.Run("when every bucket is empty the longest wait is answered", func(t *testing.T) {
+		result, _ := store.Take(ctx, client, wallet)
+
+		assert.False(t, result.Allowed)
+		assert.Equal(t, 2*time.Second, result.RetryAfter)
+	})
+}
+
+func TestLimit_Enabled(t *testing.T) {
+	assert.True(t, ratelimit.Limit{Rate: 0.5, Burst: 1}.Enabled())
+	assert.False(t, ratelimit.Limit{Rate: 0, Burst: 1}.Enabled())
+	assert.False(t, ratelimit.Limit{Rate: 1, Burst: 0}.Enabled())
+}
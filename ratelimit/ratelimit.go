@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit is a token bucket refilled with Rate tokens per second holding at most Burst tokens.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Enabled returns true when the limit allows a finite rate.
+func (l Limit) Enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed bool
+	// RetryAfter is how long to wait until a token of every bucket is available, zero when allowed.
+	RetryAfter time.Duration
+}
+
+// Bucket is the token bucket of Key refilled with Limit.
+type Bucket struct {
+	Key   string
+	Limit Limit
+}
+
+// Store keeps the token buckets, implementations shared by several instances can replace MemoryStore.
+type Store interface {
+	// Take will take one token from every bucket, or none when one of them is empty, so a denied request
+	// does not use the tokens of the other buckets. A bucket is created full with its limit.
+	Take(ctx context.Context, buckets ...Bucket) (result Result, err error)
+}
+
+// Policy is the limits of a route, a disabled limit is not enforced. The routes and grpc methods with the
+// same policy Name share its buckets.
+type Policy struct {
+	Name string
+	// Client limits the requests of every api key, user or ip address.
+	Client Limit
+	// Wallet limits the requests targeting every wallet id.
+	Wallet Limit
+}
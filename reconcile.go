@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ijalalfrz/coinbit-test/pubsub"
+	"github.com/ijalalfrz/coinbit-test/wallet"
+	"github.com/sirupsen/logrus"
+)
+
+// exit codes of the reconcile command.
+const (
+	reconcileOK         = 0
+	reconcileFailed     = 1
+	reconcileMismatched = 2
+)
+
+// runReconcile will reconcile the balance view with the deposits stream and write the report as json,
+// the exit code is reconcileMismatched when some mismatched wallets are not corrected.
+func runReconcile(logger *logrus.Logger, args []string) int {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	correct := flags.Bool("correct", false, "send a correction event of the delta of every mismatched wallet")
+	output := flags.String("output", "", "path of the json report, it is written to stdout when empty")
+	if err := flags.Parse(args); err != nil {
+		return reconcileFailed
+	}
+
+	depositWalletCodec := wallet.NewDepositCodec()
+	depositReplayer, err := pubsub.NewGokaStreamReplayer(logger, cfg.SaramaKafka.Addresses, cfg.SaramaKafka.Config, cfg.Topic.Deposit, depositWalletCodec)
+	if err != nil {
+		logger.Error(err)
+		return reconcileFailed
+	}
+	defer depositReplayer.Close()
+	correctionReplayer, err := pubsub.NewGokaStreamReplayer(logger, cfg.SaramaKafka.Addresses, cfg.SaramaKafka.Config, cfg.Topic.Correction, depositWalletCodec)
+	if err != nil {
+		logger.Error(err)
+		return reconcileFailed
+	}
+	defer correctionReplayer.Close()
+	balanceVt, err := pubsub.NewGokaViewTableAdapter(logger, cfg.Topic.BalanceGroup, cfg.SaramaKafka.Addresses, cfg.SaramaKafka.Config, wallet.NewWalletCodec())
+	if err != nil {
+		logger.Error(err)
+		return reconcileFailed
+	}
+	balanceVt.Open()
+	defer balanceVt.Close()
+
+	property := wallet.ReconcilerProperty{
+		Logger:             logger,
+		DepositReplayer:    depositReplayer,
+		CorrectionReplayer: correctionReplayer,
+		BalanceViewTable:   balanceVt,
+		CatchUpTimeout:     cfg.Wallet.ConsistencyTimeout,
+	}
+	if *correct {
+		correctionTopicPublisher, err := pubsub.NewGokaProducerAdapter(logger, cfg.SaramaKafka.Addresses, cfg.SaramaKafka.Config, cfg.Topic.Correction, depositWalletCodec)
+		if err != nil {
+			logger.Error(err)
+			return reconcileFailed
+		}
+		defer correctionTopicPublisher.Close()
+		property.CorrectionTopicPublisher = correctionTopicPublisher
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	report, err := wallet.NewReconciler(property).Reconcile(ctx, *correct)
+	if err != nil {
+		logger.Error(err)
+		// the corrections sent before the error are reported
+		if report.Corrections == 0 {
+			return reconcileFailed
+		}
+	}
+
+	data, marshalErr := json.MarshalIndent(report, "", "  ")
+	if marshalErr != nil {
+		logger.Error(marshalErr)
+		return reconcileFailed
+	}
+	data = append(data, '\n')
+	if *output == "" {
+		_, marshalErr = os.Stdout.Write(data)
+	} else {
+		marshalErr = os.WriteFile(*output, data, 0644)
+	}
+	if marshalErr != nil {
+		logger.Error(marshalErr)
+		return reconcileFailed
+	}
+
+	switch {
+	case err != nil:
+		return reconcileFailed
+	case report.Corrections < len(report.Mismatches):
+		return reconcileMismatched
+	}
+	return reconcileOK
+}
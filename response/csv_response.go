@@ -0,0 +1,19 @@
+package response
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+)
+
+// CSVContentType is the media type of the CSV responses.
+const CSVContentType = "text/csv"
+
+// CSV will response the records as a CSV attachment named filename, the first record is the header.
+func CSV(w http.ResponseWriter, filename string, records [][]string) {
+	w.Header().Set("Content-Type", CSVContentType+"; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	writer := csv.NewWriter(w)
+	writer.WriteAll(records)
+}
@@ -0,0 +1,29 @@
+package response
+
+import (
+	"github.com/ijalalfrz/coinbit-test/exception"
+)
+
+// statusExceptions is the exception of an http status, it is used when the error of a response is not an exception.
+// It is derived from kindStatuses where every kind has its own http status.
+var statusExceptions = func() map[int]error {
+	exceptions := make(map[int]error, len(kindStatuses))
+	for kind, status := range kindStatuses {
+		exceptions[status.httpStatusCode] = kind.Exception()
+	}
+	return exceptions
+}()
+
+// ErrorCode returns the stable code of the error response, it is empty for a success response.
+func ErrorCode(resp Response) string {
+	if resp.Error() == nil {
+		return ""
+	}
+	if code := exception.Code(resp.Error()); code != "" {
+		return code
+	}
+	if err, ok := statusExceptions[resp.HTTPStatusCode()]; ok {
+		return exception.Code(err)
+	}
+	return exception.CodeInternalServer
+}
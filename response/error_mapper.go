@@ -0,0 +1,64 @@
+package response
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ijalalfrz/coinbit-test/exception"
+	"google.golang.org/grpc/codes"
+)
+
+// kindStatus is how a kind of domain error is answered.
+type kindStatus struct {
+	httpStatusCode int
+	status         string
+	grpcCode       codes.Code
+}
+
+// kindStatuses maps the kinds of domain error to their http status code, response status and grpc code.
+var kindStatuses = map[exception.Kind]kindStatus{
+	exception.KindInternal:            {http.StatusInternalServerError, StatUnexpectedError, codes.Internal},
+	exception.KindBadRequest:          {http.StatusBadRequest, StatusInvalidPayload, codes.InvalidArgument},
+	exception.KindUnprocessableEntity: {http.StatusUnprocessableEntity, StatusInvalidPayload, codes.InvalidArgument},
+	exception.KindUnauthorized:        {http.StatusUnauthorized, StatUnauthorized, codes.Unauthenticated},
+	exception.KindForbidden:           {http.StatusForbidden, StatForbidden, codes.PermissionDenied},
+	exception.KindNotFound:            {http.StatusNotFound, StatNotFound, codes.NotFound},
+	exception.KindConflict:            {http.StatusConflict, StatAlreadyExist, codes.AlreadyExists},
+	exception.KindLocked:              {http.StatusLocked, StatLocked, codes.FailedPrecondition},
+	exception.KindTooManyRequests:     {http.StatusTooManyRequests, StatTooManyRequests, codes.ResourceExhausted},
+	exception.KindTimeout:             {http.StatusRequestTimeout, StatTimeout, codes.Canceled},
+	exception.KindGatewayTimeout:      {http.StatusGatewayTimeout, StatTimeout, codes.DeadlineExceeded},
+	exception.KindServiceUnavailable:  {http.StatusServiceUnavailable, StatServiceUnavailable, codes.Unavailable},
+}
+
+// HTTPStatusCode returns the http status code of the kind of domain error.
+func HTTPStatusCode(kind exception.Kind) int {
+	if status, ok := kindStatuses[kind]; ok {
+		return status.httpStatusCode
+	}
+	return http.StatusInternalServerError
+}
+
+// FromError is a constructor of the error response of err, the status is decided by the kind of err.
+// The message and details are only answered for a domain error, the message of any other error is not
+// safe to answer and the one of its exception is used instead.
+func FromError(err error) Response {
+	kind := exception.KindOf(err)
+	status, ok := kindStatuses[kind]
+	if !ok {
+		status = kindStatuses[exception.KindInternal]
+	}
+
+	message := kind.Exception().Error()
+	var data interface{}
+	var domainErr *exception.Error
+	if errors.As(err, &domainErr) {
+		if domainErr.Message != "" {
+			message = domainErr.Message
+		}
+		if len(domainErr.Details) > 0 {
+			data = domainErr.Details
+		}
+	}
+	return NewErrorResponse(err, status.httpStatusCode, data, status.status, message)
+}
@@ -0,0 +1,69 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/ijalalfrz/coinbit-test/exception"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// codeGRPCCodes maps the error codes answered with another grpc code than the one of their kind.
+var codeGRPCCodes = map[string]codes.Code{
+	exception.CodeHistoryNotRetained: codes.FailedPrecondition,
+}
+
+// GRPCCode returns the grpc code of the http status code, it is used when the error of a response is not an exception.
+func GRPCCode(httpStatusCode int) codes.Code {
+	switch httpStatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusAccepted:
+		return codes.OK
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusLocked:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusRequestTimeout:
+		return codes.Canceled
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	default:
+		return codes.Internal
+	}
+}
+
+// GRPCErrorCode returns the grpc code of err, it is decided by the error code and the kind of err.
+func GRPCErrorCode(err error) codes.Code {
+	if code, ok := codeGRPCCodes[exception.Code(err)]; ok {
+		return code
+	}
+	if status, ok := kindStatuses[exception.KindOf(err)]; ok {
+		return status.grpcCode
+	}
+	return codes.Internal
+}
+
+// GRPCError returns the grpc status error of the response, it is nil when the response is not an error.
+func GRPCError(resp Response) error {
+	if resp.Error() == nil {
+		return nil
+	}
+	code := GRPCCode(resp.HTTPStatusCode())
+	if exception.Code(resp.Error()) != "" {
+		code = GRPCErrorCode(resp.Error())
+	}
+	return status.Error(code, resp.Message())
+}
@@ -0,0 +1,76 @@
+package response
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ProblemContentType is the media type of the RFC 7807 problem details.
+const ProblemContentType = "application/problem+json"
+
+// problemTypePrefix prefixes the error code in the problem type.
+const problemTypePrefix = "urn:wallet:error:"
+
+// problemObject is a RFC 7807 problem details document, Code is the stable error code and Details
+// are the details of the domain error.
+type problemObject struct {
+	Type     string      `json:"type"`
+	Title    string      `json:"title"`
+	Status   int         `json:"status"`
+	Detail   string      `json:"detail,omitempty"`
+	Instance string      `json:"instance,omitempty"`
+	Code     string      `json:"code"`
+	Details  interface{} `json:"details,omitempty"`
+}
+
+// Negotiate will response the error as problem details when the client accepts application/problem+json,
+// otherwise it responses as JSON.
+func Negotiate(w http.ResponseWriter, r *http.Request, resp Response) {
+	if resp.Error() == nil || !acceptsProblem(r.Header.Get("Accept")) {
+		JSON(w, resp)
+		return
+	}
+	Problem(w, r, resp)
+}
+
+// Problem will response the error as RFC 7807 problem details.
+func Problem(w http.ResponseWriter, r *http.Request, resp Response) {
+	code := ErrorCode(resp)
+	po := problemObject{
+		Type:     problemTypePrefix + code,
+		Title:    http.StatusText(resp.HTTPStatusCode()),
+		Status:   resp.HTTPStatusCode(),
+		Detail:   resp.Message(),
+		Instance: r.URL.Path,
+		Code:     code,
+		Details:  resp.Data(),
+	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(po.Status)
+	json.NewEncoder(w).Encode(po)
+}
+
+// acceptsProblem returns true when the accept header lists application/problem+json with a non zero quality.
+func acceptsProblem(accept string) bool {
+	return Accepts(accept, ProblemContentType)
+}
+
+// Accepts returns true when the accept header lists mediaType with a non zero quality.
+func Accepts(accept, mediaType string) bool {
+	for _, mediaRange := range strings.Split(accept, ",") {
+		listed, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil || listed != mediaType {
+			continue
+		}
+		if q, ok := params["q"]; ok {
+			if quality, err := strconv.ParseFloat(q, 64); err != nil || quality <= 0 {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
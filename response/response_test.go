@@ -1,6 +1,8 @@
 package response_test
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -8,6 +10,8 @@ import (
 	"github.com/ijalalfrz/coinbit-test/exception"
 	"github.com/ijalalfrz/coinbit-test/response"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestErrorResponse(t *testing.T) {
@@ -37,6 +41,15 @@ func TestSuccessResponse(t *testing.T) {
 		assert.Equal(t, "OK", resp.Message())
 	})
 
+	t.Run("when status is accepted", func(t *testing.T) {
+		resp := response.NewSuccessResponse(
+			nil, response.StatAccepted, "Accepted",
+		)
+
+		assert.Equal(t, http.StatusAccepted, resp.HTTPStatusCode())
+		assert.Equal(t, response.StatAccepted, resp.Status())
+	})
+
 	t.Run("when status is created", func(t *testing.T) {
 		resp := response.NewSuccessResponse(
 			nil, response.StatCreated, "Created",
@@ -49,6 +62,15 @@ func TestSuccessResponse(t *testing.T) {
 		assert.Equal(t, response.StatCreated, resp.Status())
 		assert.Equal(t, "Created", resp.Message())
 	})
+
+	t.Run("when response has meta", func(t *testing.T) {
+		meta := map[string]interface{}{"next_cursor": "abc"}
+		resp := response.NewSuccessResponseWithMeta([]string{"1"}, meta, response.StatOK, "OK")
+
+		assert.Equal(t, http.StatusOK, resp.HTTPStatusCode())
+		assert.Equal(t, []string{"1"}, resp.Data())
+		assert.Equal(t, meta, resp.Meta())
+	})
 }
 
 func TestRESTResponse(t *testing.T) {
@@ -72,3 +94,192 @@ func TestRESTResponse(t *testing.T) {
 		assert.Equal(t, http.StatusNotFound, recoreder.Code)
 	})
 }
+
+func TestGRPCError(t *testing.T) {
+	t.Run("when response is success", func(t *testing.T) {
+		resp := response.NewSuccessResponse(nil, response.StatAccepted, "Accepted")
+
+		assert.Nil(t, response.GRPCError(resp))
+	})
+
+	t.Run("when response is error", func(t *testing.T) {
+		resp := response.NewErrorResponse(exception.ErrNotFound, http.StatusNotFound, nil, response.StatNotFound, "Resource not found")
+
+		err := response.GRPCError(resp)
+
+		assert.Equal(t, codes.NotFound, status.Code(err))
+		assert.Equal(t, "Resource not found", status.Convert(err).Message())
+	})
+
+	t.Run("when error is a domain error", func(t *testing.T) {
+		errs := map[codes.Code]error{
+			codes.Canceled:           exception.New(exception.KindTimeout, "Canceled"),
+			codes.DeadlineExceeded:   exception.New(exception.KindGatewayTimeout, "Timed out"),
+			codes.FailedPrecondition: exception.New(exception.KindLocked, "Locked"),
+			codes.InvalidArgument:    exception.New(exception.KindUnprocessableEntity, "Invalid"),
+		}
+		for code, err := range errs {
+			assert.Equal(t, code, status.Code(response.GRPCError(response.FromError(err))), err.Error())
+		}
+	})
+
+	t.Run("when error code is answered with another grpc code than its kind", func(t *testing.T) {
+		err := exception.New(exception.KindUnprocessableEntity, "Not retained").WithCode(exception.CodeHistoryNotRetained)
+
+		assert.Equal(t, codes.FailedPrecondition, status.Code(response.GRPCError(response.FromError(err))))
+	})
+
+	t.Run("when error is not an exception", func(t *testing.T) {
+		resp := response.NewErrorResponse(errors.New("conflict"), http.StatusConflict, nil, response.StatAlreadyExist, "Conflict")
+
+		assert.Equal(t, codes.AlreadyExists, status.Code(response.GRPCError(resp)))
+	})
+
+	t.Run("when status code is not mapped", func(t *testing.T) {
+		assert.Equal(t, codes.Internal, response.GRPCCode(http.StatusTeapot))
+		assert.Equal(t, codes.DeadlineExceeded, response.GRPCCode(http.StatusGatewayTimeout))
+	})
+}
+
+func TestErrorCode(t *testing.T) {
+	t.Run("when error is an exception", func(t *testing.T) {
+		err := fmt.Errorf("%w: invalid character", exception.ErrUnprocessableEntity)
+		resp := response.NewErrorResponse(err, http.StatusUnprocessableEntity, nil, response.StatusInvalidPayload, "Invalid")
+
+		assert.Equal(t, exception.CodeUnprocessableEntity, response.ErrorCode(resp))
+	})
+
+	t.Run("when error is not an exception", func(t *testing.T) {
+		resp := response.NewErrorResponse(errors.New("kafka: broker not connected"), http.StatusGatewayTimeout, nil, response.StatTimeout, "Timeout")
+
+		assert.Equal(t, exception.CodeGatewayTimeout, response.ErrorCode(resp))
+	})
+
+	t.Run("when error of any kind is not an exception", func(t *testing.T) {
+		for kind := exception.KindInternal; kind <= exception.KindServiceUnavailable; kind++ {
+			resp := response.NewErrorResponse(errors.New("boom"), response.HTTPStatusCode(kind), nil, response.StatUnexpectedError, "Unexpected")
+
+			assert.Equal(t, kind.Code(), response.ErrorCode(resp))
+		}
+	})
+
+	t.Run("when status is not mapped", func(t *testing.T) {
+		resp := response.NewErrorResponse(errors.New("boom"), http.StatusTeapot, nil, response.StatUnexpectedError, "Unexpected")
+
+		assert.Equal(t, exception.CodeInternalServer, response.ErrorCode(resp))
+	})
+
+	t.Run("when response is success", func(t *testing.T) {
+		assert.Equal(t, "", response.ErrorCode(response.NewSuccessResponse(nil, response.StatOK, "OK")))
+	})
+}
+
+func TestFromError(t *testing.T) {
+	t.Run("when error is a domain error", func(t *testing.T) {
+		cause := errors.New("view is not running")
+		err := fmt.Errorf("reading wallet: %w", exception.Wrap(exception.KindServiceUnavailable, cause, "Wallet is not available"))
+
+		resp := response.FromError(err)
+
+		assert.Equal(t, http.StatusServiceUnavailable, resp.HTTPStatusCode())
+		assert.Equal(t, response.StatServiceUnavailable, resp.Status())
+		assert.Equal(t, "Wallet is not available", resp.Message())
+		assert.Equal(t, exception.CodeServiceUnavailable, response.ErrorCode(resp))
+		assert.ErrorIs(t, resp.Error(), exception.ErrServiceUnavailable)
+		assert.ErrorIs(t, resp.Error(), cause)
+	})
+
+	t.Run("when domain error has code and details", func(t *testing.T) {
+		err := exception.New(exception.KindBadRequest, "Invalid 'Amount'").
+			WithCode("invalid_amount").
+			WithDetails(map[string]interface{}{"field": "Amount"})
+
+		resp := response.FromError(err)
+
+		assert.Equal(t, http.StatusBadRequest, resp.HTTPStatusCode())
+		assert.Equal(t, "invalid_amount", response.ErrorCode(resp))
+		assert.Equal(t, map[string]interface{}{"field": "Amount"}, resp.Data())
+		var domainErr *exception.Error
+		assert.True(t, errors.As(resp.Error(), &domainErr))
+		assert.Equal(t, exception.KindBadRequest, domainErr.Kind)
+	})
+
+	t.Run("when error is an exception", func(t *testing.T) {
+		resp := response.FromError(fmt.Errorf("%w: wallet 1", exception.ErrNotFound))
+
+		assert.Equal(t, http.StatusNotFound, resp.HTTPStatusCode())
+		assert.Equal(t, response.StatNotFound, resp.Status())
+		assert.Equal(t, exception.ErrNotFound.Error(), resp.Message())
+	})
+
+	t.Run("when error is unknown", func(t *testing.T) {
+		resp := response.FromError(errors.New("kafka: broker not connected"))
+
+		assert.Equal(t, http.StatusInternalServerError, resp.HTTPStatusCode())
+		assert.Equal(t, response.StatUnexpectedError, resp.Status())
+		assert.Equal(t, exception.ErrInternalServer.Error(), resp.Message(), "should not tell the internal error")
+		assert.Equal(t, exception.CodeInternalServer, response.ErrorCode(resp))
+	})
+}
+
+func TestNegotiate(t *testing.T) {
+	notFound := response.NewErrorResponse(exception.ErrNotFound, http.StatusNotFound, nil, response.StatNotFound, "Wallet is not found")
+
+	t.Run("when problem details are accepted", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/wallet/v1/details/1", nil)
+		r.Header.Set("Accept", "application/json;q=0.5, application/problem+json")
+		recorder := httptest.NewRecorder()
+
+		response.Negotiate(recorder, r, notFound)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		assert.Equal(t, response.ProblemContentType, recorder.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{
+			"type": "urn:wallet:error:not_found",
+			"title": "Not Found",
+			"status": 404,
+			"detail": "Wallet is not found",
+			"instance": "/wallet/v1/details/1",
+			"code": "not_found"
+		}`, recorder.Body.String())
+	})
+
+	t.Run("when problem details are refused", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/wallet/v1/details/1", nil)
+		r.Header.Set("Accept", "application/problem+json;q=0, application/json")
+		recorder := httptest.NewRecorder()
+
+		response.Negotiate(recorder, r, notFound)
+
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+		assert.Contains(t, recorder.Body.String(), `"error_code":"not_found"`)
+	})
+
+	t.Run("when response is success", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/wallet/v1/details/1", nil)
+		r.Header.Set("Accept", response.ProblemContentType)
+		recorder := httptest.NewRecorder()
+
+		response.Negotiate(recorder, r, response.NewSuccessResponse(nil, response.StatOK, "OK"))
+
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+		assert.NotContains(t, recorder.Body.String(), "error_code")
+	})
+}
+
+func TestAccepts(t *testing.T) {
+	assert.True(t, response.Accepts("application/json, text/csv;q=0.8", response.CSVContentType))
+	assert.False(t, response.Accepts("text/csv;q=0", response.CSVContentType))
+	assert.False(t, response.Accepts("", response.CSVContentType))
+}
+
+func TestCSV(t *testing.T) {
+	recorder := httptest.NewRecorder()
+
+	response.CSV(recorder, "statements.csv", [][]string{{"period", "credits"}, {"2022-08", "100"}})
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="statements.csv"`, recorder.Header().Get("Content-Disposition"))
+	assert.Equal(t, "period,credits\n2022-08,100\n", recorder.Body.String())
+}
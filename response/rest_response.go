@@ -11,12 +11,13 @@ type REST interface {
 }
 
 type restObject struct {
-	Success bool        `json:"success"`
-	Data    interface{} `json:"data"`
-	Message string      `json:"message"`
-	Status  string      `json:"status"`
-	Code    int         `json:"code"`
-	Meta    interface{} `json:"meta,omitempty"` // will not be appeared if not set.
+	Success   bool        `json:"success"`
+	Data      interface{} `json:"data"`
+	Message   string      `json:"message"`
+	Status    string      `json:"status"`
+	Code      int         `json:"code"`
+	Meta      interface{} `json:"meta,omitempty"`       // will not be appeared if not set.
+	ErrorCode string      `json:"error_code,omitempty"` // stable code of an error response.
 	// can add more
 }
 
@@ -60,12 +61,13 @@ func JSON(w http.ResponseWriter, resp Response) {
 		success = true
 	}
 	ro := restObject{
-		Success: success,
-		Data:    resp.Data(),
-		Message: resp.Message(),
-		Status:  resp.Status(),
-		Code:    resp.HTTPStatusCode(),
-		Meta:    resp.Meta(),
+		Success:   success,
+		Data:      resp.Data(),
+		Message:   resp.Message(),
+		Status:    resp.Status(),
+		Code:      resp.HTTPStatusCode(),
+		Meta:      resp.Meta(),
+		ErrorCode: ErrorCode(resp),
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(ro.Code)
@@ -2,13 +2,19 @@ package response
 
 // Collection of status.
 const (
-	StatOK                string = "OK"
-	StatCreated           string = "CREATED"
-	StatNotFound          string = "NOT_FOUND"
-	StatUnexpectedError   string = "UNEXPECTED_ERROR"
-	StatInsufficientPoint string = "INSUFFICIENT_POINT"
-	StatusInvalidPayload  string = "INVALID_PAYLOAD"
-	StatUnauthorized      string = "UNAUTHORIZED"
-	StatAlreadyExist      string = "ALREADY_EXIST"
-	StatBadRequest        string = "BAD_REQUEST"
+	StatOK                 string = "OK"
+	StatCreated            string = "CREATED"
+	StatAccepted           string = "ACCEPTED"
+	StatNotFound           string = "NOT_FOUND"
+	StatUnexpectedError    string = "UNEXPECTED_ERROR"
+	StatInsufficientPoint  string = "INSUFFICIENT_POINT"
+	StatusInvalidPayload   string = "INVALID_PAYLOAD"
+	StatUnauthorized       string = "UNAUTHORIZED"
+	StatForbidden          string = "FORBIDDEN"
+	StatAlreadyExist       string = "ALREADY_EXIST"
+	StatBadRequest         string = "BAD_REQUEST"
+	StatServiceUnavailable string = "SERVICE_UNAVAILABLE"
+	StatTooManyRequests    string = "TOO_MANY_REQUESTS"
+	StatTimeout            string = "TIMEOUT"
+	StatLocked             string = "LOCKED"
 )
@@ -18,6 +18,9 @@ func NewSuccessResponse(data interface{}, status string, message string) Respons
 	case StatCreated:
 		httpStatusCode = http.StatusCreated
 		break
+	case StatAccepted:
+		httpStatusCode = http.StatusAccepted
+		break
 	default:
 		httpStatusCode = http.StatusOK
 		break
@@ -31,6 +34,13 @@ func NewSuccessResponse(data interface{}, status string, message string) Respons
 	}
 }
 
+// NewSuccessResponseWithMeta is a constructor of a success response with metadata, e.g. the pagination of a list.
+func NewSuccessResponseWithMeta(data interface{}, meta interface{}, status string, message string) Response {
+	resp := NewSuccessResponse(data, status, message).(SuccessResponse)
+	resp.meta = meta
+	return resp
+}
+
 // Data returns data.
 func (r SuccessResponse) Data() interface{} {
 	return r.data
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+const (
+	grpcStartingMessage string = "gRPC Server starts to listen on %s"
+	grpcShutdownMessage string = "gRPC Server is gracefully shutdown."
+)
+
+// grpcShutdownTimeout is how long the running calls are waited for, the open streams are closed after it.
+const grpcShutdownTimeout = time.Second * 10
+
+// GRPCServer is a concrete struct of grpc server.
+type GRPCServer struct {
+	logger     *logrus.Logger
+	grpcServer *grpc.Server
+	addr       string
+}
+
+// NewGRPCServer is a constructor, the services must be registered on grpcServer before Start.
+func NewGRPCServer(logger *logrus.Logger, grpcServer *grpc.Server, port string) *GRPCServer {
+	return &GRPCServer{
+		logger:     logger,
+		grpcServer: grpcServer,
+		addr:       fmt.Sprintf(":%s", port),
+	}
+}
+
+// Start will listen on the port and serve in the background, it fails when the port can not be listened on.
+// Do not call this in goroutine.
+func (s *GRPCServer) Start() (err error) {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("gRPC Server cannot listen on %s: %w", s.addr, err)
+	}
+	s.logger.Info(fmt.Sprintf(grpcStartingMessage, s.addr))
+	go func() {
+		if serveErr := s.grpcServer.Serve(listener); serveErr != nil {
+			s.logger.Error(serveErr)
+		}
+	}()
+	return
+}
+
+// Close will block all the incomming calls and subsequently shutdown the server.
+func (s *GRPCServer) Close() {
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(grpcShutdownTimeout):
+		s.grpcServer.Stop()
+	}
+	s.logger.Info(grpcShutdownMessage)
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"time"
 
@@ -14,6 +15,9 @@ const (
 	shutdownMessage string = "HTTP Server is gracefully shutdown."
 )
 
+// WriteTimeout is the maximum duration of writing a response, long lived responses must end before it.
+const WriteTimeout = time.Second * 60
+
 // Server is a concrete struct of http server.
 type Server struct {
 	logger     *logrus.Logger
@@ -24,7 +28,7 @@ type Server struct {
 func NewServer(logger *logrus.Logger, handler http.Handler, port string) *Server {
 	httpServer := &http.Server{
 		Addr:         fmt.Sprintf(":%s", port),
-		WriteTimeout: time.Second * 60,
+		WriteTimeout: WriteTimeout,
 		ReadTimeout:  time.Second * 30,
 		IdleTimeout:  time.Second * 60,
 		Handler:      handler,
@@ -36,13 +40,20 @@ func NewServer(logger *logrus.Logger, handler http.Handler, port string) *Server
 	}
 }
 
-// Start will start the server.
+// Start will listen on the port and serve in the background, it fails when the port can not be listened on.
 // Do not call this in goroutine.
-func (s *Server) Start() {
+func (s *Server) Start() (err error) {
+	listener, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return fmt.Errorf("HTTP Server cannot listen on %s: %w", s.httpServer.Addr, err)
+	}
+	s.logger.Info(fmt.Sprintf(startingMessage, s.httpServer.Addr))
 	go func() {
-		s.logger.Info(fmt.Sprintf(startingMessage, s.httpServer.Addr))
-		s.httpServer.ListenAndServe()
+		if serveErr := s.httpServer.Serve(listener); serveErr != nil && serveErr != http.ErrServerClosed {
+			s.logger.Error(serveErr)
+		}
 	}()
+	return
 }
 
 // Close will block all the incomming request and subsequently shutdown the server.
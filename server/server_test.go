@@ -7,13 +7,34 @@ import (
 
 	"github.com/ijalalfrz/coinbit-test/server"
 	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
 )
 
 func TestServer(t *testing.T) {
 	httpHandler := http.NewServeMux()
 
 	srv := server.NewServer(logrus.New(), httpHandler, "9091")
-	srv.Start()
+	assert.Nil(t, srv.Start())
 	time.Sleep(time.Second * 1)
+
+	t.Run("when port is already listened on", func(t *testing.T) {
+		err := server.NewServer(logrus.New(), httpHandler, "9091").Start()
+
+		assert.Error(t, err, "should fail to start")
+	})
+	srv.Close()
+}
+
+func TestGRPCServer(t *testing.T) {
+	srv := server.NewGRPCServer(logrus.New(), grpc.NewServer(), "9092")
+	assert.Nil(t, srv.Start())
+	time.Sleep(time.Second * 1)
+
+	t.Run("when port is already listened on", func(t *testing.T) {
+		err := server.NewGRPCServer(logrus.New(), grpc.NewServer(), "9092").Start()
+
+		assert.Error(t, err, "should fail to start")
+	})
 	srv.Close()
 }
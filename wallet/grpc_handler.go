@@ -0,0 +1,176 @@
+package wallet
+
+import (
+	"context"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/ijalalfrz/coinbit-test/auth"
+	"github.com/ijalalfrz/coinbit-test/exception"
+	"github.com/ijalalfrz/coinbit-test/model"
+	"github.com/ijalalfrz/coinbit-test/pubsub"
+	"github.com/ijalalfrz/coinbit-test/response"
+	"github.com/ijalalfrz/coinbit-test/webmodel"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+// Full method names of the wallet service.
+const (
+	DepositMethod     = "/model.WalletService/Deposit"
+	GetDetailMethod   = "/model.WalletService/GetDetail"
+	WatchWalletMethod = "/model.WalletService/WatchWallet"
+)
+
+const walletIdErrMessage = "wallet_id is required"
+
+// GRPCHandler is a concrete struct of wallet grpc handler, it has the same validation,
+// authorization and errors as the http handler.
+type GRPCHandler struct {
+	model.UnimplementedWalletServiceServer
+	Logger   *logrus.Logger
+	Validate *validator.Validate
+	Usecase  Usecase
+	Hub      *StreamHub
+}
+
+// NewWalletGRPCHandler will register the wallet service on the grpc server.
+func NewWalletGRPCHandler(logger *logrus.Logger, validate *validator.Validate, server *grpc.Server, usecase Usecase, hub *StreamHub) {
+	handler := &GRPCHandler{
+		Logger:   logger,
+		Validate: validate,
+		Usecase:  usecase,
+		Hub:      hub,
+	}
+	model.RegisterWalletServiceServer(server, handler)
+}
+
+// Deposit is a function to handle deposit call, with wait it answers the new balance once the deposit is applied.
+func (handler GRPCHandler) Deposit(ctx context.Context, req *model.DepositRequest) (*model.DepositResponse, error) {
+	payload := webmodel.DepositWalletPayload{
+		WalletId: req.GetWalletId(),
+		Amount:   req.GetAmount(),
+	}
+	if err := validatePayload(handler.Validate, payload); err != nil {
+		return nil, response.GRPCError(response.FromError(err))
+	}
+
+	var result webmodel.DepositWalletResponse
+	var err error
+	if req.GetWait() {
+		result, err = handler.Usecase.DepositAndWait(ctx, payload)
+	} else {
+		result, err = handler.Usecase.Deposit(ctx, payload)
+	}
+	if err != nil {
+		return nil, response.GRPCError(response.FromError(err))
+	}
+
+	deposit := &model.DepositResponse{
+		Partition:        result.Partition,
+		Offset:           result.Offset,
+		ConsistencyToken: result.ConsistencyToken,
+	}
+	if result.Wallet != nil && result.AboveThreshold != nil {
+		deposit.Wallet = &model.WalletDetail{
+			WalletId:         result.Wallet.WalletId,
+			Balance:          result.Wallet.Balance,
+			AboveThreshold:   *result.AboveThreshold,
+			ConsistencyToken: result.ConsistencyToken,
+		}
+	}
+	return deposit, nil
+}
+
+// GetDetail is a function to handle get detail wallet call, the optional min_offset is the
+// consistency token of a deposit which must be applied before answering.
+func (handler GRPCHandler) GetDetail(ctx context.Context, req *model.GetDetailRequest) (*model.WalletDetail, error) {
+	if err := requireWalletId(req.GetWalletId()); err != nil {
+		return nil, err
+	}
+	if err := authorizeWallet(ctx, req.GetWalletId()); err != nil {
+		return nil, err
+	}
+
+	var minPosition *pubsub.Position
+	if token := req.GetMinOffset(); token != "" {
+		position, err := pubsub.ParsePosition(token)
+		if err != nil {
+			return nil, response.GRPCError(response.FromError(exception.Wrap(exception.KindBadRequest, err, minOffsetErrMessage)))
+		}
+		minPosition = &position
+	}
+
+	detail, err := handler.Usecase.GetDetail(ctx, req.GetWalletId(), minPosition)
+	if err != nil {
+		return nil, response.GRPCError(response.FromError(err))
+	}
+
+	return &model.WalletDetail{
+		WalletId:         detail.WalletId,
+		Balance:          detail.Balance,
+		AboveThreshold:   detail.AboveThreshold,
+		ConsistencyToken: detail.ConsistencyToken,
+	}, nil
+}
+
+// WatchWallet is a function to stream the wallet details every time a deposit is applied,
+// the current details are sent first.
+func (handler GRPCHandler) WatchWallet(req *model.WatchWalletRequest, stream model.WalletService_WatchWalletServer) error {
+	ctx := stream.Context()
+	walletId := req.GetWalletId()
+	if err := requireWalletId(walletId); err != nil {
+		return err
+	}
+	if err := authorizeWallet(ctx, walletId); err != nil {
+		return err
+	}
+
+	sub, err := handler.Hub.Subscribe(walletId)
+	if err != nil {
+		return response.GRPCError(response.FromError(exception.Wrap(exception.KindServiceUnavailable, err, streamTooManyErrMessage)))
+	}
+	defer handler.Hub.Unsubscribe(sub)
+
+	var last *lastSent
+	for {
+		detail, position, ok, err := handler.Hub.Snapshot(walletId)
+		if err != nil {
+			handler.Logger.WithContext(ctx).Warnf("Wallet watch of %s is closed: %v", walletId, err)
+			return response.GRPCError(response.FromError(exception.Wrap(exception.KindInternal, err, detailUnexpectedErrMessage)))
+		}
+		if ok && last.newer(detail, position) {
+			err = stream.Send(&model.WalletDetail{
+				WalletId:         detail.WalletId,
+				Balance:          detail.Balance,
+				AboveThreshold:   detail.AboveThreshold,
+				ConsistencyToken: position.String(),
+			})
+			if err != nil {
+				return err
+			}
+			last = &lastSent{position: position, detail: &detail}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sub.C():
+		}
+	}
+}
+
+// requireWalletId will reject an empty wallet id, it is a path parameter which cannot be empty on the http handler.
+func requireWalletId(walletId string) error {
+	if walletId == "" {
+		return response.GRPCError(response.FromError(exception.New(exception.KindBadRequest, walletIdErrMessage)))
+	}
+	return nil
+}
+
+// authorizeWallet will reject the users which do not own the wallet.
+func authorizeWallet(ctx context.Context, walletId string) error {
+	if principal, ok := auth.PrincipalFromContext(ctx); ok && !principal.CanAccessWallet(walletId) {
+		return response.GRPCError(response.FromError(exception.New(exception.KindForbidden, detailForbiddenErrMessage)))
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package wallet_test
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ijalalfrz/coinbit-test/auth"
+	"github.com/ijalalfrz/coinbit-test/entity"
+	"github.com/ijalalfrz/coinbit-test/exception"
+	"github.com/ijalalfrz/coinbit-test/model"
+	"github.com/ijalalfrz/coinbit-test/pubsub"
+	"github.com/ijalalfrz/coinbit-test/wallet"
+	"github.com/ijalalfrz/coinbit-test/wallet/mocks"
+	"github.com/ijalalfrz/coinbit-test/webmodel"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestNewWalletGRPCHandlerConstruct(t *testing.T) {
+	t.Run("should construct the wallet grpc handler", func(t *testing.T) {
+		var listeners []pubsub.UpdateListener
+		balanceTableMock, thresholdTableMock := newStreamViews(&listeners)
+		hub := wallet.NewStreamHub(balanceTableMock, thresholdTableMock, 1)
+		wallet.NewWalletGRPCHandler(logrus.New(), vld, grpc.NewServer(), &mocks.Usecase{}, hub)
+	})
+}
+
+func TestGRPCDeposit(t *testing.T) {
+	t.Run("when payload is invalid", func(t *testing.T) {
+		usecase := &mocks.Usecase{}
+		gh := wallet.GRPCHandler{Logger: logrus.New(), Validate: vld, Usecase: usecase}
+
+		_, err := gh.Deposit(context.TODO(), &model.DepositRequest{Amount: 100})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		usecase.AssertNotCalled(t, "Deposit", mock.Anything, mock.Anything)
+	})
+
+	t.Run("when deposit is published", func(t *testing.T) {
+		usecase := &mocks.Usecase{}
+		gh := wallet.GRPCHandler{Logger: logrus.New(), Validate: vld, Usecase: usecase}
+		data := webmodel.DepositWalletResponse{Partition: 1, Offset: 7, ConsistencyToken: "1:7"}
+		usecase.On("Deposit", mock.Anything, webmodel.DepositWalletPayload{WalletId: "1", Amount: 100}).
+			Return(data, nil)
+
+		resp, err := gh.Deposit(context.TODO(), &model.DepositRequest{WalletId: "1", Amount: 100})
+
+		assert.Nil(t, err)
+		assert.Equal(t, "1:7", resp.ConsistencyToken)
+		assert.Nil(t, resp.Wallet)
+	})
+
+	t.Run("when deposit is waited for", func(t *testing.T) {
+		usecase := &mocks.Usecase{}
+		gh := wallet.GRPCHandler{Logger: logrus.New(), Validate: vld, Usecase: usecase}
+		aboveThreshold := true
+		data := webmodel.DepositWalletResponse{
+			Partition:        1,
+			Offset:           7,
+			ConsistencyToken: "1:7",
+			Wallet:           &entity.Wallet{WalletId: "1", Balance: 12000},
+			AboveThreshold:   &aboveThreshold,
+		}
+		usecase.On("DepositAndWait", mock.Anything, mock.Anything).Return(data, nil)
+
+		resp, err := gh.Deposit(context.TODO(), &model.DepositRequest{WalletId: "1", Amount: 100, Wait: true})
+
+		assert.Nil(t, err)
+		assert.Equal(t, float64(12000), resp.Wallet.Balance)
+		assert.True(t, resp.Wallet.AboveThreshold)
+		usecase.AssertNotCalled(t, "Deposit", mock.Anything, mock.Anything)
+	})
+
+	t.Run("when deposit is failed", func(t *testing.T) {
+		usecase := &mocks.Usecase{}
+		gh := wallet.GRPCHandler{Logger: logrus.New(), Validate: vld, Usecase: usecase}
+		usecase.On("Deposit", mock.Anything, mock.Anything).
+			Return(webmodel.DepositWalletResponse{}, exception.ErrInternalServer)
+
+		_, err := gh.Deposit(context.TODO(), &model.DepositRequest{WalletId: "1", Amount: 100})
+
+		assert.Equal(t, codes.Internal, status.Code(err))
+	})
+}
+
+func TestGRPCGetDetail(t *testing.T) {
+	t.Run("when wallet is found", func(t *testing.T) {
+		usecase := &mocks.Usecase{}
+		gh := wallet.GRPCHandler{Logger: logrus.New(), Validate: vld, Usecase: usecase}
+		data := webmodel.DetailWalletResponse{WalletId: "1", Balance: 500, ConsistencyToken: "0:3"}
+		usecase.On("GetDetail", mock.Anything, "1", &pubsub.Position{Partition: 0, Offset: 3}).
+			Return(data, nil)
+
+		resp, err := gh.GetDetail(context.TODO(), &model.GetDetailRequest{WalletId: "1", MinOffset: "0:3"})
+
+		assert.Nil(t, err)
+		assert.Equal(t, float64(500), resp.Balance)
+		assert.Equal(t, "0:3", resp.ConsistencyToken)
+	})
+
+	t.Run("when wallet is not found", func(t *testing.T) {
+		usecase := &mocks.Usecase{}
+		gh := wallet.GRPCHandler{Logger: logrus.New(), Validate: vld, Usecase: usecase}
+		usecase.On("GetDetail", mock.Anything, "1", (*pubsub.Position)(nil)).
+			Return(webmodel.DetailWalletResponse{}, exception.New(exception.KindNotFound, "Wallet is not found"))
+
+		_, err := gh.GetDetail(context.TODO(), &model.GetDetailRequest{WalletId: "1"})
+
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("when min offset is invalid", func(t *testing.T) {
+		usecase := &mocks.Usecase{}
+		gh := wallet.GRPCHandler{Logger: logrus.New(), Validate: vld, Usecase: usecase}
+
+		_, err := gh.GetDetail(context.TODO(), &model.GetDetailRequest{WalletId: "1", MinOffset: "latest"})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("when wallet id is empty", func(t *testing.T) {
+		usecase := &mocks.Usecase{}
+		gh := wallet.GRPCHandler{Logger: logrus.New(), Validate: vld, Usecase: usecase}
+		user := auth.Principal{Kind: auth.KindUser, Subject: "user-1", Wallets: []string{"2"}}
+
+		_, err := gh.GetDetail(auth.ContextWithPrincipal(context.TODO(), user), &model.GetDetailRequest{})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		usecase.AssertNotCalled(t, "GetDetail", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("when user does not own the wallet", func(t *testing.T) {
+		usecase := &mocks.Usecase{}
+		gh := wallet.GRPCHandler{Logger: logrus.New(), Validate: vld, Usecase: usecase}
+		user := auth.Principal{Kind: auth.KindUser, Subject: "user-1", Wallets: []string{"2"}}
+
+		_, err := gh.GetDetail(auth.ContextWithPrincipal(context.TODO(), user), &model.GetDetailRequest{WalletId: "1"})
+
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		usecase.AssertNotCalled(t, "GetDetail", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestGRPCWatchWallet(t *testing.T) {
+	var listeners []pubsub.UpdateListener
+	balanceTableMock, thresholdTableMock := newStreamViews(&listeners)
+	hub := wallet.NewStreamHub(balanceTableMock, thresholdTableMock, 1)
+	var offset int64 = 1
+	balanceTableMock.On("Get", "1").Return(func(string) interface{} {
+		o := atomic.LoadInt64(&offset)
+		return &entity.Wallet{WalletId: "1", Balance: float64(o * 100), LastDepositOffset: o}
+	}, nil)
+	thresholdTableMock.On("Get", "1").Return(func(string) interface{} {
+		return &entity.Threshold{WalletId: "1", LastDepositOffset: atomic.LoadInt64(&offset)}
+	}, nil)
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	wallet.NewWalletGRPCHandler(logrus.New(), vld, server, &mocks.Usecase{}, hub)
+	go server.Serve(listener)
+	defer server.Stop()
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	}))
+	assert.Nil(t, err)
+	defer conn.Close()
+	client := model.NewWalletServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	stream, err := client.WatchWallet(ctx, &model.WatchWalletRequest{WalletId: "1"})
+	assert.Nil(t, err)
+
+	first, err := stream.Recv()
+	assert.Nil(t, err)
+	assert.Equal(t, float64(100), first.Balance)
+	assert.Equal(t, "0:1", first.ConsistencyToken)
+
+	atomic.StoreInt64(&offset, 2)
+	listeners[0]("1", nil)
+
+	second, err := stream.Recv()
+	assert.Nil(t, err)
+	assert.Equal(t, float64(200), second.Balance)
+	assert.Equal(t, "0:2", second.ConsistencyToken)
+
+	empty, err := client.WatchWallet(ctx, &model.WatchWalletRequest{})
+	assert.Nil(t, err)
+	_, err = empty.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "should reject an empty wallet id before taking a stream")
+}
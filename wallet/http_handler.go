@@ -4,9 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
+	"github.com/ijalalfrz/coinbit-test/auth"
+	"github.com/ijalalfrz/coinbit-test/exception"
+	"github.com/ijalalfrz/coinbit-test/pubsub"
 	"github.com/ijalalfrz/coinbit-test/response"
 	"github.com/ijalalfrz/coinbit-test/webmodel"
 	"github.com/sirupsen/logrus"
@@ -16,6 +21,40 @@ const (
 	basePath = "/wallet"
 )
 
+// collection of request error message, they do not tell the internal errors
+const (
+	minOffsetErrMessage = "min_offset must be a consistency token as partition:offset"
+	waitErrMessage      = "wait must be true or false"
+	payloadErrMessage   = "Request body must be a json object with wallet_id and amount"
+	batchErrMessage     = "Request body must be a json object with wallet_ids"
+	limitErrMessage     = "limit must be a number between 1 and 500"
+	balanceErrMessage   = "min_balance and max_balance must be numbers"
+	thresholdErrMessage = "above_threshold must be true or false"
+	sortErrMessage      = "sort must be wallet_id, -wallet_id, balance or -balance"
+	listSuccessMessage  = "List wallets"
+	batchSuccessMessage = "Batch of wallet details"
+	atErrMessage        = "at must be an RFC 3339 timestamp or a consistency token as partition:offset"
+	periodErrMessage    = "period must be daily or monthly"
+	formatErrMessage    = "format must be json or csv"
+)
+
+// Page sizes of the wallets list.
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+// Path templates of the wallet endpoints.
+const (
+	DepositPath = basePath + "/v1/deposit"
+	DetailsPath = basePath + "/v1/details/{walletId}"
+	// BatchDetailsPath is the path of the batch of details, the colon is not a path variable.
+	BatchDetailsPath = basePath + "/v1/details:batch"
+	WalletsPath      = basePath + "/v1/wallets"
+	BalanceAtPath    = basePath + "/v1/wallets/{walletId}/balance"
+	StatementsPath   = basePath + "/v1/wallets/{walletId}/statements"
+)
+
 // HTTPHandler is a concrete struct of wallet http handler.
 type HTTPHandler struct {
 	Logger   *logrus.Logger
@@ -29,12 +68,17 @@ func NewWalletHTTPHandler(logger *logrus.Logger, validate *validator.Validate, r
 		Validate: validate,
 		Usecase:  usecase,
 	}
-	router.HandleFunc(basePath+"/v1/deposit", handler.DepositWallet).Methods(http.MethodPost)
-	router.HandleFunc(basePath+"/v1/details/{walletId}", handler.GetDetailWallet).Methods(http.MethodGet)
+	router.HandleFunc(DepositPath, handler.DepositWallet).Methods(http.MethodPost)
+	router.HandleFunc(DetailsPath, handler.GetDetailWallet).Methods(http.MethodGet)
+	router.HandleFunc(BatchDetailsPath, handler.GetBatchDetailWallet).Methods(http.MethodPost)
+	router.HandleFunc(WalletsPath, handler.ListWallets).Methods(http.MethodGet)
+	router.HandleFunc(BalanceAtPath, handler.GetBalanceAt).Methods(http.MethodGet)
+	router.HandleFunc(StatementsPath, handler.GetStatements).Methods(http.MethodGet)
 
 }
 
-// GetDetailWallet is a function  handle get detail wallet
+// GetDetailWallet is a function  handle get detail wallet, the optional min_offset query is the
+// consistency token of a deposit which must be applied before answering
 func (handler HTTPHandler) GetDetailWallet(w http.ResponseWriter, r *http.Request) {
 	var resp response.Response
 	ctx := r.Context()
@@ -42,46 +86,305 @@ func (handler HTTPHandler) GetDetailWallet(w http.ResponseWriter, r *http.Reques
 	pathVariables := mux.Vars(r)
 	walletId := pathVariables["walletId"]
 
-	resp = handler.Usecase.GetDetail(ctx, walletId)
-	response.JSON(w, resp)
+	if principal, ok := auth.PrincipalFromContext(ctx); ok && !principal.CanAccessWallet(walletId) {
+		response.Negotiate(w, r, response.FromError(exception.New(exception.KindForbidden, detailForbiddenErrMessage)))
+		return
+	}
+
+	var minPosition *pubsub.Position
+	if token := r.URL.Query().Get("min_offset"); token != "" {
+		position, err := pubsub.ParsePosition(token)
+		if err != nil {
+			response.Negotiate(w, r, response.FromError(exception.Wrap(exception.KindBadRequest, err, minOffsetErrMessage)))
+			return
+		}
+		minPosition = &position
+	}
+
+	detail, err := handler.Usecase.GetDetail(ctx, walletId, minPosition)
+	if err != nil {
+		resp = response.FromError(err)
+	} else {
+		resp = response.NewSuccessResponse(detail, response.StatOK, detailSuccessMessage)
+	}
+	response.Negotiate(w, r, resp)
+	return
+}
+
+// GetBalanceAt is a function to handle get balance of a wallet at a point in time, the required at query is
+// an RFC 3339 timestamp or the consistency token of a deposit
+func (handler HTTPHandler) GetBalanceAt(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	ctx := r.Context()
+
+	walletId := mux.Vars(r)["walletId"]
+	if principal, ok := auth.PrincipalFromContext(ctx); ok && !principal.CanAccessWallet(walletId) {
+		response.Negotiate(w, r, response.FromError(exception.New(exception.KindForbidden, detailForbiddenErrMessage)))
+		return
+	}
+
+	at, err := ParsePointInTime(r.URL.Query().Get("at"))
+	if err != nil {
+		response.Negotiate(w, r, response.FromError(exception.Wrap(exception.KindBadRequest, err, atErrMessage)))
+		return
+	}
+
+	balance, err := handler.Usecase.GetBalanceAt(ctx, walletId, at)
+	if err != nil {
+		resp = response.FromError(err)
+	} else {
+		resp = response.NewSuccessResponse(balance, response.StatOK, balanceAtSuccessMessage)
+	}
+	response.Negotiate(w, r, resp)
+	return
+}
+
+// GetStatements is a function to handle get statements of a wallet, the period query is daily or monthly (the default).
+// The statements are answered as CSV when the format query is csv or the client accepts text/csv.
+func (handler HTTPHandler) GetStatements(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	ctx := r.Context()
+
+	walletId := mux.Vars(r)["walletId"]
+	if principal, ok := auth.PrincipalFromContext(ctx); ok && !principal.CanAccessWallet(walletId) {
+		response.Negotiate(w, r, response.FromError(exception.New(exception.KindForbidden, detailForbiddenErrMessage)))
+		return
+	}
+
+	query := r.URL.Query()
+	period := query.Get("period")
+	switch period {
+	case "":
+		period = StatementPeriodMonthly
+	case StatementPeriodDaily, StatementPeriodMonthly:
+	default:
+		response.Negotiate(w, r, response.FromError(exception.New(exception.KindBadRequest, periodErrMessage)))
+		return
+	}
+	var asCSV bool
+	switch query.Get("format") {
+	case "":
+		asCSV = response.Accepts(r.Header.Get("Accept"), response.CSVContentType)
+	case "json":
+	case "csv":
+		asCSV = true
+	default:
+		response.Negotiate(w, r, response.FromError(exception.New(exception.KindBadRequest, formatErrMessage)))
+		return
+	}
+
+	statements, err := handler.Usecase.GetStatements(ctx, walletId, period)
+	if err != nil {
+		resp = response.FromError(err)
+	} else if asCSV {
+		response.CSV(w, fmt.Sprintf("%s-%s-statements.csv", walletId, period), statementRecords(statements))
+		return
+	} else {
+		resp = response.NewSuccessResponse(statements, response.StatOK, statementSuccessMessage)
+	}
+	response.Negotiate(w, r, resp)
+	return
+}
+
+// statementRecords returns the statements as CSV records with a header.
+func statementRecords(statements webmodel.StatementResponse) [][]string {
+	records := [][]string{{"wallet_id", "period", "opening_balance", "credits", "debits", "closing_balance", "deposits"}}
+	for _, bucket := range statements.Statements {
+		records = append(records, []string{
+			statements.WalletId,
+			bucket.Period,
+			strconv.FormatFloat(bucket.OpeningBalance, 'f', -1, 64),
+			strconv.FormatFloat(bucket.Credits, 'f', -1, 64),
+			strconv.FormatFloat(bucket.Debits, 'f', -1, 64),
+			strconv.FormatFloat(bucket.ClosingBalance, 'f', -1, 64),
+			strconv.Itoa(bucket.Deposits),
+		})
+	}
+	return records
+}
+
+// GetBatchDetailWallet is a function to handle get details of many wallets, every wallet id of the body is answered
+// in the same order with the wallet or the code of why it is not found. The users only read the wallets they own,
+// the other ones are answered as forbidden.
+func (handler HTTPHandler) GetBatchDetailWallet(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var payload webmodel.BatchDetailWalletPayload
+	ctx := r.Context()
+
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		response.Negotiate(w, r, response.FromError(exception.Wrap(exception.KindUnprocessableEntity, err, batchErrMessage)))
+		return
+	}
+	if err := handler.validateRequestBody(payload); err != nil {
+		response.Negotiate(w, r, response.FromError(err))
+		return
+	}
+
+	details := make([]webmodel.BatchDetailWalletItem, len(payload.WalletIds))
+	var allowed []string
+	var positions []int
+	principal, authenticated := auth.PrincipalFromContext(ctx)
+	for i, walletId := range payload.WalletIds {
+		if authenticated && !principal.CanAccessWallet(walletId) {
+			details[i] = webmodel.BatchDetailWalletItem{WalletId: walletId, ErrorCode: exception.CodeForbidden}
+			continue
+		}
+		allowed = append(allowed, walletId)
+		positions = append(positions, i)
+	}
+
+	if len(allowed) > 0 {
+		found, err := handler.Usecase.GetDetails(ctx, allowed)
+		if err != nil {
+			response.Negotiate(w, r, response.FromError(err))
+			return
+		}
+		for i, detail := range found {
+			details[positions[i]] = detail
+		}
+	}
+
+	resp = response.NewSuccessResponse(details, response.StatOK, batchSuccessMessage)
+	response.Negotiate(w, r, resp)
 	return
 }
 
-// DepositWallet is a function to handle deposit request
+// ListWallets is a function to handle list wallets, the wallets are filtered by min_balance, max_balance,
+// above_threshold and prefix queries, sorted by sort query and paginated by cursor and limit queries.
+// The users only list the wallets they own.
+func (handler HTTPHandler) ListWallets(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	ctx := r.Context()
+
+	query, err := parseListWalletsQuery(r.URL.Query())
+	if err != nil {
+		response.Negotiate(w, r, response.FromError(err))
+		return
+	}
+	if principal, ok := auth.PrincipalFromContext(ctx); ok && principal.Kind != auth.KindService {
+		query.Restricted = true
+		query.WalletIds = principal.Wallets
+	}
+
+	wallets, page, err := handler.Usecase.ListWallets(ctx, query)
+	if err != nil {
+		resp = response.FromError(err)
+	} else {
+		resp = response.NewSuccessResponseWithMeta(wallets, page, response.StatOK, listSuccessMessage)
+	}
+	response.Negotiate(w, r, resp)
+	return
+}
+
+// parseListWalletsQuery will read the list wallets query, the limit is defaultListLimit when it is not set.
+func parseListWalletsQuery(values url.Values) (query webmodel.ListWalletsQuery, err error) {
+	query = webmodel.ListWalletsQuery{
+		Prefix: values.Get("prefix"),
+		Sort:   values.Get("sort"),
+		Cursor: values.Get("cursor"),
+		Limit:  defaultListLimit,
+	}
+
+	if value := values.Get("limit"); value != "" {
+		limit, parseErr := strconv.Atoi(value)
+		if parseErr != nil || limit < 1 || limit > maxListLimit {
+			return query, exception.New(exception.KindBadRequest, limitErrMessage)
+		}
+		query.Limit = limit
+	}
+
+	for name, balance := range map[string]**float64{"min_balance": &query.MinBalance, "max_balance": &query.MaxBalance} {
+		value := values.Get(name)
+		if value == "" {
+			continue
+		}
+		parsed, parseErr := strconv.ParseFloat(value, 64)
+		if parseErr != nil {
+			return query, exception.Wrap(exception.KindBadRequest, parseErr, balanceErrMessage)
+		}
+		*balance = &parsed
+	}
+
+	if value := values.Get("above_threshold"); value != "" {
+		query.AboveThresholdOnly, err = strconv.ParseBool(value)
+		if err != nil {
+			return query, exception.Wrap(exception.KindBadRequest, err, thresholdErrMessage)
+		}
+	}
+
+	switch query.Sort {
+	case "", SortByWalletId, SortByWalletIdDesc, SortByBalance, SortByBalanceDesc:
+	default:
+		return query, exception.New(exception.KindBadRequest, sortErrMessage)
+	}
+	return
+}
+
+// DepositWallet is a function to handle deposit request, with wait=true query it answers the new balance
+// once the deposit is applied
 func (handler HTTPHandler) DepositWallet(w http.ResponseWriter, r *http.Request) {
 	var resp response.Response
 	var payload webmodel.DepositWalletPayload
 
 	ctx := r.Context()
 
+	wait := false
+	if value := r.URL.Query().Get("wait"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			response.Negotiate(w, r, response.FromError(exception.Wrap(exception.KindBadRequest, err, waitErrMessage)))
+			return
+		}
+		wait = parsed
+	}
+
 	err := json.NewDecoder(r.Body).Decode(&payload)
 	if err != nil {
-		resp = response.NewErrorResponse(err, http.StatusUnprocessableEntity, nil, response.StatusInvalidPayload, err.Error())
-		response.JSON(w, resp)
+		response.Negotiate(w, r, response.FromError(exception.Wrap(exception.KindUnprocessableEntity, err, payloadErrMessage)))
 		return
 	}
 
 	if err := handler.validateRequestBody(payload); err != nil {
-		resp = response.NewErrorResponse(err, http.StatusBadRequest, nil, response.StatusInvalidPayload, err.Error())
-		response.JSON(w, resp)
+		response.Negotiate(w, r, response.FromError(err))
 		return
 	}
 
-	resp = handler.Usecase.Deposit(ctx, payload)
-	response.JSON(w, resp)
+	var result webmodel.DepositWalletResponse
+	if wait {
+		result, err = handler.Usecase.DepositAndWait(ctx, payload)
+	} else {
+		result, err = handler.Usecase.Deposit(ctx, payload)
+	}
+	switch {
+	case err != nil:
+		resp = response.FromError(err)
+	case !wait:
+		resp = response.NewSuccessResponse(result, response.StatOK, depositSuccessMessage)
+	case result.Wallet == nil:
+		resp = response.NewSuccessResponse(result, response.StatAccepted, depositAcceptedMessage)
+	default:
+		resp = response.NewSuccessResponse(result, response.StatOK, depositAppliedMessage)
+	}
+	response.Negotiate(w, r, resp)
 	return
 }
 
 // validateRequestBody will validate payload to be processed
 func (handler HTTPHandler) validateRequestBody(body interface{}) (err error) {
-	err = handler.Validate.Struct(body)
+	return validatePayload(handler.Validate, body)
+}
+
+// validatePayload will validate payload, the bad request error describes the first invalid field
+func validatePayload(validate *validator.Validate, body interface{}) (err error) {
+	err = validate.Struct(body)
 	if err == nil {
 		return
 	}
 
 	errorFields := err.(validator.ValidationErrors)
 	errorField := errorFields[0]
-	err = fmt.Errorf("Invalid '%s' with value '%v'", errorField.Field(), errorField.Value())
+	err = exception.Wrap(exception.KindBadRequest, err, fmt.Sprintf("Invalid '%s' with value '%v'", errorField.Field(), errorField.Value())).
+		WithDetails(map[string]interface{}{"field": errorField.Field()})
 
 	return
 }
@@ -7,11 +7,14 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
+	"github.com/ijalalfrz/coinbit-test/auth"
+	"github.com/ijalalfrz/coinbit-test/entity"
 	"github.com/ijalalfrz/coinbit-test/exception"
-	"github.com/ijalalfrz/coinbit-test/response"
+	"github.com/ijalalfrz/coinbit-test/pubsub"
 	"github.com/ijalalfrz/coinbit-test/wallet"
 	"github.com/ijalalfrz/coinbit-test/wallet/mocks"
 	"github.com/ijalalfrz/coinbit-test/webmodel"
@@ -52,6 +55,8 @@ func TestDepositWallet_Error_UnprocessableEntity(t *testing.T) {
 
 	handler.ServeHTTP(recorder, r)
 	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), "invalid character", "decoder error should not be sent")
+	assert.Contains(t, recorder.Body.String(), `"error_code":"unprocessable_entity"`)
 }
 
 func TestDepositWallet_Error_BadRequest(t *testing.T) {
@@ -68,6 +73,7 @@ func TestDepositWallet_Error_BadRequest(t *testing.T) {
 
 	handler.ServeHTTP(recorder, r)
 	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"data":{"field":"WalletId"}`, "invalid field should be in the details")
 }
 
 func TestDepositWallet_Error_UnexpectedError(t *testing.T) {
@@ -81,8 +87,7 @@ func TestDepositWallet_Error_UnexpectedError(t *testing.T) {
 		WalletId: "1",
 		Amount:   1000,
 	})
-	resp := response.NewErrorResponse(exception.ErrInternalServer, http.StatusInternalServerError, nil, response.StatUnexpectedError, "Error")
-	usecase.On("Deposit", mock.Anything, mock.Anything).Return(resp)
+	usecase.On("Deposit", mock.Anything, mock.Anything).Return(webmodel.DepositWalletResponse{}, exception.ErrInternalServer)
 	r := httptest.NewRequest(http.MethodPost, "/just/for/testing", bytes.NewReader(payload))
 	recorder := httptest.NewRecorder()
 	handler := http.HandlerFunc(hh.DepositWallet)
@@ -103,8 +108,7 @@ func TestDepositWallet_Success(t *testing.T) {
 		WalletId: "1",
 		Amount:   1000,
 	})
-	resp := response.NewSuccessResponse(nil, response.StatOK, "Success")
-	usecase.On("Deposit", mock.Anything, mock.Anything).Return(resp)
+	usecase.On("Deposit", mock.Anything, mock.Anything).Return(webmodel.DepositWalletResponse{}, nil)
 	r := httptest.NewRequest(http.MethodPost, "/just/for/testing", bytes.NewReader(payload))
 	recorder := httptest.NewRecorder()
 	handler := http.HandlerFunc(hh.DepositWallet)
@@ -122,8 +126,7 @@ func TestGetDetail_Success(t *testing.T) {
 		Usecase:  usecase,
 	}
 
-	resp := response.NewSuccessResponse(nil, response.StatOK, "Success")
-	usecase.On("GetDetail", mock.Anything, mock.Anything).Return(resp)
+	usecase.On("GetDetail", mock.Anything, mock.Anything, mock.Anything).Return(webmodel.DetailWalletResponse{}, nil)
 	r := httptest.NewRequest(http.MethodGet, "/just/for/testing", nil)
 	recorder := httptest.NewRecorder()
 	handler := http.HandlerFunc(hh.GetDetailWallet)
@@ -141,8 +144,7 @@ func TestGetDetail_Error_NotFound(t *testing.T) {
 		Usecase:  usecase,
 	}
 
-	resp := response.NewErrorResponse(exception.ErrNotFound, http.StatusNotFound, nil, response.StatNotFound, "Not found")
-	usecase.On("GetDetail", mock.Anything, mock.Anything).Return(resp)
+	usecase.On("GetDetail", mock.Anything, mock.Anything, mock.Anything).Return(webmodel.DetailWalletResponse{}, exception.New(exception.KindNotFound, "Not found"))
 	r := httptest.NewRequest(http.MethodGet, "/just/for/testing", nil)
 	recorder := httptest.NewRecorder()
 	handler := http.HandlerFunc(hh.GetDetailWallet)
@@ -151,3 +153,349 @@ func TestGetDetail_Error_NotFound(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, recorder.Code)
 	usecase.AssertExpectations(t)
 }
+
+func TestGetDetail_Authorization(t *testing.T) {
+	hh := func(usecase *mocks.Usecase) http.Handler {
+		return http.HandlerFunc(wallet.HTTPHandler{
+			Logger:   logrus.New(),
+			Validate: vld,
+			Usecase:  usecase,
+		}.GetDetailWallet)
+	}
+	user := auth.Principal{Kind: auth.KindUser, Subject: "user-1", Wallets: []string{"1"}}
+
+	t.Run("when user does not own the wallet", func(t *testing.T) {
+		usecase := &mocks.Usecase{}
+		r := httptest.NewRequest(http.MethodGet, "/just/for/testing", nil)
+		r = mux.SetURLVars(r, map[string]string{"walletId": "2"})
+		r = r.WithContext(auth.ContextWithPrincipal(r.Context(), user))
+		recorder := httptest.NewRecorder()
+
+		hh(usecase).ServeHTTP(recorder, r)
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+		usecase.AssertNotCalled(t, "GetDetail", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("when user owns the wallet", func(t *testing.T) {
+		usecase := &mocks.Usecase{}
+		usecase.On("GetDetail", mock.Anything, "1", mock.Anything).Return(webmodel.DetailWalletResponse{}, nil)
+		r := httptest.NewRequest(http.MethodGet, "/just/for/testing", nil)
+		r = mux.SetURLVars(r, map[string]string{"walletId": "1"})
+		r = r.WithContext(auth.ContextWithPrincipal(r.Context(), user))
+		recorder := httptest.NewRecorder()
+
+		hh(usecase).ServeHTTP(recorder, r)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		usecase.AssertExpectations(t)
+	})
+}
+
+func TestGetDetail_Error_Invalid_MinOffset(t *testing.T) {
+	usecase := &mocks.Usecase{}
+	hh := wallet.HTTPHandler{
+		Logger:   logrus.New(),
+		Validate: vld,
+		Usecase:  usecase,
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/just/for/testing?min_offset=abc", nil)
+	recorder := httptest.NewRecorder()
+	handler := http.HandlerFunc(hh.GetDetailWallet)
+
+	handler.ServeHTTP(recorder, r)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	usecase.AssertNotCalled(t, "GetDetail", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetDetail_With_MinOffset(t *testing.T) {
+	usecase := &mocks.Usecase{}
+	hh := wallet.HTTPHandler{
+		Logger:   logrus.New(),
+		Validate: vld,
+		Usecase:  usecase,
+	}
+
+	usecase.On("GetDetail", mock.Anything, mock.Anything, &pubsub.Position{Partition: 2, Offset: 15}).Return(webmodel.DetailWalletResponse{}, nil)
+	r := httptest.NewRequest(http.MethodGet, "/just/for/testing?min_offset=2:15", nil)
+	recorder := httptest.NewRecorder()
+	handler := http.HandlerFunc(hh.GetDetailWallet)
+
+	handler.ServeHTTP(recorder, r)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	usecase.AssertExpectations(t)
+}
+
+func TestDepositWallet_Wait(t *testing.T) {
+	payload, _ := json.Marshal(webmodel.DepositWalletPayload{
+		WalletId: "1",
+		Amount:   1000,
+	})
+
+	t.Run("when wait is true", func(t *testing.T) {
+		usecase := &mocks.Usecase{}
+		hh := wallet.HTTPHandler{
+			Logger:   logrus.New(),
+			Validate: vld,
+			Usecase:  usecase,
+		}
+		usecase.On("DepositAndWait", mock.Anything, mock.Anything).Return(webmodel.DepositWalletResponse{ConsistencyToken: "0:3"}, nil)
+		r := httptest.NewRequest(http.MethodPost, "/just/for/testing?wait=true", bytes.NewReader(payload))
+		recorder := httptest.NewRecorder()
+
+		http.HandlerFunc(hh.DepositWallet).ServeHTTP(recorder, r)
+		assert.Equal(t, http.StatusAccepted, recorder.Code)
+		usecase.AssertExpectations(t)
+		usecase.AssertNotCalled(t, "Deposit", mock.Anything, mock.Anything)
+	})
+
+	t.Run("when wait is invalid", func(t *testing.T) {
+		usecase := &mocks.Usecase{}
+		hh := wallet.HTTPHandler{
+			Logger:   logrus.New(),
+			Validate: vld,
+			Usecase:  usecase,
+		}
+		r := httptest.NewRequest(http.MethodPost, "/just/for/testing?wait=soon", bytes.NewReader(payload))
+		recorder := httptest.NewRecorder()
+
+		http.HandlerFunc(hh.DepositWallet).ServeHTTP(recorder, r)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+}
+
+func TestListWallets_HTTP(t *testing.T) {
+	newRouter := func(usecase *mocks.Usecase) *mux.Router {
+		router := mux.NewRouter()
+		wallet.NewWalletHTTPHandler(logrus.New(), vld, router, usecase)
+		return router
+	}
+
+	t.Run("when wallets are listed", func(t *testing.T) {
+		usecase := &mocks.Usecase{}
+		minBalance := float64(100)
+		query := webmodel.ListWalletsQuery{MinBalance: &minBalance, AboveThresholdOnly: true, Prefix: "user-", Sort: wallet.SortByBalance, Cursor: "abc", Limit: 2}
+		usecase.On("ListWallets", mock.Anything, query).Return([]webmodel.DetailWalletResponse{{WalletId: "user-1", Balance: 100}},
+			webmodel.PageMeta{Limit: 2, Count: 1, Total: 1, Sort: wallet.SortByBalance}, nil)
+		r := httptest.NewRequest(http.MethodGet, "/wallet/v1/wallets?min_balance=100&above_threshold=true&prefix=user-&sort=balance&cursor=abc&limit=2", nil)
+		recorder := httptest.NewRecorder()
+
+		newRouter(usecase).ServeHTTP(recorder, r)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"meta":{"limit":2,"count":1,"total":1,"sort":"balance"}`)
+		usecase.AssertExpectations(t)
+	})
+
+	t.Run("when user lists wallets", func(t *testing.T) {
+		usecase := &mocks.Usecase{}
+		query := webmodel.ListWalletsQuery{Limit: 50, Restricted: true, WalletIds: []string{"1"}}
+		usecase.On("ListWallets", mock.Anything, query).Return([]webmodel.DetailWalletResponse{}, webmodel.PageMeta{Limit: 50}, nil)
+		user := auth.Principal{Kind: auth.KindUser, Subject: "user-1", Wallets: []string{"1"}}
+		r := httptest.NewRequest(http.MethodGet, "/wallet/v1/wallets", nil)
+		r = r.WithContext(auth.ContextWithPrincipal(r.Context(), user))
+		recorder := httptest.NewRecorder()
+
+		newRouter(usecase).ServeHTTP(recorder, r)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		usecase.AssertExpectations(t)
+	})
+
+	t.Run("when query is invalid", func(t *testing.T) {
+		for _, rawQuery := range []string{"limit=0", "limit=501", "min_balance=lots", "above_threshold=maybe", "sort=name"} {
+			usecase := &mocks.Usecase{}
+			r := httptest.NewRequest(http.MethodGet, "/wallet/v1/wallets?"+rawQuery, nil)
+			recorder := httptest.NewRecorder()
+
+			newRouter(usecase).ServeHTTP(recorder, r)
+
+			assert.Equal(t, http.StatusBadRequest, recorder.Code, rawQuery)
+			usecase.AssertNotCalled(t, "ListWallets", mock.Anything, mock.Anything)
+		}
+	})
+}
+
+func TestGetBatchDetailWallet(t *testing.T) {
+	newRouter := func(usecase *mocks.Usecase) *mux.Router {
+		router := mux.NewRouter()
+		wallet.NewWalletHTTPHandler(logrus.New(), vld, router, usecase)
+		return router
+	}
+
+	t.Run("when user reads wallets it does not own", func(t *testing.T) {
+		usecase := &mocks.Usecase{}
+		usecase.On("GetDetails", mock.Anything, []string{"1", "3"}).Return([]webmodel.BatchDetailWalletItem{
+			{WalletId: "1", Found: true, Wallet: &webmodel.DetailWalletResponse{WalletId: "1", Balance: 100}},
+			{WalletId: "3", ErrorCode: exception.CodeNotFound},
+		}, nil)
+		user := auth.Principal{Kind: auth.KindUser, Subject: "user-1", Wallets: []string{"1", "3"}}
+		r := httptest.NewRequest(http.MethodPost, "/wallet/v1/details:batch", strings.NewReader(`{"wallet_ids":["1","2","3"]}`))
+		r = r.WithContext(auth.ContextWithPrincipal(r.Context(), user))
+		recorder := httptest.NewRecorder()
+
+		newRouter(usecase).ServeHTTP(recorder, r)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var body struct {
+			Data []webmodel.BatchDetailWalletItem `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		assert.Equal(t, []webmodel.BatchDetailWalletItem{
+			{WalletId: "1", Found: true, Wallet: &webmodel.DetailWalletResponse{WalletId: "1", Balance: 100}},
+			{WalletId: "2", ErrorCode: exception.CodeForbidden},
+			{WalletId: "3", ErrorCode: exception.CodeNotFound},
+		}, body.Data)
+		usecase.AssertExpectations(t)
+	})
+
+	t.Run("when wallet ids are empty", func(t *testing.T) {
+		usecase := &mocks.Usecase{}
+		r := httptest.NewRequest(http.MethodPost, "/wallet/v1/details:batch", strings.NewReader(`{"wallet_ids":[]}`))
+		recorder := httptest.NewRecorder()
+
+		newRouter(usecase).ServeHTTP(recorder, r)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		usecase.AssertNotCalled(t, "GetDetails", mock.Anything, mock.Anything)
+	})
+
+	t.Run("when views are not readable", func(t *testing.T) {
+		usecase := &mocks.Usecase{}
+		usecase.On("GetDetails", mock.Anything, mock.Anything).Return(nil, exception.New(exception.KindInternal, "Unexpected"))
+		r := httptest.NewRequest(http.MethodPost, "/wallet/v1/details:batch", strings.NewReader(`{"wallet_ids":["1"]}`))
+		recorder := httptest.NewRecorder()
+
+		newRouter(usecase).ServeHTTP(recorder, r)
+
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	})
+}
+
+func TestGetBalanceAt_HTTP(t *testing.T) {
+	newRouter := func(usecase *mocks.Usecase) *mux.Router {
+		router := mux.NewRouter()
+		wallet.NewWalletHTTPHandler(logrus.New(), vld, router, usecase)
+		return router
+	}
+
+	t.Run("at a timestamp", func(t *testing.T) {
+		usecase := &mocks.Usecase{}
+		at := wallet.PointInTime{Time: time.Date(2022, 8, 1, 23, 59, 59, 0, time.UTC)}
+		usecase.On("GetBalanceAt", mock.Anything, "1", at).Return(webmodel.BalanceAtResponse{WalletId: "1", Balance: 100, Deposits: 1}, nil)
+		r := httptest.NewRequest(http.MethodGet, "/wallet/v1/wallets/1/balance?at=2022-08-01T23:59:59Z", nil)
+		recorder := httptest.NewRecorder()
+
+		newRouter(usecase).ServeHTTP(recorder, r)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		usecase.AssertExpectations(t)
+	})
+
+	t.Run("at a consistency token", func(t *testing.T) {
+		usecase := &mocks.Usecase{}
+		at := wallet.PointInTime{Position: &pubsub.Position{Partition: 0, Offset: 3}}
+		usecase.On("GetBalanceAt", mock.Anything, "1", at).Return(webmodel.BalanceAtResponse{}, exception.New(exception.KindNotFound, "Not found"))
+		r := httptest.NewRequest(http.MethodGet, "/wallet/v1/wallets/1/balance?at=0:3", nil)
+		recorder := httptest.NewRecorder()
+
+		newRouter(usecase).ServeHTTP(recorder, r)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		usecase.AssertExpectations(t)
+	})
+
+	t.Run("when at is missing or invalid", func(t *testing.T) {
+		for _, query := range []string{"", "?at=yesterday"} {
+			usecase := &mocks.Usecase{}
+			r := httptest.NewRequest(http.MethodGet, "/wallet/v1/wallets/1/balance"+query, nil)
+			recorder := httptest.NewRecorder()
+
+			newRouter(usecase).ServeHTTP(recorder, r)
+
+			assert.Equal(t, http.StatusBadRequest, recorder.Code, query)
+			usecase.AssertNotCalled(t, "GetBalanceAt", mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("when user does not own the wallet", func(t *testing.T) {
+		usecase := &mocks.Usecase{}
+		user := auth.Principal{Kind: auth.KindUser, Subject: "user-1", Wallets: []string{"2"}}
+		r := httptest.NewRequest(http.MethodGet, "/wallet/v1/wallets/1/balance?at=0:3", nil)
+		r = r.WithContext(auth.ContextWithPrincipal(r.Context(), user))
+		recorder := httptest.NewRecorder()
+
+		newRouter(usecase).ServeHTTP(recorder, r)
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+		usecase.AssertNotCalled(t, "GetBalanceAt", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestGetStatements_HTTP(t *testing.T) {
+	newRouter := func(usecase *mocks.Usecase) *mux.Router {
+		router := mux.NewRouter()
+		wallet.NewWalletHTTPHandler(logrus.New(), vld, router, usecase)
+		return router
+	}
+	statements := webmodel.StatementResponse{
+		WalletId:   "1",
+		Period:     wallet.StatementPeriodMonthly,
+		Statements: []entity.StatementBucket{{Period: "2022-08", Credits: 1000.5, Debits: 200, ClosingBalance: 800.5, Deposits: 2}},
+	}
+
+	t.Run("as json by default", func(t *testing.T) {
+		usecase := &mocks.Usecase{}
+		usecase.On("GetStatements", mock.Anything, "1", wallet.StatementPeriodMonthly).Return(statements, nil)
+		r := httptest.NewRequest(http.MethodGet, "/wallet/v1/wallets/1/statements", nil)
+		recorder := httptest.NewRecorder()
+
+		newRouter(usecase).ServeHTTP(recorder, r)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"period":"2022-08"`)
+	})
+
+	t.Run("as csv", func(t *testing.T) {
+		for name, setup := range map[string]func(r *http.Request){
+			"by format": func(r *http.Request) { r.URL.RawQuery += "&format=csv" },
+			"by accept": func(r *http.Request) { r.Header.Set("Accept", "text/csv") },
+		} {
+			usecase := &mocks.Usecase{}
+			usecase.On("GetStatements", mock.Anything, "1", wallet.StatementPeriodDaily).Return(statements, nil)
+			r := httptest.NewRequest(http.MethodGet, "/wallet/v1/wallets/1/statements?period=daily", nil)
+			setup(r)
+			recorder := httptest.NewRecorder()
+
+			newRouter(usecase).ServeHTTP(recorder, r)
+
+			assert.Equal(t, http.StatusOK, recorder.Code, name)
+			assert.Equal(t, "wallet_id,period,opening_balance,credits,debits,closing_balance,deposits\n"+
+				"1,2022-08,0,1000.5,200,800.5,2\n", recorder.Body.String(), name)
+		}
+	})
+
+	t.Run("when period or format is invalid", func(t *testing.T) {
+		for _, query := range []string{"?period=weekly", "?format=xml"} {
+			usecase := &mocks.Usecase{}
+			r := httptest.NewRequest(http.MethodGet, "/wallet/v1/wallets/1/statements"+query, nil)
+			recorder := httptest.NewRecorder()
+
+			newRouter(usecase).ServeHTTP(recorder, r)
+
+			assert.Equal(t, http.StatusBadRequest, recorder.Code, query)
+			usecase.AssertNotCalled(t, "GetStatements", mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("when wallet is not found", func(t *testing.T) {
+		usecase := &mocks.Usecase{}
+		usecase.On("GetStatements", mock.Anything, "1", mock.Anything).Return(webmodel.StatementResponse{},
+			exception.New(exception.KindNotFound, "Wallet is not found"))
+		r := httptest.NewRequest(http.MethodGet, "/wallet/v1/wallets/1/statements?format=csv", nil)
+		recorder := httptest.NewRecorder()
+
+		newRouter(usecase).ServeHTTP(recorder, r)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
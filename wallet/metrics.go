@@ -0,0 +1,38 @@
+package wallet
+
+import (
+	"github.com/ijalalfrz/coinbit-test/entity"
+	"github.com/ijalalfrz/coinbit-test/pubsub"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// NewAboveThresholdGauge returns a gauge of wallets currently above threshold,
+// it is counted from the threshold view table on every scrape.
+func NewAboveThresholdGauge(logger *logrus.Logger, thresholdViewTable pubsub.ViewTable) prometheus.GaugeFunc {
+	return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "wallet_above_threshold",
+		Help: "Number of wallets currently above threshold.",
+	}, func() float64 {
+		var count float64
+		err := thresholdViewTable.Iterate(func(key string, value interface{}) bool {
+			if threshold, ok := value.(*entity.Threshold); ok && threshold.AboveThreshold {
+				count++
+			}
+			return true
+		})
+		if err != nil {
+			logger.Error(err)
+		}
+		return count
+	})
+}
+
+// addByDirection will add the absolute amount to the credit or the debit counter, a counter panics on a negative value.
+func addByDirection(counter *prometheus.CounterVec, amount float64) {
+	if amount < 0 {
+		counter.WithLabelValues("debit").Add(-amount)
+		return
+	}
+	counter.WithLabelValues("credit").Add(amount)
+}
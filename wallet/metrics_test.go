@@ -0,0 +1,39 @@
+package wallet_test
+
+import (
+	"testing"
+
+	"github.com/ijalalfrz/coinbit-test/entity"
+	"github.com/ijalalfrz/coinbit-test/exception"
+	pubsubMock "github.com/ijalalfrz/coinbit-test/pubsub/mocks"
+	"github.com/ijalalfrz/coinbit-test/wallet"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAboveThresholdGauge_Success(t *testing.T) {
+	thresholdTableMock := pubsubMock.ViewTable{}
+	thresholdTableMock.On("Iterate", mock.Anything).Return(func(fn func(string, interface{}) bool) error {
+		fn("1", &entity.Threshold{WalletId: "1", AboveThreshold: true})
+		fn("2", &entity.Threshold{WalletId: "2", AboveThreshold: false})
+		fn("3", &entity.Threshold{WalletId: "3", AboveThreshold: true})
+		return nil
+	})
+
+	gauge := wallet.NewAboveThresholdGauge(logrus.New(), &thresholdTableMock)
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(gauge))
+	thresholdTableMock.AssertExpectations(t)
+}
+
+func TestAboveThresholdGauge_Error_Iterate(t *testing.T) {
+	thresholdTableMock := pubsubMock.ViewTable{}
+	thresholdTableMock.On("Iterate", mock.Anything).Return(exception.ErrInternalServer)
+
+	gauge := wallet.NewAboveThresholdGauge(logrus.New(), &thresholdTableMock)
+
+	assert.Equal(t, float64(0), testutil.ToFloat64(gauge))
+	thresholdTableMock.AssertExpectations(t)
+}
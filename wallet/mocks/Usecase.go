@@ -5,13 +5,17 @@ package mocks
 import (
 	context "context"
 
+	entity "github.com/ijalalfrz/coinbit-test/entity"
+
 	goka "github.com/lovoo/goka"
 
 	mock "github.com/stretchr/testify/mock"
 
 	model "github.com/ijalalfrz/coinbit-test/model"
 
-	response "github.com/ijalalfrz/coinbit-test/response"
+	pubsub "github.com/ijalalfrz/coinbit-test/pubsub"
+
+	wallet "github.com/ijalalfrz/coinbit-test/wallet"
 
 	webmodel "github.com/ijalalfrz/coinbit-test/webmodel"
 )
@@ -22,65 +26,228 @@ type Usecase struct {
 }
 
 // AddBalance provides a mock function with given fields: ctx, payload
-func (_m *Usecase) AddBalance(ctx goka.Context, payload *model.DepositWallet) response.Response {
+func (_m *Usecase) AddBalance(ctx goka.Context, payload *model.DepositWallet) *entity.Wallet {
 	ret := _m.Called(ctx, payload)
 
-	var r0 response.Response
-	if rf, ok := ret.Get(0).(func(goka.Context, *model.DepositWallet) response.Response); ok {
+	var r0 *entity.Wallet
+	if rf, ok := ret.Get(0).(func(goka.Context, *model.DepositWallet) *entity.Wallet); ok {
 		r0 = rf(ctx, payload)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(response.Response)
+			r0 = ret.Get(0).(*entity.Wallet)
 		}
 	}
 
 	return r0
 }
 
-// Deposit provides a mock function with given fields: ctx, payload
-func (_m *Usecase) Deposit(ctx context.Context, payload webmodel.DepositWalletPayload) response.Response {
+// AggregateStatement provides a mock function with given fields: ctx, payload
+func (_m *Usecase) AggregateStatement(ctx goka.Context, payload *model.DepositWallet) *entity.Statement {
 	ret := _m.Called(ctx, payload)
 
-	var r0 response.Response
-	if rf, ok := ret.Get(0).(func(context.Context, webmodel.DepositWalletPayload) response.Response); ok {
+	var r0 *entity.Statement
+	if rf, ok := ret.Get(0).(func(goka.Context, *model.DepositWallet) *entity.Statement); ok {
 		r0 = rf(ctx, payload)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(response.Response)
+			r0 = ret.Get(0).(*entity.Statement)
 		}
 	}
 
 	return r0
 }
 
-// GetDetail provides a mock function with given fields: ctx, walletId
-func (_m *Usecase) GetDetail(ctx context.Context, walletId string) response.Response {
-	ret := _m.Called(ctx, walletId)
+// ApplyCorrection provides a mock function with given fields: ctx, payload
+func (_m *Usecase) ApplyCorrection(ctx goka.Context, payload *model.DepositWallet) *entity.Wallet {
+	ret := _m.Called(ctx, payload)
 
-	var r0 response.Response
-	if rf, ok := ret.Get(0).(func(context.Context, string) response.Response); ok {
-		r0 = rf(ctx, walletId)
+	var r0 *entity.Wallet
+	if rf, ok := ret.Get(0).(func(goka.Context, *model.DepositWallet) *entity.Wallet); ok {
+		r0 = rf(ctx, payload)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(response.Response)
+			r0 = ret.Get(0).(*entity.Wallet)
 		}
 	}
 
 	return r0
 }
 
+// Deposit provides a mock function with given fields: ctx, payload
+func (_m *Usecase) Deposit(ctx context.Context, payload webmodel.DepositWalletPayload) (webmodel.DepositWalletResponse, error) {
+	ret := _m.Called(ctx, payload)
+
+	var r0 webmodel.DepositWalletResponse
+	if rf, ok := ret.Get(0).(func(context.Context, webmodel.DepositWalletPayload) webmodel.DepositWalletResponse); ok {
+		r0 = rf(ctx, payload)
+	} else {
+		r0 = ret.Get(0).(webmodel.DepositWalletResponse)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, webmodel.DepositWalletPayload) error); ok {
+		r1 = rf(ctx, payload)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DepositAndWait provides a mock function with given fields: ctx, payload
+func (_m *Usecase) DepositAndWait(ctx context.Context, payload webmodel.DepositWalletPayload) (webmodel.DepositWalletResponse, error) {
+	ret := _m.Called(ctx, payload)
+
+	var r0 webmodel.DepositWalletResponse
+	if rf, ok := ret.Get(0).(func(context.Context, webmodel.DepositWalletPayload) webmodel.DepositWalletResponse); ok {
+		r0 = rf(ctx, payload)
+	} else {
+		r0 = ret.Get(0).(webmodel.DepositWalletResponse)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, webmodel.DepositWalletPayload) error); ok {
+		r1 = rf(ctx, payload)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBalanceAt provides a mock function with given fields: ctx, walletId, at
+func (_m *Usecase) GetBalanceAt(ctx context.Context, walletId string, at wallet.PointInTime) (webmodel.BalanceAtResponse, error) {
+	ret := _m.Called(ctx, walletId, at)
+
+	var r0 webmodel.BalanceAtResponse
+	if rf, ok := ret.Get(0).(func(context.Context, string, wallet.PointInTime) webmodel.BalanceAtResponse); ok {
+		r0 = rf(ctx, walletId, at)
+	} else {
+		r0 = ret.Get(0).(webmodel.BalanceAtResponse)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, wallet.PointInTime) error); ok {
+		r1 = rf(ctx, walletId, at)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetDetail provides a mock function with given fields: ctx, walletId, minPosition
+func (_m *Usecase) GetDetail(ctx context.Context, walletId string, minPosition *pubsub.Position) (webmodel.DetailWalletResponse, error) {
+	ret := _m.Called(ctx, walletId, minPosition)
+
+	var r0 webmodel.DetailWalletResponse
+	if rf, ok := ret.Get(0).(func(context.Context, string, *pubsub.Position) webmodel.DetailWalletResponse); ok {
+		r0 = rf(ctx, walletId, minPosition)
+	} else {
+		r0 = ret.Get(0).(webmodel.DetailWalletResponse)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, *pubsub.Position) error); ok {
+		r1 = rf(ctx, walletId, minPosition)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetDetails provides a mock function with given fields: ctx, walletIds
+func (_m *Usecase) GetDetails(ctx context.Context, walletIds []string) ([]webmodel.BatchDetailWalletItem, error) {
+	ret := _m.Called(ctx, walletIds)
+
+	var r0 []webmodel.BatchDetailWalletItem
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []webmodel.BatchDetailWalletItem); ok {
+		r0 = rf(ctx, walletIds)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]webmodel.BatchDetailWalletItem)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, walletIds)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetStatements provides a mock function with given fields: ctx, walletId, period
+func (_m *Usecase) GetStatements(ctx context.Context, walletId string, period string) (webmodel.StatementResponse, error) {
+	ret := _m.Called(ctx, walletId, period)
+
+	var r0 webmodel.StatementResponse
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) webmodel.StatementResponse); ok {
+		r0 = rf(ctx, walletId, period)
+	} else {
+		r0 = ret.Get(0).(webmodel.StatementResponse)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, walletId, period)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListWallets provides a mock function with given fields: ctx, query
+func (_m *Usecase) ListWallets(ctx context.Context, query webmodel.ListWalletsQuery) ([]webmodel.DetailWalletResponse, webmodel.PageMeta, error) {
+	ret := _m.Called(ctx, query)
+
+	var r0 []webmodel.DetailWalletResponse
+	if rf, ok := ret.Get(0).(func(context.Context, webmodel.ListWalletsQuery) []webmodel.DetailWalletResponse); ok {
+		r0 = rf(ctx, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]webmodel.DetailWalletResponse)
+		}
+	}
+
+	var r1 webmodel.PageMeta
+	if rf, ok := ret.Get(1).(func(context.Context, webmodel.ListWalletsQuery) webmodel.PageMeta); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Get(1).(webmodel.PageMeta)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, webmodel.ListWalletsQuery) error); ok {
+		r2 = rf(ctx, query)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // ProcessThreshold provides a mock function with given fields: ctx, payload
-func (_m *Usecase) ProcessThreshold(ctx goka.Context, payload *model.DepositWallet) response.Response {
+func (_m *Usecase) ProcessThreshold(ctx goka.Context, payload *model.DepositWallet) *entity.Threshold {
 	ret := _m.Called(ctx, payload)
 
-	var r0 response.Response
-	if rf, ok := ret.Get(0).(func(goka.Context, *model.DepositWallet) response.Response); ok {
+	var r0 *entity.Threshold
+	if rf, ok := ret.Get(0).(func(goka.Context, *model.DepositWallet) *entity.Threshold); ok {
 		r0 = rf(ctx, payload)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(response.Response)
+			r0 = ret.Get(0).(*entity.Threshold)
 		}
 	}
 
 	return r0
 }
+
+// ReloadRule provides a mock function with given fields: rule
+func (_m *Usecase) ReloadRule(rule wallet.Rule) {
+	_m.Called(rule)
+}
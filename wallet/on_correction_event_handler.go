@@ -0,0 +1,37 @@
+package wallet
+
+import (
+	"github.com/ijalalfrz/coinbit-test/model"
+	"github.com/ijalalfrz/coinbit-test/pubsub"
+	"github.com/lovoo/goka"
+	"github.com/sirupsen/logrus"
+	"go.elastic.co/apm"
+	"go.elastic.co/apm/module/apmlogrus"
+)
+
+// CorrectionEventHandler is a concrete struct of wallet balance correction event handler.
+type CorrectionEventHandler struct {
+	logger  *logrus.Logger
+	tracer  *apm.Tracer
+	usecase Usecase
+}
+
+// NewCorrectionEventHandler is a constructor.
+func NewCorrectionEventHandler(logger *logrus.Logger, tracer *apm.Tracer, usecase Usecase) pubsub.GokaEventHandler {
+	return &CorrectionEventHandler{logger, tracer, usecase}
+}
+
+// Handle will apply the correction to the balance of the wallet, the trace started by the publisher is continued when present.
+func (handler CorrectionEventHandler) Handle(ctx goka.Context, message interface{}) {
+	tx, txCtx := pubsub.StartTransaction(handler.tracer, ctx, "CorrectionEventHandler")
+	defer tx.End()
+	logger := handler.logger.WithFields(apmlogrus.TraceContext(txCtx))
+
+	payload, ok := message.(*model.DepositWallet)
+	if !ok {
+		logger.Error("Not a kafka message")
+		return
+	}
+	wallet := handler.usecase.ApplyCorrection(ctx, payload)
+	logger.Warnf(correctionSuccessMessage, wallet.WalletId, payload.GetAmount(), wallet.Balance)
+}
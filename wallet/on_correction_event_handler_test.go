@@ -0,0 +1,42 @@
+package wallet_test
+
+import (
+	"testing"
+
+	"github.com/ijalalfrz/coinbit-test/entity"
+	"github.com/ijalalfrz/coinbit-test/model"
+	pubsubMock "github.com/ijalalfrz/coinbit-test/pubsub/mocks"
+	"github.com/ijalalfrz/coinbit-test/wallet"
+	"github.com/ijalalfrz/coinbit-test/wallet/mocks"
+	"github.com/lovoo/goka"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/mock"
+	"go.elastic.co/apm/apmtest"
+)
+
+func TestOnCorrectionEventHandler_Error_When_CastMessage(t *testing.T) {
+	usecase := mocks.Usecase{}
+	context := pubsubMock.GokaContext{}
+	context.On("Headers").Return(goka.Headers(nil))
+	handler := wallet.NewCorrectionEventHandler(logrus.New(), apmtest.DiscardTracer, &usecase)
+
+	handler.Handle(&context, nil)
+
+	usecase.AssertNotCalled(t, "ApplyCorrection", mock.Anything, mock.Anything)
+}
+
+func TestOnCorrectionEventHandler_Success(t *testing.T) {
+	usecase := mocks.Usecase{}
+	context := pubsubMock.GokaContext{}
+	context.On("Headers").Return(goka.Headers(nil))
+	handler := wallet.NewCorrectionEventHandler(logrus.New(), apmtest.DiscardTracer, &usecase)
+	usecase.On("ApplyCorrection", mock.Anything, mock.Anything).Return(&entity.Wallet{WalletId: "1"})
+	payload := &model.DepositWallet{
+		WalletId: "1",
+		Amount:   1000,
+	}
+
+	handler.Handle(&context, payload)
+
+	usecase.AssertCalled(t, "ApplyCorrection", &context, payload)
+}
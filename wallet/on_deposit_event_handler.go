@@ -5,32 +5,36 @@ import (
 	"github.com/ijalalfrz/coinbit-test/pubsub"
 	"github.com/lovoo/goka"
 	"github.com/sirupsen/logrus"
+	"go.elastic.co/apm"
+	"go.elastic.co/apm/module/apmlogrus"
 )
 
 // DepositWalletEventHandler is a concrete struct of wallet event handler.
 type DepositWalletEventHandler struct {
 	logger   *logrus.Logger
+	tracer   *apm.Tracer
 	usescase Usecase
 }
 
 // NewDepositWalletEventHandler is a constructor.
-func NewDepositWalletEventHandler(logger *logrus.Logger, usecase Usecase) pubsub.GokaEventHandler {
-	return &DepositWalletEventHandler{logger, usecase}
+func NewDepositWalletEventHandler(logger *logrus.Logger, tracer *apm.Tracer, usecase Usecase) pubsub.GokaEventHandler {
+	return &DepositWalletEventHandler{logger, tracer, usecase}
 }
 
-// Handle will process the message.
+// Handle will process the message, the trace started by the publisher is continued when present.
 func (handler DepositWalletEventHandler) Handle(ctx goka.Context, message interface{}) {
+	tx, txCtx := pubsub.StartTransaction(handler.tracer, ctx, "DepositWalletEventHandler")
+	defer tx.End()
+	logger := handler.logger.WithFields(apmlogrus.TraceContext(txCtx))
 
 	payload, ok := message.(*model.DepositWallet)
 	if !ok {
-		handler.logger.Error("Not a kafka message")
+		logger.Error("Not a kafka message")
 		return
 	}
 
-	result := handler.usescase.AddBalance(ctx, payload)
-	if result != nil {
-		handler.logger.Info(result)
-	}
+	wallet := handler.usescase.AddBalance(ctx, payload)
+	logger.Infof(addBalanceSuccessMessage, wallet.WalletId, wallet.Balance)
 
 	return
 }
@@ -1,21 +1,26 @@
 package wallet_test
 
 import (
+	"encoding/hex"
 	"testing"
 
+	"github.com/ijalalfrz/coinbit-test/entity"
 	"github.com/ijalalfrz/coinbit-test/model"
 	pubsubMock "github.com/ijalalfrz/coinbit-test/pubsub/mocks"
-	"github.com/ijalalfrz/coinbit-test/response"
 	"github.com/ijalalfrz/coinbit-test/wallet"
 	"github.com/ijalalfrz/coinbit-test/wallet/mocks"
+	"github.com/lovoo/goka"
 	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.elastic.co/apm/apmtest"
 )
 
 func TestOnDepositEventHandler_Error_When_CastMessage(t *testing.T) {
 	usecase := mocks.Usecase{}
 	context := pubsubMock.GokaContext{}
-	handler := wallet.NewDepositWalletEventHandler(logrus.New(), &usecase)
+	context.On("Headers").Return(goka.Headers(nil))
+	handler := wallet.NewDepositWalletEventHandler(logrus.New(), apmtest.DiscardTracer, &usecase)
 
 	t.Run("Should error not a kafka message", func(t *testing.T) {
 		handler.Handle(&context, nil)
@@ -25,9 +30,9 @@ func TestOnDepositEventHandler_Error_When_CastMessage(t *testing.T) {
 func TestOnDepositEventHandler_Success(t *testing.T) {
 	usecase := mocks.Usecase{}
 	context := pubsubMock.GokaContext{}
-	handler := wallet.NewDepositWalletEventHandler(logrus.New(), &usecase)
-	resp := response.NewSuccessResponse(nil, response.StatOK, "success")
-	usecase.On("AddBalance", mock.Anything, mock.Anything).Return(resp)
+	context.On("Headers").Return(goka.Headers(nil))
+	handler := wallet.NewDepositWalletEventHandler(logrus.New(), apmtest.DiscardTracer, &usecase)
+	usecase.On("AddBalance", mock.Anything, mock.Anything).Return(&entity.Wallet{WalletId: "1", Balance: 1000})
 	t.Run("Should error not a kafka message", func(t *testing.T) {
 		payload := &model.DepositWallet{
 			WalletId: "1",
@@ -36,3 +41,29 @@ func TestOnDepositEventHandler_Success(t *testing.T) {
 		handler.Handle(&context, payload)
 	})
 }
+
+func TestOnDepositEventHandler_Continue_Trace_From_Headers(t *testing.T) {
+	usecase := mocks.Usecase{}
+	context := pubsubMock.GokaContext{}
+	context.On("Headers").Return(goka.Headers{
+		"Traceparent": []byte("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"),
+	})
+	tracer := apmtest.NewRecordingTracer()
+	handler := wallet.NewDepositWalletEventHandler(logrus.New(), tracer.Tracer, &usecase)
+	usecase.On("AddBalance", mock.Anything, mock.Anything).Return(&entity.Wallet{WalletId: "1", Balance: 1000})
+	t.Run("Should read trace context from message headers", func(t *testing.T) {
+		payload := &model.DepositWallet{
+			WalletId: "1",
+			Amount:   1000,
+		}
+		handler.Handle(&context, payload)
+		context.AssertCalled(t, "Headers")
+		usecase.AssertCalled(t, "AddBalance", &context, payload)
+
+		tracer.Flush(nil)
+		transactions := tracer.Payloads().Transactions
+		assert.Len(t, transactions, 1)
+		assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", hex.EncodeToString(transactions[0].TraceID[:]))
+		assert.Equal(t, "b7ad6b7169203331", hex.EncodeToString(transactions[0].ParentID[:]))
+	})
+}
@@ -5,31 +5,35 @@ import (
 	"github.com/ijalalfrz/coinbit-test/pubsub"
 	"github.com/lovoo/goka"
 	"github.com/sirupsen/logrus"
+	"go.elastic.co/apm"
+	"go.elastic.co/apm/module/apmlogrus"
 )
 
 // ProcessThresholdEventHandler is a concrete struct of wallet event handler.
 type ProcessThresholdEventHandler struct {
 	logger   *logrus.Logger
+	tracer   *apm.Tracer
 	usescase Usecase
 }
 
 // NewProcessThresholdEventHandler is a constructor.
-func NewProcessThresholdEventHandler(logger *logrus.Logger, usecase Usecase) pubsub.GokaEventHandler {
-	return &ProcessThresholdEventHandler{logger, usecase}
+func NewProcessThresholdEventHandler(logger *logrus.Logger, tracer *apm.Tracer, usecase Usecase) pubsub.GokaEventHandler {
+	return &ProcessThresholdEventHandler{logger, tracer, usecase}
 }
 
-// Handle will process the message.
+// Handle will process the message, the trace started by the publisher is continued when present.
 func (handler ProcessThresholdEventHandler) Handle(ctx goka.Context, message interface{}) {
+	tx, txCtx := pubsub.StartTransaction(handler.tracer, ctx, "ProcessThresholdEventHandler")
+	defer tx.End()
+	logger := handler.logger.WithFields(apmlogrus.TraceContext(txCtx))
 
 	payload, ok := message.(*model.DepositWallet)
 	if !ok {
-		handler.logger.Error("Not a kafka message")
+		logger.Error("Not a kafka message")
 		return
 	}
-	result := handler.usescase.ProcessThreshold(ctx, payload)
-	if result != nil {
-		handler.logger.Info(result)
-	}
+	threshold := handler.usescase.ProcessThreshold(ctx, payload)
+	logger.Infof(processThresholdSuccessMessage, threshold.WalletId, threshold.AboveThreshold)
 
 	return
 }
@@ -0,0 +1,37 @@
+package wallet
+
+import (
+	"github.com/ijalalfrz/coinbit-test/model"
+	"github.com/ijalalfrz/coinbit-test/pubsub"
+	"github.com/lovoo/goka"
+	"github.com/sirupsen/logrus"
+	"go.elastic.co/apm"
+	"go.elastic.co/apm/module/apmlogrus"
+)
+
+// StatementEventHandler is a concrete struct of wallet statement event handler.
+type StatementEventHandler struct {
+	logger  *logrus.Logger
+	tracer  *apm.Tracer
+	usecase Usecase
+}
+
+// NewStatementEventHandler is a constructor.
+func NewStatementEventHandler(logger *logrus.Logger, tracer *apm.Tracer, usecase Usecase) pubsub.GokaEventHandler {
+	return &StatementEventHandler{logger, tracer, usecase}
+}
+
+// Handle will add the deposit to the statements of the wallet, the trace started by the publisher is continued when present.
+func (handler StatementEventHandler) Handle(ctx goka.Context, message interface{}) {
+	tx, txCtx := pubsub.StartTransaction(handler.tracer, ctx, "StatementEventHandler")
+	defer tx.End()
+	logger := handler.logger.WithFields(apmlogrus.TraceContext(txCtx))
+
+	payload, ok := message.(*model.DepositWallet)
+	if !ok {
+		logger.Error("Not a kafka message")
+		return
+	}
+	statement := handler.usecase.AggregateStatement(ctx, payload)
+	logger.Infof(statementAddedMessage, statement.WalletId, statement.Balance)
+}
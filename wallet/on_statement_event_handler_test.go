@@ -0,0 +1,42 @@
+package wallet_test
+
+import (
+	"testing"
+
+	"github.com/ijalalfrz/coinbit-test/entity"
+	"github.com/ijalalfrz/coinbit-test/model"
+	pubsubMock "github.com/ijalalfrz/coinbit-test/pubsub/mocks"
+	"github.com/ijalalfrz/coinbit-test/wallet"
+	"github.com/ijalalfrz/coinbit-test/wallet/mocks"
+	"github.com/lovoo/goka"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/mock"
+	"go.elastic.co/apm/apmtest"
+)
+
+func TestOnStatementEventHandler_Error_When_CastMessage(t *testing.T) {
+	usecase := mocks.Usecase{}
+	context := pubsubMock.GokaContext{}
+	context.On("Headers").Return(goka.Headers(nil))
+	handler := wallet.NewStatementEventHandler(logrus.New(), apmtest.DiscardTracer, &usecase)
+
+	handler.Handle(&context, nil)
+
+	usecase.AssertNotCalled(t, "AggregateStatement", mock.Anything, mock.Anything)
+}
+
+func TestOnStatementEventHandler_Success(t *testing.T) {
+	usecase := mocks.Usecase{}
+	context := pubsubMock.GokaContext{}
+	context.On("Headers").Return(goka.Headers(nil))
+	handler := wallet.NewStatementEventHandler(logrus.New(), apmtest.DiscardTracer, &usecase)
+	usecase.On("AggregateStatement", mock.Anything, mock.Anything).Return(&entity.Statement{WalletId: "1"})
+	payload := &model.DepositWallet{
+		WalletId: "1",
+		Amount:   1000,
+	}
+
+	handler.Handle(&context, payload)
+
+	usecase.AssertCalled(t, "AggregateStatement", &context, payload)
+}
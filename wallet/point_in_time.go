@@ -0,0 +1,38 @@
+package wallet
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ijalalfrz/coinbit-test/pubsub"
+)
+
+// PointInTime is the bound of a point-in-time balance query, the deposits written at Time or before, or at
+// Position or before when it is not nil, are applied.
+type PointInTime struct {
+	Time     time.Time
+	Position *pubsub.Position
+}
+
+// ParsePointInTime will parse an RFC 3339 timestamp or a consistency token as partition:offset.
+func ParsePointInTime(value string) (at PointInTime, err error) {
+	if timestamp, timeErr := time.Parse(time.RFC3339Nano, value); timeErr == nil {
+		at.Time = timestamp
+		return
+	}
+	position, err := pubsub.ParsePosition(value)
+	if err != nil {
+		err = fmt.Errorf("point in time must be an RFC 3339 timestamp or a consistency token, got '%s'", value)
+		return
+	}
+	at.Position = &position
+	return
+}
+
+// includes returns true when the message is written at the point in time or before.
+func (at PointInTime) includes(message pubsub.ReplayedMessage) bool {
+	if at.Position != nil {
+		return message.Position.Offset <= at.Position.Offset
+	}
+	return !message.Timestamp.After(at.Time)
+}
@@ -0,0 +1,26 @@
+package wallet_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ijalalfrz/coinbit-test/pubsub"
+	"github.com/ijalalfrz/coinbit-test/wallet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePointInTime(t *testing.T) {
+	at, err := wallet.ParsePointInTime("2022-08-01T23:59:59+07:00")
+	assert.Nil(t, err)
+	assert.True(t, at.Time.Equal(time.Date(2022, 8, 1, 16, 59, 59, 0, time.UTC)))
+	assert.Nil(t, at.Position)
+
+	at, err = wallet.ParsePointInTime("1:42")
+	assert.Nil(t, err)
+	assert.Equal(t, &pubsub.Position{Partition: 1, Offset: 42}, at.Position)
+
+	for _, value := range []string{"", "yesterday", "2022-08-01", "1:-1"} {
+		_, err = wallet.ParsePointInTime(value)
+		assert.NotNil(t, err, value)
+	}
+}
@@ -1,6 +1,8 @@
 package wallet
 
 import (
+	"time"
+
 	"github.com/ijalalfrz/coinbit-test/pubsub"
 	"github.com/sirupsen/logrus"
 )
@@ -13,4 +15,15 @@ type UsecaseProperty struct {
 	Threshold             int64
 	BalanceViewTable      pubsub.ViewTable
 	ThresholdViewTable    pubsub.ViewTable
+	StatementViewTable    pubsub.ViewTable
+	// ConsistencyTimeout is how long GetDetail waits for the views to catch up with a consistency token.
+	ConsistencyTimeout time.Duration
+	// BatchLookupWorkers is the number of concurrent view lookups of GetDetails.
+	BatchLookupWorkers int
+	DepositReplayer    pubsub.Replayer
+	// ReplayTimeout is how long GetBalanceAt may replay the deposits of a wallet.
+	ReplayTimeout time.Duration
+	// StatementDays and StatementMonths are the number of daily and monthly statements kept per wallet.
+	StatementDays   int
+	StatementMonths int
 }
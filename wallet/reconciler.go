@@ -0,0 +1,309 @@
+package wallet
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ijalalfrz/coinbit-test/entity"
+	"github.com/ijalalfrz/coinbit-test/model"
+	"github.com/ijalalfrz/coinbit-test/pubsub"
+	"github.com/sirupsen/logrus"
+)
+
+// Collection of reason of a mismatched wallet.
+const (
+	MismatchBalanceDiffers  = "balance_differs"
+	MismatchMissingInView   = "missing_in_view"
+	MismatchMissingInStream = "missing_in_stream"
+)
+
+// Collection of reason of a wallet which can not be compared.
+const (
+	UnverifiedTruncated     = "truncated"
+	UnverifiedLagging       = "lagging"
+	UnverifiedNewerDeposits = "newer_deposits"
+)
+
+// reconcileTolerance is the largest difference of balance which is not a mismatch, the sums of floats are not exact.
+const reconcileTolerance = 1e-6
+
+// Reconciler is a collection of behavior of the reconciliation of the balance view with the deposits stream.
+type Reconciler interface {
+	// Reconcile compares the balance of every wallet with the sum of its deposits, when correct is true a
+	// correction event of the delta is sent for every mismatched wallet.
+	Reconcile(ctx context.Context, correct bool) (report ReconciliationReport, err error)
+}
+
+type ReconcilerProperty struct {
+	Logger          *logrus.Logger
+	DepositReplayer pubsub.Replayer
+	// CorrectionReplayer reads the corrections topic, the view is waited for to apply the sent corrections.
+	CorrectionReplayer       pubsub.Replayer
+	BalanceViewTable         pubsub.ViewTable
+	CorrectionTopicPublisher pubsub.Publisher
+	// CatchUpTimeout is how long the view is waited for to apply the replayed deposits.
+	CatchUpTimeout time.Duration
+}
+
+// ReconciliationReport is the result of a reconciliation, Deposits is the number of replayed deposits and
+// Wallets the number of wallets found in the stream or the view.
+type ReconciliationReport struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Deposits   int       `json:"deposits"`
+	Wallets    int       `json:"wallets"`
+	Matched    int       `json:"matched"`
+	// TruncatedPartitions are the partitions whose oldest deposits are not retained, their wallets are unverified.
+	TruncatedPartitions []int32            `json:"truncated_partitions,omitempty"`
+	Mismatches          []WalletMismatch   `json:"mismatches"`
+	Unverified          []UnverifiedWallet `json:"unverified"`
+	Corrections         int                `json:"corrections"`
+}
+
+// WalletMismatch is a wallet whose balance is not the sum of its deposits, Delta is Expected minus Actual.
+type WalletMismatch struct {
+	WalletId  string  `json:"wallet_id"`
+	Reason    string  `json:"reason"`
+	Expected  float64 `json:"expected"`
+	Actual    float64 `json:"actual"`
+	Delta     float64 `json:"delta"`
+	Corrected bool    `json:"corrected"`
+}
+
+// UnverifiedWallet is a wallet which can not be compared.
+type UnverifiedWallet struct {
+	WalletId string `json:"wallet_id"`
+	Reason   string `json:"reason"`
+}
+
+// awaitedMessage is the last message of a partition, the view has applied the partition once the wallet of the
+// message has applied it.
+type awaitedMessage struct {
+	walletId string
+	offset   int64
+}
+
+// replayedBalance is the sum of the replayed deposits of a wallet and the position of the last one.
+type replayedBalance struct {
+	balance  float64
+	position pubsub.Position
+}
+
+type walletReconciler struct {
+	logger                   *logrus.Logger
+	depositReplayer          pubsub.Replayer
+	correctionReplayer       pubsub.Replayer
+	balanceViewTable         pubsub.ViewTable
+	correctionTopicPublisher pubsub.Publisher
+	catchUpTimeout           time.Duration
+}
+
+func NewReconciler(property ReconcilerProperty) Reconciler {
+	return &walletReconciler{
+		logger:                   property.Logger,
+		depositReplayer:          property.DepositReplayer,
+		correctionReplayer:       property.CorrectionReplayer,
+		balanceViewTable:         property.BalanceViewTable,
+		correctionTopicPublisher: property.CorrectionTopicPublisher,
+		catchUpTimeout:           property.CatchUpTimeout,
+	}
+}
+
+// Reconcile will replay the deposits stream from the beginning into memory, wait until the view has applied the
+// replayed deposits and the sent corrections and compare every wallet of both. A correction sent by a former
+// reconciliation which is not applied yet makes its partition lagging, so it is never sent twice
+func (r walletReconciler) Reconcile(ctx context.Context, correct bool) (report ReconciliationReport, err error) {
+	report.StartedAt = time.Now()
+	if err = r.balanceViewTable.WaitRunning(ctx); err != nil {
+		return
+	}
+
+	replayed := map[string]*replayedBalance{}
+	report.TruncatedPartitions, err = r.depositReplayer.ReplayAll(ctx, func(message pubsub.ReplayedMessage) bool {
+		deposit, ok := message.Value.(*model.DepositWallet)
+		if !ok {
+			return true
+		}
+		wallet, ok := replayed[message.Key]
+		if !ok {
+			wallet = new(replayedBalance)
+			replayed[message.Key] = wallet
+		}
+		wallet.balance += deposit.GetAmount()
+		wallet.position = message.Position
+		report.Deposits++
+		return true
+	})
+	if err != nil {
+		return
+	}
+
+	corrections := map[int32]awaitedMessage{}
+	_, err = r.correctionReplayer.ReplayAll(ctx, func(message pubsub.ReplayedMessage) bool {
+		corrections[message.Position.Partition] = awaitedMessage{walletId: message.Key, offset: message.Position.Offset}
+		return true
+	})
+	if err != nil {
+		return
+	}
+
+	lagging, err := r.catchUp(ctx, replayed, corrections)
+	if err != nil {
+		return
+	}
+	balances := map[string]entity.Wallet{}
+	err = r.balanceViewTable.Iterate(func(key string, value interface{}) bool {
+		if wallet, ok := value.(*entity.Wallet); ok {
+			balances[key] = *wallet
+		}
+		return true
+	})
+	if err != nil {
+		return
+	}
+
+	r.compare(&report, replayed, balances, lagging)
+	if correct {
+		err = r.correct(ctx, &report)
+	}
+	report.FinishedAt = time.Now()
+	r.logger.WithFields(logrus.Fields{
+		"deposits":    report.Deposits,
+		"wallets":     report.Wallets,
+		"matched":     report.Matched,
+		"mismatches":  len(report.Mismatches),
+		"unverified":  len(report.Unverified),
+		"corrections": report.Corrections,
+	}).Info("Reconciliation of the balance view has finished")
+	return
+}
+
+// catchUp will read the wallets of the last replayed deposit and the last correction of every partition until the
+// view has applied them, then every deposit and correction of the partition is applied. The partitions which are
+// not applied in time are lagging.
+func (r walletReconciler) catchUp(ctx context.Context, replayed map[string]*replayedBalance, corrections map[int32]awaitedMessage) (lagging map[int32]bool, err error) {
+	deposits := map[int32]awaitedMessage{}
+	for walletId, wallet := range replayed {
+		partition := wallet.position.Partition
+		if current, ok := deposits[partition]; !ok || current.offset < wallet.position.Offset {
+			deposits[partition] = awaitedMessage{walletId: walletId, offset: wallet.position.Offset}
+		}
+	}
+
+	timer := time.NewTimer(r.catchUpTimeout)
+	defer timer.Stop()
+	for {
+		lagging = map[int32]bool{}
+		for partition, deposit := range deposits {
+			wallet, getErr := r.viewWallet(deposit.walletId)
+			if getErr != nil {
+				return nil, getErr
+			}
+			if wallet == nil || wallet.LastDepositPartition != partition || wallet.LastDepositOffset < deposit.offset {
+				lagging[partition] = true
+			}
+		}
+		for partition, correction := range corrections {
+			wallet, getErr := r.viewWallet(correction.walletId)
+			if getErr != nil {
+				return nil, getErr
+			}
+			if wallet == nil || wallet.CorrectionOffset <= correction.offset {
+				lagging[partition] = true
+			}
+		}
+		if len(lagging) == 0 {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+			return
+		case <-time.After(consistencyPollInterval):
+		}
+	}
+}
+
+// viewWallet returns the wallet of the view, nil when it is not found.
+func (r walletReconciler) viewWallet(walletId string) (wallet *entity.Wallet, err error) {
+	data, err := r.balanceViewTable.Get(walletId)
+	if err != nil {
+		return
+	}
+	wallet, _ = data.(*entity.Wallet)
+	return
+}
+
+// compare will add every wallet of the stream or the view to the report.
+func (r walletReconciler) compare(report *ReconciliationReport, replayed map[string]*replayedBalance, balances map[string]entity.Wallet, lagging map[int32]bool) {
+	truncated := map[int32]bool{}
+	for _, partition := range report.TruncatedPartitions {
+		truncated[partition] = true
+	}
+
+	walletIds := make([]string, 0, len(replayed)+len(balances))
+	for walletId := range replayed {
+		walletIds = append(walletIds, walletId)
+	}
+	for walletId := range balances {
+		if _, ok := replayed[walletId]; !ok {
+			walletIds = append(walletIds, walletId)
+		}
+	}
+	sort.Strings(walletIds)
+
+	report.Wallets = len(walletIds)
+	report.Mismatches = []WalletMismatch{}
+	report.Unverified = []UnverifiedWallet{}
+	for _, walletId := range walletIds {
+		expected, inStream := replayed[walletId]
+		actual, inView := balances[walletId]
+		partition := actual.LastDepositPartition
+		if inStream {
+			partition = expected.position.Partition
+		}
+
+		mismatch := WalletMismatch{WalletId: walletId, Reason: MismatchBalanceDiffers, Actual: actual.Balance}
+		if inStream {
+			mismatch.Expected = expected.balance
+		}
+		mismatch.Delta = mismatch.Expected - mismatch.Actual
+		switch {
+		case !inView:
+			mismatch.Reason = MismatchMissingInView
+		case !inStream:
+			mismatch.Reason = MismatchMissingInStream
+		}
+
+		switch {
+		case truncated[partition]:
+			report.Unverified = append(report.Unverified, UnverifiedWallet{WalletId: walletId, Reason: UnverifiedTruncated})
+		case lagging[partition]:
+			report.Unverified = append(report.Unverified, UnverifiedWallet{WalletId: walletId, Reason: UnverifiedLagging})
+		case inStream && inView && actual.LastDepositOffset > expected.position.Offset:
+			report.Unverified = append(report.Unverified, UnverifiedWallet{WalletId: walletId, Reason: UnverifiedNewerDeposits})
+		case math.Abs(mismatch.Delta) <= reconcileTolerance:
+			report.Matched++
+		default:
+			report.Mismatches = append(report.Mismatches, mismatch)
+		}
+	}
+}
+
+// correct will send a correction event of the delta of every mismatched wallet, it stops at the first error.
+func (r walletReconciler) correct(ctx context.Context, report *ReconciliationReport) (err error) {
+	for i := range report.Mismatches {
+		mismatch := &report.Mismatches[i]
+		correction := &model.DepositWallet{WalletId: mismatch.WalletId, Amount: mismatch.Delta}
+		if _, err = r.correctionTopicPublisher.Send(ctx, mismatch.WalletId, correction); err != nil {
+			return
+		}
+		mismatch.Corrected = true
+		report.Corrections++
+	}
+	return
+}
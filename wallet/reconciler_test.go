@@ -0,0 +1,185 @@
+package wallet_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ijalalfrz/coinbit-test/entity"
+	"github.com/ijalalfrz/coinbit-test/exception"
+	"github.com/ijalalfrz/coinbit-test/model"
+	"github.com/ijalalfrz/coinbit-test/pubsub"
+	pubsubMock "github.com/ijalalfrz/coinbit-test/pubsub/mocks"
+	"github.com/ijalalfrz/coinbit-test/wallet"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestReconcile(t *testing.T) {
+	deposit := func(walletId string, partition int32, offset int64, amount float64) pubsub.ReplayedMessage {
+		return pubsub.ReplayedMessage{
+			Key:      walletId,
+			Position: pubsub.Position{Partition: partition, Offset: offset},
+			Value:    &model.DepositWallet{WalletId: walletId, Amount: amount},
+		}
+	}
+	replayAll := func(replayerMock *pubsubMock.Replayer, truncated []int32, messages ...pubsub.ReplayedMessage) {
+		replayerMock.On("ReplayAll", mock.Anything, mock.Anything).Return(truncated, nil).Run(func(args mock.Arguments) {
+			fn := args.Get(1).(func(pubsub.ReplayedMessage) bool)
+			for _, message := range messages {
+				if !fn(message) {
+					return
+				}
+			}
+		})
+	}
+	correctionsOf := func(messages ...pubsub.ReplayedMessage) *pubsubMock.Replayer {
+		replayerMock := &pubsubMock.Replayer{}
+		replayAll(replayerMock, nil, messages...)
+		return replayerMock
+	}
+	viewOf := func(balances ...*entity.Wallet) *pubsubMock.ViewTable {
+		balanceTableMock := &pubsubMock.ViewTable{}
+		balanceTableMock.On("WaitRunning", mock.Anything).Return(nil)
+		byId := map[string]interface{}{}
+		for _, balance := range balances {
+			byId[balance.WalletId] = balance
+		}
+		balanceTableMock.On("Get", mock.Anything).Return(func(walletId string) interface{} {
+			return byId[walletId]
+		}, nil)
+		balanceTableMock.On("Iterate", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			fn := args.Get(0).(func(string, interface{}) bool)
+			for walletId, balance := range byId {
+				if !fn(walletId, balance) {
+					return
+				}
+			}
+		})
+		return balanceTableMock
+	}
+
+	t.Run("when balances are compared and corrected", func(t *testing.T) {
+		replayerMock := pubsubMock.Replayer{}
+		replayAll(&replayerMock, []int32{2},
+			deposit("matched", 0, 0, 100),
+			deposit("matched", 0, 1, 0.1),
+			deposit("differs", 0, 2, 300),
+			deposit("missing", 0, 3, 50),
+			deposit("newer", 1, 0, 10),
+			deposit("truncated", 2, 7, 10),
+			deposit("last", 0, 4, 1),
+		)
+		balanceTableMock := viewOf(
+			&entity.Wallet{WalletId: "matched", Balance: 100.1, LastDepositPartition: 0, LastDepositOffset: 1},
+			&entity.Wallet{WalletId: "differs", Balance: 200, LastDepositPartition: 0, LastDepositOffset: 2},
+			&entity.Wallet{WalletId: "newer", Balance: 30, LastDepositPartition: 1, LastDepositOffset: 5},
+			&entity.Wallet{WalletId: "truncated", Balance: 1000, LastDepositPartition: 2, LastDepositOffset: 7},
+			&entity.Wallet{WalletId: "orphan", Balance: 70, LastDepositPartition: 0, LastDepositOffset: 0},
+			&entity.Wallet{WalletId: "last", Balance: 1, LastDepositPartition: 0, LastDepositOffset: 4},
+		)
+		publisherMock := pubsubMock.Publisher{}
+		publisherMock.On("Send", mock.Anything, mock.Anything, mock.Anything).Return(pubsub.Position{}, nil)
+		reconciler := wallet.NewReconciler(wallet.ReconcilerProperty{
+			Logger:                   logrus.New(),
+			DepositReplayer:          &replayerMock,
+			CorrectionReplayer:       correctionsOf(),
+			BalanceViewTable:         balanceTableMock,
+			CorrectionTopicPublisher: &publisherMock,
+			CatchUpTimeout:           time.Second,
+		})
+
+		report, err := reconciler.Reconcile(context.TODO(), true)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 7, report.Deposits)
+		assert.Equal(t, 7, report.Wallets)
+		assert.Equal(t, 2, report.Matched)
+		assert.Equal(t, []wallet.WalletMismatch{
+			{WalletId: "differs", Reason: wallet.MismatchBalanceDiffers, Expected: 300, Actual: 200, Delta: 100, Corrected: true},
+			{WalletId: "missing", Reason: wallet.MismatchMissingInView, Expected: 50, Delta: 50, Corrected: true},
+			{WalletId: "orphan", Reason: wallet.MismatchMissingInStream, Actual: 70, Delta: -70, Corrected: true},
+		}, report.Mismatches)
+		assert.Equal(t, []wallet.UnverifiedWallet{
+			{WalletId: "newer", Reason: wallet.UnverifiedNewerDeposits},
+			{WalletId: "truncated", Reason: wallet.UnverifiedTruncated},
+		}, report.Unverified)
+		assert.Equal(t, 3, report.Corrections)
+		publisherMock.AssertCalled(t, "Send", mock.Anything, "differs", &model.DepositWallet{WalletId: "differs", Amount: 100})
+		publisherMock.AssertCalled(t, "Send", mock.Anything, "orphan", &model.DepositWallet{WalletId: "orphan", Amount: -70})
+	})
+
+	t.Run("when view does not catch up with a partition", func(t *testing.T) {
+		replayerMock := pubsubMock.Replayer{}
+		replayAll(&replayerMock, nil, deposit("1", 0, 0, 100), deposit("2", 1, 0, 100), deposit("3", 1, 1, 100))
+		balanceTableMock := viewOf(
+			&entity.Wallet{WalletId: "1", Balance: 100, LastDepositPartition: 0, LastDepositOffset: 0},
+			&entity.Wallet{WalletId: "2", Balance: 100, LastDepositPartition: 1, LastDepositOffset: 0},
+		)
+		reconciler := wallet.NewReconciler(wallet.ReconcilerProperty{
+			Logger:             logrus.New(),
+			DepositReplayer:    &replayerMock,
+			CorrectionReplayer: correctionsOf(),
+			BalanceViewTable:   balanceTableMock,
+			CatchUpTimeout:     100 * time.Millisecond,
+		})
+
+		report, err := reconciler.Reconcile(context.TODO(), false)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, report.Matched)
+		assert.Empty(t, report.Mismatches)
+		assert.Equal(t, []wallet.UnverifiedWallet{
+			{WalletId: "2", Reason: wallet.UnverifiedLagging},
+			{WalletId: "3", Reason: wallet.UnverifiedLagging},
+		}, report.Unverified)
+	})
+
+	t.Run("when a former correction is not applied yet", func(t *testing.T) {
+		replayerMock := pubsubMock.Replayer{}
+		replayAll(&replayerMock, nil, deposit("1", 0, 0, 100), deposit("2", 1, 0, 100))
+		balanceTableMock := viewOf(
+			&entity.Wallet{WalletId: "1", Balance: 50, LastDepositPartition: 0, LastDepositOffset: 0, CorrectionOffset: 3},
+			&entity.Wallet{WalletId: "2", Balance: 50, LastDepositPartition: 1, LastDepositOffset: 0},
+		)
+		publisherMock := pubsubMock.Publisher{}
+		publisherMock.On("Send", mock.Anything, mock.Anything, mock.Anything).Return(pubsub.Position{}, nil)
+		reconciler := wallet.NewReconciler(wallet.ReconcilerProperty{
+			Logger:          logrus.New(),
+			DepositReplayer: &replayerMock,
+			CorrectionReplayer: correctionsOf(
+				pubsub.ReplayedMessage{Key: "1", Position: pubsub.Position{Partition: 0, Offset: 2}},
+				pubsub.ReplayedMessage{Key: "2", Position: pubsub.Position{Partition: 1, Offset: 0}},
+			),
+			BalanceViewTable:         balanceTableMock,
+			CorrectionTopicPublisher: &publisherMock,
+			CatchUpTimeout:           100 * time.Millisecond,
+		})
+
+		report, err := reconciler.Reconcile(context.TODO(), true)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []wallet.WalletMismatch{
+			{WalletId: "1", Reason: wallet.MismatchBalanceDiffers, Expected: 100, Actual: 50, Delta: 50, Corrected: true},
+		}, report.Mismatches, "should compare the partition whose corrections are applied")
+		assert.Equal(t, []wallet.UnverifiedWallet{{WalletId: "2", Reason: wallet.UnverifiedLagging}}, report.Unverified)
+		assert.Equal(t, 1, report.Corrections)
+		publisherMock.AssertNotCalled(t, "Send", mock.Anything, "2", mock.Anything)
+	})
+
+	t.Run("when stream is not readable", func(t *testing.T) {
+		replayerMock := pubsubMock.Replayer{}
+		replayerMock.On("ReplayAll", mock.Anything, mock.Anything).Return(nil, exception.ErrServiceUnavailable)
+		reconciler := wallet.NewReconciler(wallet.ReconcilerProperty{
+			Logger:             logrus.New(),
+			DepositReplayer:    &replayerMock,
+			CorrectionReplayer: correctionsOf(),
+			BalanceViewTable:   viewOf(),
+		})
+
+		_, err := reconciler.Reconcile(context.TODO(), true)
+
+		assert.ErrorIs(t, err, exception.ErrServiceUnavailable)
+	})
+}
@@ -0,0 +1,7 @@
+package wallet
+
+// Rule is a collection of wallet rules, it can be reloaded at runtime.
+type Rule struct {
+	Threshold     int64
+	RollingPeriod int
+}
@@ -0,0 +1,33 @@
+package wallet
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ijalalfrz/coinbit-test/entity"
+	"github.com/ijalalfrz/coinbit-test/pubsub"
+)
+
+type statement struct {
+}
+
+func NewStatementCodec() pubsub.GokaCodec {
+	return &statement{}
+}
+
+func (c *statement) Encode(value interface{}) ([]byte, error) {
+	v, isStatement := value.(*entity.Statement)
+	if !isStatement {
+		return nil, fmt.Errorf("Codec requires value *entity.Statement, got %T", value)
+	}
+	return json.Marshal(v)
+}
+
+// Decodes a statement from []byte to it's go representation.
+func (c *statement) Decode(data []byte) (interface{}, error) {
+	var statement entity.Statement
+	if err := json.Unmarshal(data, &statement); err != nil {
+		return nil, fmt.Errorf("Error unmarshaling statement: %v", err)
+	}
+	return &statement, nil
+}
@@ -0,0 +1,47 @@
+package wallet_test
+
+import (
+	"testing"
+
+	"github.com/ijalalfrz/coinbit-test/entity"
+	"github.com/ijalalfrz/coinbit-test/model"
+	"github.com/ijalalfrz/coinbit-test/wallet"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestStatementCodec_Error_Encode(t *testing.T) {
+	codec := wallet.NewStatementCodec()
+
+	result, err := codec.Encode(&entity.Wallet{})
+
+	assert.Nil(t, result, "should be null")
+	assert.Error(t, err, "should be error")
+}
+
+func TestStatementCodec_Success_Encode_Decode(t *testing.T) {
+	codec := wallet.NewStatementCodec()
+	data := &entity.Statement{
+		WalletId: "1",
+		Balance:  100,
+		Daily:    []entity.StatementBucket{{Period: "2022-08-01", Credits: 100, ClosingBalance: 100, Deposits: 1}},
+		Monthly:  []entity.StatementBucket{{Period: "2022-08", Credits: 100, ClosingBalance: 100, Deposits: 1}},
+	}
+
+	buff, err := codec.Encode(data)
+	assert.Nil(t, err, "should be null")
+	result, err := codec.Decode(buff)
+
+	assert.Nil(t, err, "should be null")
+	assert.Equal(t, data, result)
+}
+
+func TestStatementCodec_Error_Decode(t *testing.T) {
+	codec := wallet.NewStatementCodec()
+	buff, _ := proto.Marshal(&model.DepositWallet{WalletId: "1"})
+
+	result, err := codec.Decode(buff)
+
+	assert.Error(t, err, "should be error")
+	assert.Nil(t, result, "should be null")
+}
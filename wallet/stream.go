@@ -0,0 +1,197 @@
+package wallet
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/ijalalfrz/coinbit-test/entity"
+	"github.com/ijalalfrz/coinbit-test/pubsub"
+	"github.com/ijalalfrz/coinbit-test/webmodel"
+)
+
+// ErrTooManyStreams is returned by Subscribe when the cap of concurrent streams is reached.
+var ErrTooManyStreams = errors.New("too many concurrent streams")
+
+// StreamHub notifies the stream subscribers of a wallet when its balance or threshold view is updated.
+type StreamHub struct {
+	balanceViewTable   pubsub.ViewTable
+	thresholdViewTable pubsub.ViewTable
+	maxStreams         int
+
+	mu          sync.Mutex
+	count       int
+	subscribers map[string]map[*Subscription]struct{}
+}
+
+// Subscription is a stream of one or more wallets, C receives a signal when a watched wallet may have changed.
+type Subscription struct {
+	notify chan struct{}
+	// wallets is guarded by the hub.
+	wallets map[string]struct{}
+
+	mu      sync.Mutex
+	changed map[string]struct{}
+}
+
+// C returns the channel signalling wallet changes.
+func (s *Subscription) C() <-chan struct{} {
+	return s.notify
+}
+
+// Changed returns the wallets changed since the last call, in no particular order.
+func (s *Subscription) Changed() (walletIds []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for walletId := range s.changed {
+		walletIds = append(walletIds, walletId)
+	}
+	s.changed = map[string]struct{}{}
+	return
+}
+
+// NewStreamHub is a constructor, it listens to the updates of both view tables.
+func NewStreamHub(balanceViewTable, thresholdViewTable pubsub.ViewTable, maxStreams int) *StreamHub {
+	hub := &StreamHub{
+		balanceViewTable:   balanceViewTable,
+		thresholdViewTable: thresholdViewTable,
+		maxStreams:         maxStreams,
+		subscribers:        map[string]map[*Subscription]struct{}{},
+	}
+	balanceViewTable.OnUpdate(hub.notify)
+	thresholdViewTable.OnUpdate(hub.notify)
+	return hub
+}
+
+// Open will open a stream without any wallet, it fails with ErrTooManyStreams when the cap is reached.
+func (h *StreamHub) Open() (sub *Subscription, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.count >= h.maxStreams {
+		err = ErrTooManyStreams
+		return
+	}
+	sub = &Subscription{
+		notify:  make(chan struct{}, 1),
+		wallets: map[string]struct{}{},
+		changed: map[string]struct{}{},
+	}
+	h.count++
+	return
+}
+
+// Subscribe will open a stream of the wallet, it fails with ErrTooManyStreams when the cap is reached.
+func (h *StreamHub) Subscribe(walletId string) (sub *Subscription, err error) {
+	sub, err = h.Open()
+	if err != nil {
+		return
+	}
+	h.Watch(sub, walletId)
+	return
+}
+
+// Watch will add the wallet to the stream.
+func (h *StreamHub) Watch(sub *Subscription, walletId string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.subscribers[walletId] == nil {
+		h.subscribers[walletId] = map[*Subscription]struct{}{}
+	}
+	h.subscribers[walletId][sub] = struct{}{}
+	sub.wallets[walletId] = struct{}{}
+}
+
+// Unwatch will remove the wallet from the stream.
+func (h *StreamHub) Unwatch(sub *Subscription, walletId string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.unwatch(sub, walletId)
+}
+
+// Unsubscribe will close the stream.
+func (h *StreamHub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if sub.wallets == nil {
+		return
+	}
+	for walletId := range sub.wallets {
+		h.unwatch(sub, walletId)
+	}
+	sub.wallets = nil
+	h.count--
+}
+
+// unwatch must be called with the lock held.
+func (h *StreamHub) unwatch(sub *Subscription, walletId string) {
+	subs := h.subscribers[walletId]
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.subscribers, walletId)
+	}
+	delete(sub.wallets, walletId)
+}
+
+// notify will signal the subscribers of the wallet without blocking the view.
+func (h *StreamHub) notify(walletId string, value interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subscribers[walletId] {
+		sub.mu.Lock()
+		sub.changed[walletId] = struct{}{}
+		sub.mu.Unlock()
+		select {
+		case sub.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// lastSent is the last snapshot sent to a watcher, detail is nil when only the position is known.
+type lastSent struct {
+	position pubsub.Position
+	detail   *webmodel.DetailWalletResponse
+}
+
+// newer returns true when the snapshot must be sent after last: it is at a later deposit, or at the same deposit
+// with other details because a correction changes the balance without moving the position.
+func (last *lastSent) newer(detail webmodel.DetailWalletResponse, position pubsub.Position) bool {
+	if last == nil || last.position.Partition != position.Partition || last.position.Offset < position.Offset {
+		return true
+	}
+	return last.position.Offset == position.Offset && last.detail != nil && *last.detail != detail
+}
+
+// Snapshot returns the wallet details once both views have applied the same deposit, ok is false otherwise.
+func (h *StreamHub) Snapshot(walletId string) (detail webmodel.DetailWalletResponse, position pubsub.Position, ok bool, err error) {
+	balanceData, err := h.balanceViewTable.Get(walletId)
+	if err != nil {
+		return
+	}
+	thresholdData, err := h.thresholdViewTable.Get(walletId)
+	if err != nil {
+		return
+	}
+
+	balance, isWallet := balanceData.(*entity.Wallet)
+	threshold, isThreshold := thresholdData.(*entity.Threshold)
+	if !isWallet || !isThreshold ||
+		balance.LastDepositPartition != threshold.LastDepositPartition ||
+		balance.LastDepositOffset != threshold.LastDepositOffset {
+		return
+	}
+
+	detail = webmodel.DetailWalletResponse{
+		WalletId:       balance.WalletId,
+		Balance:        balance.Balance,
+		AboveThreshold: threshold.AboveThreshold,
+	}
+	position = pubsub.Position{Partition: balance.LastDepositPartition, Offset: balance.LastDepositOffset}
+	ok = true
+	return
+}
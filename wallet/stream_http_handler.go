@@ -0,0 +1,132 @@
+package wallet
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ijalalfrz/coinbit-test/auth"
+	"github.com/ijalalfrz/coinbit-test/exception"
+	"github.com/ijalalfrz/coinbit-test/pubsub"
+	"github.com/ijalalfrz/coinbit-test/response"
+	"github.com/sirupsen/logrus"
+)
+
+// StreamPath is the path template of the wallet stream endpoint.
+const StreamPath = basePath + "/v1/wallets/{walletId}/stream"
+
+// streamRetry is the reconnection delay sent to the clients in milliseconds.
+const streamRetry = 1000
+
+// collection of stream message
+const (
+	streamUnsupportedErrMessage = "Streaming is not supported by the connection"
+	streamTooManyErrMessage     = "Too many concurrent streams, retry later"
+)
+
+// StreamHTTPHandler is a concrete struct of wallet stream http handler.
+type StreamHTTPHandler struct {
+	Logger *logrus.Logger
+	Hub    *StreamHub
+	// KeepaliveInterval is how often a comment is sent to keep idle connections open.
+	KeepaliveInterval time.Duration
+	// MaxDuration is how long a stream is kept open before the client is asked to reconnect.
+	MaxDuration time.Duration
+}
+
+// NewWalletStreamHTTPHandler will register the server-sent events stream of wallet changes.
+func NewWalletStreamHTTPHandler(logger *logrus.Logger, router *mux.Router, hub *StreamHub, keepaliveInterval, maxDuration time.Duration) {
+	handler := &StreamHTTPHandler{
+		Logger:            logger,
+		Hub:               hub,
+		KeepaliveInterval: keepaliveInterval,
+		MaxDuration:       maxDuration,
+	}
+	router.HandleFunc(StreamPath, handler.StreamWallet).Methods(http.MethodGet)
+}
+
+// StreamWallet is a function to stream the wallet details as server-sent events every time a deposit is applied.
+// The current details are sent first unless the Last-Event-ID header is already up to date.
+func (handler StreamHTTPHandler) StreamWallet(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	walletId := mux.Vars(r)["walletId"]
+
+	if principal, ok := auth.PrincipalFromContext(ctx); ok && !principal.CanAccessWallet(walletId) {
+		response.Negotiate(w, r, response.FromError(exception.New(exception.KindForbidden, detailForbiddenErrMessage)))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		response.Negotiate(w, r, response.FromError(exception.New(exception.KindInternal, streamUnsupportedErrMessage)))
+		return
+	}
+
+	sub, err := handler.Hub.Subscribe(walletId)
+	if err != nil {
+		w.Header().Set("Retry-After", "1")
+		response.Negotiate(w, r, response.FromError(exception.Wrap(exception.KindServiceUnavailable, err, streamTooManyErrMessage)))
+		return
+	}
+	defer handler.Hub.Unsubscribe(sub)
+
+	var last *lastSent
+	if position, parseErr := pubsub.ParsePosition(r.Header.Get("Last-Event-ID")); parseErr == nil {
+		last = &lastSent{position: position}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if _, err = fmt.Fprintf(w, "retry: %d\n\n", streamRetry); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	keepalive := time.NewTicker(handler.KeepaliveInterval)
+	defer keepalive.Stop()
+	end := time.NewTimer(handler.MaxDuration)
+	defer end.Stop()
+
+	for {
+		if err = handler.send(w, walletId, &last); err != nil {
+			handler.Logger.WithContext(ctx).Warnf("Wallet stream of %s is closed: %v", walletId, err)
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-end.C:
+			return
+		case <-keepalive.C:
+			if _, err = fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case <-sub.C():
+		}
+	}
+}
+
+// send will write the wallet details as an event when they are newer than last.
+func (handler StreamHTTPHandler) send(w http.ResponseWriter, walletId string, last **lastSent) (err error) {
+	detail, position, ok, err := handler.Hub.Snapshot(walletId)
+	if err != nil || !ok || !(*last).newer(detail, position) {
+		return
+	}
+
+	data, err := json.Marshal(detail)
+	if err != nil {
+		return
+	}
+	if _, err = fmt.Fprintf(w, "id: %s\nevent: wallet\ndata: %s\n\n", position, data); err != nil {
+		return
+	}
+	*last = &lastSent{position: position, detail: &detail}
+	return
+}
@@ -0,0 +1,109 @@
+package wallet_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ijalalfrz/coinbit-test/auth"
+	"github.com/ijalalfrz/coinbit-test/entity"
+	"github.com/ijalalfrz/coinbit-test/pubsub"
+	"github.com/ijalalfrz/coinbit-test/wallet"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func newStreamRequest(walletId string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/just/for/testing", nil)
+	return mux.SetURLVars(r, map[string]string{"walletId": walletId})
+}
+
+func TestStreamWallet(t *testing.T) {
+	var listeners []pubsub.UpdateListener
+	balanceTableMock, thresholdTableMock := newStreamViews(&listeners)
+	hub := wallet.NewStreamHub(balanceTableMock, thresholdTableMock, 1)
+	var offset int64 = 1
+	var correction int64
+	balanceTableMock.On("Get", "1").Return(func(string) interface{} {
+		o := atomic.LoadInt64(&offset)
+		return &entity.Wallet{WalletId: "1", Balance: float64(o*100 + atomic.LoadInt64(&correction)), LastDepositOffset: o}
+	}, nil)
+	thresholdTableMock.On("Get", "1").Return(func(string) interface{} {
+		return &entity.Threshold{WalletId: "1", LastDepositOffset: atomic.LoadInt64(&offset)}
+	}, nil)
+	hh := wallet.StreamHTTPHandler{
+		Logger:            logrus.New(),
+		Hub:               hub,
+		KeepaliveInterval: 50 * time.Millisecond,
+		MaxDuration:       300 * time.Millisecond,
+	}
+
+	t.Run("when wallet is updated while streaming", func(t *testing.T) {
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			atomic.StoreInt64(&offset, 2)
+			listeners[0]("1", nil)
+		}()
+		recorder := httptest.NewRecorder()
+
+		http.HandlerFunc(hh.StreamWallet).ServeHTTP(recorder, newStreamRequest("1"))
+
+		body := recorder.Body.String()
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "text/event-stream", recorder.Header().Get("Content-Type"))
+		assert.Contains(t, body, "retry: 1000\n\n")
+		assert.Contains(t, body, "id: 0:1\nevent: wallet\ndata: {\"wallet_id\":\"1\",\"balance\":100,\"above_threshold\":false}\n\n")
+		assert.Contains(t, body, "id: 0:2\nevent: wallet\ndata: {\"wallet_id\":\"1\",\"balance\":200,\"above_threshold\":false}\n\n")
+		assert.Contains(t, body, ": keepalive\n\n")
+	})
+
+	t.Run("when last event id is up to date", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		r := newStreamRequest("1")
+		r.Header.Set("Last-Event-ID", "0:2")
+
+		http.HandlerFunc(hh.StreamWallet).ServeHTTP(recorder, r)
+
+		assert.NotContains(t, recorder.Body.String(), "event: wallet")
+	})
+
+	t.Run("when a correction is applied while streaming", func(t *testing.T) {
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			atomic.StoreInt64(&correction, 50)
+			listeners[0]("1", nil)
+		}()
+		recorder := httptest.NewRecorder()
+
+		http.HandlerFunc(hh.StreamWallet).ServeHTTP(recorder, newStreamRequest("1"))
+
+		body := recorder.Body.String()
+		assert.Contains(t, body, "id: 0:2\nevent: wallet\ndata: {\"wallet_id\":\"1\",\"balance\":200,\"above_threshold\":false}\n\n")
+		assert.Contains(t, body, "id: 0:2\nevent: wallet\ndata: {\"wallet_id\":\"1\",\"balance\":250,\"above_threshold\":false}\n\n")
+	})
+
+	t.Run("when cap of streams is reached", func(t *testing.T) {
+		sub, err := hub.Subscribe("2")
+		assert.Nil(t, err)
+		defer hub.Unsubscribe(sub)
+		recorder := httptest.NewRecorder()
+
+		http.HandlerFunc(hh.StreamWallet).ServeHTTP(recorder, newStreamRequest("1"))
+
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	})
+
+	t.Run("when user does not own the wallet", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		r := newStreamRequest("1")
+		user := auth.Principal{Kind: auth.KindUser, Subject: "user-1", Wallets: []string{"2"}}
+		r = r.WithContext(auth.ContextWithPrincipal(r.Context(), user))
+
+		http.HandlerFunc(hh.StreamWallet).ServeHTTP(recorder, r)
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+	})
+}
@@ -0,0 +1,124 @@
+package wallet_test
+
+import (
+	"testing"
+
+	"github.com/ijalalfrz/coinbit-test/entity"
+	"github.com/ijalalfrz/coinbit-test/pubsub"
+	pubsubMock "github.com/ijalalfrz/coinbit-test/pubsub/mocks"
+	"github.com/ijalalfrz/coinbit-test/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// newStreamViews returns view table mocks capturing the update listener of the hub.
+func newStreamViews(listeners *[]pubsub.UpdateListener) (balanceTableMock, thresholdTableMock *pubsubMock.ViewTable) {
+	balanceTableMock = &pubsubMock.ViewTable{}
+	thresholdTableMock = &pubsubMock.ViewTable{}
+	capture := func(args mock.Arguments) {
+		*listeners = append(*listeners, args.Get(0).(pubsub.UpdateListener))
+	}
+	balanceTableMock.On("OnUpdate", mock.Anything).Run(capture).Return()
+	thresholdTableMock.On("OnUpdate", mock.Anything).Run(capture).Return()
+	return
+}
+
+func TestStreamHub_Subscribe(t *testing.T) {
+	var listeners []pubsub.UpdateListener
+	balanceTableMock, thresholdTableMock := newStreamViews(&listeners)
+	hub := wallet.NewStreamHub(balanceTableMock, thresholdTableMock, 2)
+	assert.Len(t, listeners, 2)
+
+	first, err := hub.Subscribe("1")
+	assert.Nil(t, err)
+	second, err := hub.Subscribe("2")
+	assert.Nil(t, err)
+
+	t.Run("when cap is reached", func(t *testing.T) {
+		_, err := hub.Subscribe("3")
+
+		assert.ErrorIs(t, err, wallet.ErrTooManyStreams)
+	})
+
+	t.Run("when wallet is updated", func(t *testing.T) {
+		listeners[0]("1", &entity.Wallet{WalletId: "1"})
+		listeners[1]("1", &entity.Threshold{WalletId: "1"})
+
+		assert.Len(t, first.C(), 1)
+		assert.Len(t, second.C(), 0)
+	})
+
+	t.Run("when stream is closed", func(t *testing.T) {
+		hub.Unsubscribe(second)
+		hub.Unsubscribe(second)
+
+		_, err := hub.Subscribe("3")
+		assert.Nil(t, err)
+	})
+}
+
+func TestStreamHub_Snapshot(t *testing.T) {
+	var listeners []pubsub.UpdateListener
+	balanceTableMock, thresholdTableMock := newStreamViews(&listeners)
+	hub := wallet.NewStreamHub(balanceTableMock, thresholdTableMock, 1)
+	balanceTableMock.On("Get", "1").Return(&entity.Wallet{WalletId: "1", Balance: 500, LastDepositOffset: 4}, nil)
+	thresholdTableMock.On("Get", "1").Return(&entity.Threshold{WalletId: "1", LastDepositOffset: 3}, nil).Once()
+	thresholdTableMock.On("Get", "1").Return(&entity.Threshold{WalletId: "1", AboveThreshold: true, LastDepositOffset: 4}, nil)
+
+	t.Run("when views are not at the same deposit", func(t *testing.T) {
+		_, _, ok, err := hub.Snapshot("1")
+
+		assert.Nil(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("when views are at the same deposit", func(t *testing.T) {
+		detail, position, ok, err := hub.Snapshot("1")
+
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, float64(500), detail.Balance)
+		assert.True(t, detail.AboveThreshold)
+		assert.Equal(t, pubsub.Position{Partition: 0, Offset: 4}, position)
+	})
+}
+
+func TestStreamHub_Watch(t *testing.T) {
+	var listeners []pubsub.UpdateListener
+	balanceTableMock, thresholdTableMock := newStreamViews(&listeners)
+	hub := wallet.NewStreamHub(balanceTableMock, thresholdTableMock, 1)
+
+	sub, err := hub.Open()
+	assert.Nil(t, err)
+	hub.Watch(sub, "1")
+	hub.Watch(sub, "2")
+	hub.Watch(sub, "3")
+
+	t.Run("when watched wallets are updated", func(t *testing.T) {
+		listeners[0]("1", nil)
+		listeners[1]("2", nil)
+		listeners[0]("4", nil)
+
+		assert.Len(t, sub.C(), 1)
+		assert.ElementsMatch(t, []string{"1", "2"}, sub.Changed())
+		assert.Empty(t, sub.Changed())
+	})
+
+	t.Run("when wallet is unwatched", func(t *testing.T) {
+		<-sub.C()
+		hub.Unwatch(sub, "3")
+		listeners[0]("3", nil)
+
+		assert.Len(t, sub.C(), 0)
+		assert.Empty(t, sub.Changed())
+	})
+
+	t.Run("when stream is closed", func(t *testing.T) {
+		hub.Unsubscribe(sub)
+		listeners[0]("1", nil)
+
+		assert.Len(t, sub.C(), 0)
+		_, err := hub.Open()
+		assert.Nil(t, err)
+	})
+}
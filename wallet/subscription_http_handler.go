@@ -0,0 +1,277 @@
+package wallet
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/ijalalfrz/coinbit-test/auth"
+	"github.com/ijalalfrz/coinbit-test/exception"
+	"github.com/ijalalfrz/coinbit-test/response"
+	"github.com/ijalalfrz/coinbit-test/webmodel"
+	"github.com/sirupsen/logrus"
+)
+
+// SubscriptionsPath is the path template of the wallet subscriptions websocket endpoint.
+const SubscriptionsPath = basePath + "/v1/wallets/subscriptions"
+
+// collection of subscription actions and message types
+const (
+	SubscribeAction   = "subscribe"
+	UnsubscribeAction = "unsubscribe"
+
+	SubscribedMessage   = "subscribed"
+	UnsubscribedMessage = "unsubscribed"
+	WalletMessage       = "wallet"
+	ErrorMessage        = "error"
+)
+
+// collection of subscription message
+const (
+	subscriptionInvalidErrMessage   = "Message must be a json object with action and wallet_ids"
+	subscriptionActionErrMessage    = "Action must be subscribe or unsubscribe"
+	subscriptionTooManyErrMessage   = "Too many wallets are subscribed on the connection"
+	subscriptionForbiddenErrMessage = "Wallets are not owned by the authenticated user"
+)
+
+const (
+	// subscriptionWriteWait is the time allowed to write a message to the client.
+	subscriptionWriteWait = 10 * time.Second
+	// subscriptionMaxMessageSize is the largest message accepted from the client.
+	subscriptionMaxMessageSize = 64 * 1024
+)
+
+// subscriptionUpgrader accepts every origin, the api is open to every origin in the cors policy too.
+var subscriptionUpgrader = websocket.Upgrader{
+	HandshakeTimeout: subscriptionWriteWait,
+	CheckOrigin:      func(r *http.Request) bool { return true },
+}
+
+// SubscriptionHTTPHandler is a concrete struct of wallet subscriptions websocket handler.
+type SubscriptionHTTPHandler struct {
+	Logger *logrus.Logger
+	Hub    *StreamHub
+	// KeepaliveInterval is how often the client is pinged, it is disconnected when no pong is back in two intervals.
+	KeepaliveInterval time.Duration
+	// MaxWallets is the cap of wallets subscribed on a connection.
+	MaxWallets int
+}
+
+// subscriptionCommand is a message read from the client, err is set when it is not valid.
+type subscriptionCommand struct {
+	request webmodel.WalletSubscriptionRequest
+	err     error
+}
+
+// NewWalletSubscriptionHTTPHandler will register the websocket endpoint streaming the changes of the subscribed wallets.
+func NewWalletSubscriptionHTTPHandler(logger *logrus.Logger, router *mux.Router, hub *StreamHub, keepaliveInterval time.Duration, maxWallets int) {
+	handler := &SubscriptionHTTPHandler{
+		Logger:            logger,
+		Hub:               hub,
+		KeepaliveInterval: keepaliveInterval,
+		MaxWallets:        maxWallets,
+	}
+	router.HandleFunc(SubscriptionsPath, handler.SubscribeWallets).Methods(http.MethodGet)
+}
+
+// SubscribeWallets is a function to upgrade the connection to a websocket where the client subscribes and
+// unsubscribes wallets. The details of a wallet are sent when it is subscribed and every time a deposit is applied.
+func (handler SubscriptionHTTPHandler) SubscribeWallets(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	sub, err := handler.Hub.Open()
+	if err != nil {
+		w.Header().Set("Retry-After", "1")
+		response.Negotiate(w, r, response.FromError(exception.Wrap(exception.KindServiceUnavailable, err, streamTooManyErrMessage)))
+		return
+	}
+	defer handler.Hub.Unsubscribe(sub)
+
+	conn, err := subscriptionUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		handler.Logger.WithContext(ctx).Warnf("Wallet subscriptions are not opened: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	commands := make(chan subscriptionCommand)
+	done := make(chan struct{})
+	defer close(done)
+	go handler.read(conn, commands, done)
+
+	session := &subscriptionSession{
+		handler:   handler,
+		conn:      conn,
+		sub:       sub,
+		last:      map[string]*lastSent{},
+		principal: principalOf(r),
+	}
+
+	ping := time.NewTicker(handler.KeepaliveInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case command, ok := <-commands:
+			if !ok {
+				return
+			}
+			err = session.handle(command)
+		case <-sub.C():
+			err = session.send(sub.Changed())
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(subscriptionWriteWait))
+		}
+		if err != nil {
+			handler.Logger.WithContext(ctx).Warnf("Wallet subscriptions are closed: %v", err)
+			return
+		}
+	}
+}
+
+// read will pass the client messages to commands until the connection is closed.
+func (handler SubscriptionHTTPHandler) read(conn *websocket.Conn, commands chan<- subscriptionCommand, done <-chan struct{}) {
+	defer close(commands)
+
+	pongWait := 2 * handler.KeepaliveInterval
+	conn.SetReadLimit(subscriptionMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var command subscriptionCommand
+		command.err = json.Unmarshal(data, &command.request)
+		select {
+		case commands <- command:
+		case <-done:
+			return
+		}
+	}
+}
+
+// principalOf returns the authenticated principal of the request, nil when authentication is disabled.
+func principalOf(r *http.Request) *auth.Principal {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		return nil
+	}
+	return &principal
+}
+
+// subscriptionSession is the state of a websocket connection, it is only used by the connection loop.
+type subscriptionSession struct {
+	handler   SubscriptionHTTPHandler
+	conn      *websocket.Conn
+	sub       *Subscription
+	last      map[string]*lastSent
+	principal *auth.Principal
+}
+
+// handle will apply a client message.
+func (s *subscriptionSession) handle(command subscriptionCommand) error {
+	if command.err != nil {
+		return s.write(webmodel.WalletSubscriptionMessage{Type: ErrorMessage, Message: subscriptionInvalidErrMessage})
+	}
+
+	switch command.request.Action {
+	case SubscribeAction:
+		return s.subscribe(command.request.WalletIds)
+	case UnsubscribeAction:
+		return s.unsubscribe(command.request.WalletIds)
+	default:
+		return s.write(webmodel.WalletSubscriptionMessage{Type: ErrorMessage, Message: subscriptionActionErrMessage})
+	}
+}
+
+// subscribe will watch the wallets and send their current details.
+func (s *subscriptionSession) subscribe(walletIds []string) (err error) {
+	var subscribed, forbidden, rejected []string
+	for _, walletId := range walletIds {
+		if walletId == "" {
+			continue
+		}
+		if s.principal != nil && !s.principal.CanAccessWallet(walletId) {
+			forbidden = append(forbidden, walletId)
+			continue
+		}
+		if _, ok := s.last[walletId]; !ok {
+			if len(s.last) >= s.handler.MaxWallets {
+				rejected = append(rejected, walletId)
+				continue
+			}
+			s.handler.Hub.Watch(s.sub, walletId)
+			s.last[walletId] = nil
+		}
+		subscribed = append(subscribed, walletId)
+	}
+
+	if len(subscribed) > 0 {
+		if err = s.write(webmodel.WalletSubscriptionMessage{Type: SubscribedMessage, WalletIds: subscribed}); err != nil {
+			return
+		}
+	}
+	if len(forbidden) > 0 {
+		if err = s.write(webmodel.WalletSubscriptionMessage{Type: ErrorMessage, WalletIds: forbidden, Message: subscriptionForbiddenErrMessage}); err != nil {
+			return
+		}
+	}
+	if len(rejected) > 0 {
+		if err = s.write(webmodel.WalletSubscriptionMessage{Type: ErrorMessage, WalletIds: rejected, Message: subscriptionTooManyErrMessage}); err != nil {
+			return
+		}
+	}
+	return s.send(subscribed)
+}
+
+// unsubscribe will stop watching the wallets.
+func (s *subscriptionSession) unsubscribe(walletIds []string) error {
+	var unsubscribed []string
+	for _, walletId := range walletIds {
+		if _, ok := s.last[walletId]; !ok {
+			continue
+		}
+		s.handler.Hub.Unwatch(s.sub, walletId)
+		delete(s.last, walletId)
+		unsubscribed = append(unsubscribed, walletId)
+	}
+	return s.write(webmodel.WalletSubscriptionMessage{Type: UnsubscribedMessage, WalletIds: unsubscribed})
+}
+
+// send will write the details of the subscribed wallets which are newer than the last sent ones.
+func (s *subscriptionSession) send(walletIds []string) (err error) {
+	for _, walletId := range walletIds {
+		last, ok := s.last[walletId]
+		if !ok {
+			continue
+		}
+		detail, position, caughtUp, snapshotErr := s.handler.Hub.Snapshot(walletId)
+		if snapshotErr != nil {
+			s.handler.Logger.Warnf("Wallet %s is not sent to the subscriber: %v", walletId, snapshotErr)
+			continue
+		}
+		if !caughtUp || !last.newer(detail, position) {
+			continue
+		}
+
+		err = s.write(webmodel.WalletSubscriptionMessage{Type: WalletMessage, Wallet: &detail, ConsistencyToken: position.String()})
+		if err != nil {
+			return
+		}
+		s.last[walletId] = &lastSent{position: position, detail: &detail}
+	}
+	return
+}
+
+// write will send the message to the client.
+func (s *subscriptionSession) write(message webmodel.WalletSubscriptionMessage) error {
+	s.conn.SetWriteDeadline(time.Now().Add(subscriptionWriteWait))
+	return s.conn.WriteJSON(message)
+}
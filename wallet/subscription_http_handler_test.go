@@ -0,0 +1,137 @@
+package wallet_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ijalalfrz/coinbit-test/auth"
+	"github.com/ijalalfrz/coinbit-test/entity"
+	"github.com/ijalalfrz/coinbit-test/pubsub"
+	"github.com/ijalalfrz/coinbit-test/wallet"
+	"github.com/ijalalfrz/coinbit-test/webmodel"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// dialSubscriptions opens a websocket on the handler, the requests are authenticated as the principal when it is set.
+func dialSubscriptions(t *testing.T, hh wallet.SubscriptionHTTPHandler, principal *auth.Principal) (*websocket.Conn, func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if principal != nil {
+			r = r.WithContext(auth.ContextWithPrincipal(r.Context(), *principal))
+		}
+		hh.SubscribeWallets(w, r)
+	}))
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if !assert.Nil(t, err) {
+		server.Close()
+		t.FailNow()
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	return conn, func() {
+		conn.Close()
+		server.Close()
+	}
+}
+
+func readSubscriptionMessage(t *testing.T, conn *websocket.Conn) (message webmodel.WalletSubscriptionMessage) {
+	assert.Nil(t, conn.ReadJSON(&message))
+	return
+}
+
+func TestSubscribeWallets(t *testing.T) {
+	var listeners []pubsub.UpdateListener
+	balanceTableMock, thresholdTableMock := newStreamViews(&listeners)
+	hub := wallet.NewStreamHub(balanceTableMock, thresholdTableMock, 10)
+	var offset int64 = 1
+	for _, walletId := range []string{"1", "2"} {
+		walletId := walletId
+		balanceTableMock.On("Get", walletId).Return(func(string) interface{} {
+			o := atomic.LoadInt64(&offset)
+			return &entity.Wallet{WalletId: walletId, Balance: float64(o * 100), LastDepositOffset: o}
+		}, nil)
+		thresholdTableMock.On("Get", walletId).Return(func(string) interface{} {
+			return &entity.Threshold{WalletId: walletId, LastDepositOffset: atomic.LoadInt64(&offset)}
+		}, nil)
+	}
+	hh := wallet.SubscriptionHTTPHandler{
+		Logger:            logrus.New(),
+		Hub:               hub,
+		KeepaliveInterval: time.Second,
+		MaxWallets:        2,
+	}
+
+	t.Run("when wallets are subscribed and updated", func(t *testing.T) {
+		conn, closeConn := dialSubscriptions(t, hh, nil)
+		defer closeConn()
+
+		assert.Nil(t, conn.WriteJSON(webmodel.WalletSubscriptionRequest{Action: wallet.SubscribeAction, WalletIds: []string{"1", "2", "3"}}))
+
+		subscribed := readSubscriptionMessage(t, conn)
+		assert.Equal(t, wallet.SubscribedMessage, subscribed.Type)
+		assert.Equal(t, []string{"1", "2"}, subscribed.WalletIds)
+		rejected := readSubscriptionMessage(t, conn)
+		assert.Equal(t, wallet.ErrorMessage, rejected.Type)
+		assert.Equal(t, []string{"3"}, rejected.WalletIds)
+		first := readSubscriptionMessage(t, conn)
+		assert.Equal(t, wallet.WalletMessage, first.Type)
+		assert.Equal(t, "1", first.Wallet.WalletId)
+		assert.Equal(t, "0:1", first.ConsistencyToken)
+		assert.Equal(t, "2", readSubscriptionMessage(t, conn).Wallet.WalletId)
+
+		assert.Nil(t, conn.WriteJSON(webmodel.WalletSubscriptionRequest{Action: wallet.UnsubscribeAction, WalletIds: []string{"2"}}))
+		unsubscribed := readSubscriptionMessage(t, conn)
+		assert.Equal(t, wallet.UnsubscribedMessage, unsubscribed.Type)
+		assert.Equal(t, []string{"2"}, unsubscribed.WalletIds)
+
+		atomic.StoreInt64(&offset, 2)
+		listeners[0]("2", nil)
+		listeners[0]("1", nil)
+
+		updated := readSubscriptionMessage(t, conn)
+		assert.Equal(t, wallet.WalletMessage, updated.Type)
+		assert.Equal(t, "1", updated.Wallet.WalletId)
+		assert.Equal(t, float64(200), updated.Wallet.Balance)
+		assert.Equal(t, "0:2", updated.ConsistencyToken)
+	})
+
+	t.Run("when message is not valid", func(t *testing.T) {
+		conn, closeConn := dialSubscriptions(t, hh, nil)
+		defer closeConn()
+
+		assert.Nil(t, conn.WriteMessage(websocket.TextMessage, []byte("not json")))
+		assert.Equal(t, wallet.ErrorMessage, readSubscriptionMessage(t, conn).Type)
+
+		assert.Nil(t, conn.WriteJSON(webmodel.WalletSubscriptionRequest{Action: "watch", WalletIds: []string{"1"}}))
+		assert.Equal(t, wallet.ErrorMessage, readSubscriptionMessage(t, conn).Type)
+	})
+
+	t.Run("when user does not own the wallet", func(t *testing.T) {
+		user := &auth.Principal{Kind: auth.KindUser, Subject: "user-1", Wallets: []string{"2"}}
+		conn, closeConn := dialSubscriptions(t, hh, user)
+		defer closeConn()
+
+		assert.Nil(t, conn.WriteJSON(webmodel.WalletSubscriptionRequest{Action: wallet.SubscribeAction, WalletIds: []string{"1"}}))
+
+		forbidden := readSubscriptionMessage(t, conn)
+		assert.Equal(t, wallet.ErrorMessage, forbidden.Type)
+		assert.Equal(t, []string{"1"}, forbidden.WalletIds)
+	})
+
+	t.Run("when cap of streams is reached", func(t *testing.T) {
+		full := hh
+		full.Hub = wallet.NewStreamHub(balanceTableMock, thresholdTableMock, 1)
+		sub, err := full.Hub.Open()
+		assert.Nil(t, err)
+		defer full.Hub.Unsubscribe(sub)
+		recorder := httptest.NewRecorder()
+
+		http.HandlerFunc(full.SubscribeWallets).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/just/for/testing", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	})
+}
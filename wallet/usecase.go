@@ -2,80 +2,188 @@ package wallet
 
 import (
 	"context"
-	"fmt"
-	"net/http"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ijalalfrz/coinbit-test/entity"
 	"github.com/ijalalfrz/coinbit-test/exception"
+	"github.com/ijalalfrz/coinbit-test/metrics"
 	"github.com/ijalalfrz/coinbit-test/model"
 	"github.com/ijalalfrz/coinbit-test/pubsub"
-	"github.com/ijalalfrz/coinbit-test/response"
 	"github.com/ijalalfrz/coinbit-test/webmodel"
 	"github.com/lovoo/goka"
 	"github.com/sirupsen/logrus"
 )
 
+// consistencyPollInterval is how often the views are read while waiting for a consistency token.
+const consistencyPollInterval = 50 * time.Millisecond
+
 // collection of message
 const (
 	depositUnexpectedErrMessage    = "Unexpected error while processing deposit wallet"
 	depositSuccessMessage          = "Deposit to wallet has been processed"
+	depositAppliedMessage          = "Deposit to wallet has been applied"
+	depositAcceptedMessage         = "Deposit to wallet has been accepted and is not applied yet"
 	addBalanceSuccessMessage       = "Add balance to wallet: %s is successfully processed, current balance: %.2f"
 	processThresholdSuccessMessage = "Balance threshold for wallet: %s has been processed, current above threshold status: %t"
 	detailUnexpectedErrMessage     = "Unexpected error while getting wallet details"
 	detailSuccessMessage           = "Detail wallet"
 	detailNotfoundErrMessage       = "Wallet is not found"
+	detailForbiddenErrMessage      = "Wallet is not owned by the authenticated user"
+	detailTimeoutErrMessage        = "Wallet details have not caught up with the consistency token in time"
+	detailPartitionErrMessage      = "Consistency token is not a position of the partition of the wallet"
+	detailBatchCanceledErrMessage  = "Batch of wallet details has been canceled"
+	listUnexpectedErrMessage       = "Unexpected error while listing wallets"
+	balanceAtSuccessMessage        = "Balance of wallet at the point in time"
+	balanceAtUnexpectedErrMessage  = "Unexpected error while replaying the deposits of the wallet"
+	balanceAtNotfoundErrMessage    = "Wallet has no deposit at the point in time"
+	balanceAtNotRetainedErrMessage = "Deposits of the wallet at the point in time are not retained anymore"
+	balanceAtPartitionErrMessage   = "Consistency token is not a position of the partition of the wallet"
+	balanceAtTimeoutErrMessage     = "Replaying the deposits of the wallet has not finished in time"
+	balanceAtCanceledErrMessage    = "Replaying the deposits of the wallet has been canceled"
+	listCursorErrMessage           = "cursor is not a cursor of this sort"
+	ruleReloadedMessage            = "Wallet rule has been reloaded"
+	statementAddedMessage          = "Statements of wallet: %s have been processed, current balance: %.2f"
+	statementUnexpectedErrMessage  = "Unexpected error while getting wallet statements"
+	statementSuccessMessage        = "Wallet statements"
+	correctionSuccessMessage       = "Balance of wallet: %s has been corrected by %.2f, current balance: %.2f"
 )
 
 // Usecase is a collection of behavior of wallet.
 type Usecase interface {
-	Deposit(ctx context.Context, payload webmodel.DepositWalletPayload) (resp response.Response)
-	AddBalance(ctx goka.Context, payload *model.DepositWallet) (resp response.Response)
-	ProcessThreshold(ctx goka.Context, payload *model.DepositWallet) (resp response.Response)
-	GetDetail(ctx context.Context, walletId string) (resp response.Response)
+	Deposit(ctx context.Context, payload webmodel.DepositWalletPayload) (result webmodel.DepositWalletResponse, err error)
+	// DepositAndWait answers the wallet of the result only when the deposit is applied.
+	DepositAndWait(ctx context.Context, payload webmodel.DepositWalletPayload) (result webmodel.DepositWalletResponse, err error)
+	AddBalance(ctx goka.Context, payload *model.DepositWallet) (wallet *entity.Wallet)
+	// ApplyCorrection adds the amount of a correction event of the reconciliation to the balance, it is not a deposit.
+	ApplyCorrection(ctx goka.Context, payload *model.DepositWallet) (wallet *entity.Wallet)
+	ProcessThreshold(ctx goka.Context, payload *model.DepositWallet) (threshold *entity.Threshold)
+	AggregateStatement(ctx goka.Context, payload *model.DepositWallet) (statement *entity.Statement)
+	// GetDetail will wait until the deposit at minPosition is applied when it is not nil.
+	GetDetail(ctx context.Context, walletId string, minPosition *pubsub.Position) (detail webmodel.DetailWalletResponse, err error)
+	// GetDetails answers the details of the wallets in the order of walletIds, the wallets which are not found are flagged.
+	GetDetails(ctx context.Context, walletIds []string) (details []webmodel.BatchDetailWalletItem, err error)
+	// ListWallets answers a page of the wallets matching the query and the cursor of the next page.
+	ListWallets(ctx context.Context, query webmodel.ListWalletsQuery) (wallets []webmodel.DetailWalletResponse, page webmodel.PageMeta, err error)
+	// GetBalanceAt answers the balance and above threshold status of a wallet at a point in time.
+	GetBalanceAt(ctx context.Context, walletId string, at PointInTime) (balance webmodel.BalanceAtResponse, err error)
+	// GetStatements answers the daily or monthly statements of a wallet, the oldest first.
+	GetStatements(ctx context.Context, walletId string, period string) (statements webmodel.StatementResponse, err error)
+	ReloadRule(rule Rule)
 }
 
 type walletUsecase struct {
 	serviceName           string
 	logger                *logrus.Logger
 	depositTopicPublisher pubsub.Publisher
-	rollingPeriod         int
-	threshold             int64
+	rule                  *atomic.Value
 	balanceViewTable      pubsub.ViewTable
 	thresholdViewTable    pubsub.ViewTable
+	statementViewTable    pubsub.ViewTable
+	consistencyTimeout    time.Duration
+	batchLookupWorkers    int
+	depositReplayer       pubsub.Replayer
+	replayTimeout         time.Duration
+	statementDays         int
+	statementMonths       int
 }
 
 func NewWalletUsecase(property UsecaseProperty) Usecase {
+	rule := new(atomic.Value)
+	rule.Store(Rule{
+		Threshold:     property.Threshold,
+		RollingPeriod: property.RollingPeriod,
+	})
 	return &walletUsecase{
 		serviceName:           property.ServiceName,
 		logger:                property.Logger,
 		depositTopicPublisher: property.DepositTopicPublisher,
-		rollingPeriod:         property.RollingPeriod,
-		threshold:             property.Threshold,
+		rule:                  rule,
 		balanceViewTable:      property.BalanceViewTable,
 		thresholdViewTable:    property.ThresholdViewTable,
+		statementViewTable:    property.StatementViewTable,
+		consistencyTimeout:    property.ConsistencyTimeout,
+		batchLookupWorkers:    property.BatchLookupWorkers,
+		depositReplayer:       property.DepositReplayer,
+		replayTimeout:         property.ReplayTimeout,
+		statementDays:         property.StatementDays,
+		statementMonths:       property.StatementMonths,
 	}
 }
 
+// ReloadRule will swap the wallet rules, deposits processed afterward use the new rules
+func (u walletUsecase) ReloadRule(rule Rule) {
+	old := u.rule.Load().(Rule)
+	u.rule.Store(rule)
+	u.logger.WithFields(logrus.Fields{
+		"old_threshold":      old.Threshold,
+		"new_threshold":      rule.Threshold,
+		"old_rolling_period": old.RollingPeriod,
+		"new_rolling_period": rule.RollingPeriod,
+	}).Info(ruleReloadedMessage)
+}
+
 // Deposit is a method for request add balance to wallet
-func (u walletUsecase) Deposit(ctx context.Context, payload webmodel.DepositWalletPayload) (resp response.Response) {
+func (u walletUsecase) Deposit(ctx context.Context, payload webmodel.DepositWalletPayload) (result webmodel.DepositWalletResponse, err error) {
+	position, err := u.sendDeposit(ctx, payload)
+	if err != nil {
+		u.logger.WithContext(ctx).Error(err)
+		err = exception.Wrap(exception.KindInternal, err, depositUnexpectedErrMessage)
+		return
+	}
+
+	return depositResponse(position), nil
+}
+
+// DepositAndWait is a method for request add balance to wallet and wait until it is applied to return the new balance,
+// the deposit is answered as accepted when it is not applied within the consistency timeout
+func (u walletUsecase) DepositAndWait(ctx context.Context, payload webmodel.DepositWalletPayload) (result webmodel.DepositWalletResponse, err error) {
+	position, err := u.sendDeposit(ctx, payload)
+	if err != nil {
+		u.logger.WithContext(ctx).Error(err)
+		err = exception.Wrap(exception.KindInternal, err, depositUnexpectedErrMessage)
+		return
+	}
+
+	result = depositResponse(position)
+	balanceData, thresholdData, readErr := u.readViews(ctx, payload.WalletId, &position)
+	if readErr != nil {
+		if !errors.Is(readErr, exception.ErrGatewayTimeout) && ctx.Err() == nil {
+			u.logger.WithContext(ctx).Error(readErr)
+		}
+		return
+	}
+
+	aboveThreshold := thresholdData.(*entity.Threshold).AboveThreshold
+	result.Wallet = balanceData.(*entity.Wallet)
+	result.AboveThreshold = &aboveThreshold
+	return
+}
+
+func (u walletUsecase) sendDeposit(ctx context.Context, payload webmodel.DepositWalletPayload) (position pubsub.Position, err error) {
 	var deposit = &model.DepositWallet{
 		WalletId: payload.WalletId,
 		Amount:   payload.Amount,
 	}
+	position, err = u.depositTopicPublisher.Send(ctx, payload.WalletId, deposit)
+	return
+}
 
-	err := u.depositTopicPublisher.Send(ctx, payload.WalletId, deposit)
-	if err != nil {
-		u.logger.Error(err)
-		return response.NewErrorResponse(err, http.StatusInternalServerError, nil, response.StatUnexpectedError, depositUnexpectedErrMessage)
+func depositResponse(position pubsub.Position) webmodel.DepositWalletResponse {
+	return webmodel.DepositWalletResponse{
+		Partition:        position.Partition,
+		Offset:           position.Offset,
+		ConsistencyToken: position.String(),
 	}
-
-	return response.NewSuccessResponse(nil, response.StatOK, depositSuccessMessage)
 }
 
 // AddBalance is a method for add balance to wallet
-func (u walletUsecase) AddBalance(ctx goka.Context, payload *model.DepositWallet) (resp response.Response) {
-	var wallet *entity.Wallet
+func (u walletUsecase) AddBalance(ctx goka.Context, payload *model.DepositWallet) (wallet *entity.Wallet) {
 	if val := ctx.Value(); val != nil {
 		wallet = val.(*entity.Wallet)
 	} else {
@@ -84,25 +192,54 @@ func (u walletUsecase) AddBalance(ctx goka.Context, payload *model.DepositWallet
 
 	wallet.Balance += payload.GetAmount()
 	wallet.WalletId = payload.GetWalletId()
+	wallet.LastDepositPartition = ctx.Partition()
+	wallet.LastDepositOffset = ctx.Offset()
 	ctx.SetValue(wallet)
-	return response.NewSuccessResponse(wallet, response.StatOK, fmt.Sprintf(addBalanceSuccessMessage, wallet.WalletId, wallet.Balance))
+	addByDirection(metrics.DepositedAmountTotal, payload.GetAmount())
+	return wallet
+}
+
+// ApplyCorrection is a method for correcting the balance of a wallet, the position of the last deposit is kept
+// because the consistency tokens are positions of the deposits stream. The offset of the correction is recorded
+// so the reconciliation knows it is applied
+func (u walletUsecase) ApplyCorrection(ctx goka.Context, payload *model.DepositWallet) (wallet *entity.Wallet) {
+	if val := ctx.Value(); val != nil {
+		wallet = val.(*entity.Wallet)
+	} else {
+		wallet = new(entity.Wallet)
+	}
 
+	wallet.Balance += payload.GetAmount()
+	wallet.WalletId = payload.GetWalletId()
+	wallet.CorrectionOffset = ctx.Offset() + 1
+	ctx.SetValue(wallet)
+	addByDirection(metrics.CorrectedAmountTotal, payload.GetAmount())
+	return wallet
 }
 
 // ProcessThreshold is a method for processing deposit threshold on rolling period
-func (u walletUsecase) ProcessThreshold(ctx goka.Context, payload *model.DepositWallet) (resp response.Response) {
-	var threshold *entity.Threshold
+func (u walletUsecase) ProcessThreshold(ctx goka.Context, payload *model.DepositWallet) (threshold *entity.Threshold) {
+	rule := u.rule.Load().(Rule)
+	now := time.Now().UnixNano()
 	if val := ctx.Value(); val != nil {
 		threshold = val.(*entity.Threshold)
 	} else {
 		threshold = new(entity.Threshold)
-		threshold.StartWindowTime = time.Now().UnixNano()
+		threshold.StartWindowTime = now
 	}
 
-	now := time.Now().UnixNano()
 	threshold.WalletId = payload.GetWalletId()
-	threshold.Deposit = payload.Amount
-	threshold.TotalDepositWithinWindow += payload.Amount
+	threshold.LastDepositPartition = ctx.Partition()
+	threshold.LastDepositOffset = ctx.Offset()
+	applyThreshold(threshold, payload.Amount, now, rule)
+	ctx.SetValue(threshold)
+	return threshold
+}
+
+// applyThreshold will add the deposit of amount made at now to the rolling period of threshold
+func applyThreshold(threshold *entity.Threshold, amount float64, now int64, rule Rule) {
+	threshold.Deposit = amount
+	threshold.TotalDepositWithinWindow += amount
 	threshold.CreatedTime = now
 
 	// get difference time between time when roliing period started and current deposit time
@@ -111,45 +248,504 @@ func (u walletUsecase) ProcessThreshold(ctx goka.Context, payload *model.Deposit
 	diff := timeNow.Sub(timeStartRollingPeriod)
 
 	// check if still in rolling period
-	if diff.Seconds() > float64(u.rollingPeriod) {
+	if diff.Seconds() > float64(rule.RollingPeriod) {
 		// Reset rolling period time to current time
 		threshold.AboveThreshold = false
 		threshold.StartWindowTime = now
-		threshold.TotalDepositWithinWindow = payload.Amount
+		threshold.TotalDepositWithinWindow = amount
 	} else {
-		if threshold.TotalDepositWithinWindow > float64(u.threshold) {
+		if threshold.TotalDepositWithinWindow > float64(rule.Threshold) {
 			threshold.AboveThreshold = true
 		} else {
 			threshold.AboveThreshold = false
 		}
 	}
-	ctx.SetValue(threshold)
-	return response.NewSuccessResponse(threshold, response.StatOK, fmt.Sprintf(processThresholdSuccessMessage, threshold.WalletId, threshold.AboveThreshold))
+}
+
+// Collection of statement period and the layout of their buckets.
+const (
+	StatementPeriodDaily   = "daily"
+	StatementPeriodMonthly = "monthly"
+	dailyStatementLayout   = "2006-01-02"
+	monthlyStatementLayout = "2006-01"
+)
+
+// AggregateStatement is a method for adding a deposit to the daily and monthly statements of a wallet, the bucket
+// is chosen by the UTC time the deposit is written at
+func (u walletUsecase) AggregateStatement(ctx goka.Context, payload *model.DepositWallet) (statement *entity.Statement) {
+	if val := ctx.Value(); val != nil {
+		statement = val.(*entity.Statement)
+	} else {
+		statement = new(entity.Statement)
+	}
 
+	at := ctx.Timestamp().UTC()
+	amount := payload.GetAmount()
+	statement.WalletId = payload.GetWalletId()
+	statement.Daily = addToBuckets(statement.Daily, at.Format(dailyStatementLayout), amount, statement.Balance, u.statementDays)
+	statement.Monthly = addToBuckets(statement.Monthly, at.Format(monthlyStatementLayout), amount, statement.Balance, u.statementMonths)
+	statement.Balance += amount
+	statement.LastDepositPartition = ctx.Partition()
+	statement.LastDepositOffset = ctx.Offset()
+	ctx.SetValue(statement)
+	return statement
 }
 
-func (u walletUsecase) GetDetail(ctx context.Context, walletId string) (resp response.Response) {
-	balanceData, err := u.balanceViewTable.Get(walletId)
+// addToBuckets will add the deposit of amount to the bucket of period and drop the oldest buckets above limit.
+// A deposit of a period older than the last bucket is booked in the last bucket because the balances of the
+// newer buckets are already closed.
+func addToBuckets(buckets []entity.StatementBucket, period string, amount, balance float64, limit int) []entity.StatementBucket {
+	if n := len(buckets); n == 0 || buckets[n-1].Period < period {
+		buckets = append(buckets, entity.StatementBucket{Period: period, OpeningBalance: balance, ClosingBalance: balance})
+	}
+
+	bucket := &buckets[len(buckets)-1]
+	if amount >= 0 {
+		bucket.Credits += amount
+	} else {
+		bucket.Debits -= amount
+	}
+	bucket.ClosingBalance += amount
+	bucket.Deposits++
+
+	if limit > 0 && len(buckets) > limit {
+		buckets = append([]entity.StatementBucket(nil), buckets[len(buckets)-limit:]...)
+	}
+	return buckets
+}
+
+// GetStatements is a method for getting the statements of a wallet from the statement view
+func (u walletUsecase) GetStatements(ctx context.Context, walletId string, period string) (statements webmodel.StatementResponse, err error) {
+	data, err := u.statementViewTable.Get(walletId)
+	if err != nil {
+		u.logger.WithContext(ctx).Error(err)
+		err = exception.Wrap(exception.KindInternal, err, statementUnexpectedErrMessage)
+		return
+	}
+	if data == nil {
+		err = exception.New(exception.KindNotFound, detailNotfoundErrMessage)
+		return
+	}
+
+	statement := data.(*entity.Statement)
+	statements = webmodel.StatementResponse{
+		WalletId:   statement.WalletId,
+		Period:     period,
+		Statements: statement.Monthly,
+	}
+	if period == StatementPeriodDaily {
+		statements.Statements = statement.Daily
+	}
+	return
+}
+
+// GetDetail is a method for getting balance and above threshold status of a wallet
+func (u walletUsecase) GetDetail(ctx context.Context, walletId string, minPosition *pubsub.Position) (detail webmodel.DetailWalletResponse, err error) {
+	if minPosition != nil {
+		if err = u.checkTokenPartition(ctx, walletId, *minPosition, detailPartitionErrMessage, detailUnexpectedErrMessage); err != nil {
+			return
+		}
+	}
+
+	balanceData, thresholdData, err := u.readViews(ctx, walletId, minPosition)
+	if err != nil {
+		if errors.Is(err, exception.ErrGatewayTimeout) || ctx.Err() != nil {
+			err = exception.Wrap(exception.KindGatewayTimeout, err, detailTimeoutErrMessage)
+			return
+		}
+		u.logger.WithContext(ctx).Error(err)
+		err = exception.Wrap(exception.KindInternal, err, detailUnexpectedErrMessage)
+		return
+	}
+	return detailResponse(balanceData, thresholdData)
+}
+
+// GetDetails is a method for getting the details of many wallets, the views are read by a bounded pool of workers.
+// A wallet which is not found does not fail the batch unlike a view error.
+func (u walletUsecase) GetDetails(ctx context.Context, walletIds []string) (details []webmodel.BatchDetailWalletItem, err error) {
+	details = make([]webmodel.BatchDetailWalletItem, len(walletIds))
+	workers := u.batchLookupWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(walletIds) {
+		workers = len(walletIds)
+	}
+
+	lookupCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var once sync.Once
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				detail, lookupErr := u.lookupDetail(walletIds[i])
+				if lookupErr != nil {
+					once.Do(func() {
+						err = lookupErr
+						cancel()
+					})
+					continue
+				}
+				details[i] = detail
+			}
+		}()
+	}
+
+feed:
+	for i := range walletIds {
+		select {
+		case indexes <- i:
+		case <-lookupCtx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
 	if err != nil {
-		u.logger.Error(err)
-		return response.NewErrorResponse(err, http.StatusInternalServerError, nil, response.StatUnexpectedError, detailUnexpectedErrMessage)
+		u.logger.WithContext(ctx).Error(err)
+		return nil, exception.Wrap(exception.KindInternal, err, detailUnexpectedErrMessage)
 	}
+	if err = ctx.Err(); err != nil {
+		return nil, exception.Wrap(exception.KindTimeout, err, detailBatchCanceledErrMessage)
+	}
+	return
+}
 
+// lookupDetail will read the detail of a wallet from both views.
+func (u walletUsecase) lookupDetail(walletId string) (item webmodel.BatchDetailWalletItem, err error) {
+	item.WalletId = walletId
+	balanceData, err := u.balanceViewTable.Get(walletId)
+	if err != nil {
+		return
+	}
 	thresholdData, err := u.thresholdViewTable.Get(walletId)
 	if err != nil {
-		u.logger.Error(err)
-		return response.NewErrorResponse(err, http.StatusInternalServerError, nil, response.StatUnexpectedError, detailUnexpectedErrMessage)
+		return
 	}
+
+	detail, notFound := detailResponse(balanceData, thresholdData)
+	if notFound != nil {
+		item.ErrorCode = exception.Code(notFound)
+		return
+	}
+	item.Found = true
+	item.Wallet = &detail
+	return
+}
+
+// readViews will read both views, when minPosition is not nil it reads again until both views have
+// applied the deposit at minPosition and fails with exception.ErrGatewayTimeout after the consistency timeout.
+func (u walletUsecase) readViews(ctx context.Context, walletId string, minPosition *pubsub.Position) (balanceData, thresholdData interface{}, err error) {
+	var deadline <-chan time.Time
+	if minPosition != nil {
+		timer := time.NewTimer(u.consistencyTimeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	for {
+		balanceData, err = u.balanceViewTable.Get(walletId)
+		if err != nil {
+			return
+		}
+		thresholdData, err = u.thresholdViewTable.Get(walletId)
+		if err != nil {
+			return
+		}
+
+		if minPosition == nil || caughtUp(balanceData, thresholdData, *minPosition) {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		case <-deadline:
+			err = exception.ErrGatewayTimeout
+			return
+		case <-time.After(consistencyPollInterval):
+		}
+	}
+}
+
+// checkTokenPartition returns a bad request with message when position is not on the partition of the deposits
+// of walletId, the views of the wallet never reach such a position.
+func (u walletUsecase) checkTokenPartition(ctx context.Context, walletId string, position pubsub.Position, message, unexpectedMessage string) (err error) {
+	partition, err := u.depositReplayer.Partition(walletId)
+	if err != nil {
+		u.logger.WithContext(ctx).Error(err)
+		return exception.Wrap(exception.KindInternal, err, unexpectedMessage)
+	}
+	if partition != position.Partition {
+		return exception.New(exception.KindBadRequest, message)
+	}
+	return nil
+}
+
+// caughtUp returns true when both views have applied the deposit at position.
+func caughtUp(balanceData, thresholdData interface{}, position pubsub.Position) bool {
+	balance, ok := balanceData.(*entity.Wallet)
+	if !ok || balance.LastDepositPartition != position.Partition || balance.LastDepositOffset < position.Offset {
+		return false
+	}
+	threshold, ok := thresholdData.(*entity.Threshold)
+	if !ok || threshold.LastDepositPartition != position.Partition || threshold.LastDepositOffset < position.Offset {
+		return false
+	}
+	return true
+}
+
+func detailResponse(balanceData, thresholdData interface{}) (detail webmodel.DetailWalletResponse, err error) {
 	if balanceData == nil || thresholdData == nil {
-		err = exception.ErrNotFound
-		return response.NewErrorResponse(err, http.StatusNotFound, nil, response.StatNotFound, detailNotfoundErrMessage)
+		err = exception.New(exception.KindNotFound, detailNotfoundErrMessage)
+		return
 	}
 	balance := balanceData.(*entity.Wallet)
 	threshold := thresholdData.(*entity.Threshold)
-	detail := webmodel.DetailWalletResponse{
+	detail = webmodel.DetailWalletResponse{
 		WalletId:       balance.WalletId,
 		Balance:        balance.Balance,
 		AboveThreshold: threshold.AboveThreshold,
 	}
-	return response.NewSuccessResponse(detail, response.StatOK, detailSuccessMessage)
+	// both views have applied the deposits up to the older of their last deposits
+	if balance.LastDepositPartition == threshold.LastDepositPartition {
+		position := pubsub.Position{Partition: balance.LastDepositPartition, Offset: balance.LastDepositOffset}
+		if threshold.LastDepositOffset < position.Offset {
+			position.Offset = threshold.LastDepositOffset
+		}
+		detail.ConsistencyToken = position.String()
+	}
+	return
+}
+
+// GetBalanceAt is a method for getting the balance and above threshold status of a wallet at a point in time,
+// the deposits of the wallet are replayed from the stream with the current rule and the time they are written at.
+// Only the deposits retained by the stream are replayed, the whole partition of the wallet is read within the replay
+// timeout. A point in time without retained deposit of a truncated partition can not be answered.
+func (u walletUsecase) GetBalanceAt(ctx context.Context, walletId string, at PointInTime) (balance webmodel.BalanceAtResponse, err error) {
+	if at.Position != nil {
+		if err = u.checkTokenPartition(ctx, walletId, *at.Position, balanceAtPartitionErrMessage, balanceAtUnexpectedErrMessage); err != nil {
+			return
+		}
+	}
+
+	rule := u.rule.Load().(Rule)
+	replayCtx, cancel := context.WithTimeout(ctx, u.replayTimeout)
+	defer cancel()
+
+	threshold := new(entity.Threshold)
+	oldest, err := u.depositReplayer.Replay(replayCtx, walletId, func(message pubsub.ReplayedMessage) bool {
+		if !at.includes(message) {
+			return false
+		}
+		deposit, ok := message.Value.(*model.DepositWallet)
+		if !ok {
+			return true
+		}
+		now := message.Timestamp.UnixNano()
+		if balance.Deposits == 0 {
+			threshold.StartWindowTime = now
+		}
+		applyThreshold(threshold, deposit.GetAmount(), now, rule)
+		balance.Balance += deposit.GetAmount()
+		balance.Deposits++
+		balance.LastDepositTime = message.Timestamp
+		balance.ConsistencyToken = message.Position.String()
+		return true
+	})
+	if err != nil {
+		switch {
+		case ctx.Err() != nil:
+			err = exception.Wrap(exception.KindTimeout, err, balanceAtCanceledErrMessage)
+		case errors.Is(err, context.DeadlineExceeded):
+			err = exception.Wrap(exception.KindGatewayTimeout, err, balanceAtTimeoutErrMessage)
+		default:
+			u.logger.WithContext(ctx).Error(err)
+			err = exception.Wrap(exception.KindInternal, err, balanceAtUnexpectedErrMessage)
+		}
+		return
+	}
+	if balance.Deposits == 0 && oldest > 0 {
+		err = exception.New(exception.KindUnprocessableEntity, balanceAtNotRetainedErrMessage).WithCode(exception.CodeHistoryNotRetained)
+		return
+	}
+	if balance.Deposits == 0 {
+		err = exception.New(exception.KindNotFound, balanceAtNotfoundErrMessage)
+		return
+	}
+
+	balance.WalletId = walletId
+	balance.AboveThreshold = threshold.AboveThreshold
+	return
+}
+
+// Collection of sort of the wallets list, the descending ones are prefixed by a minus.
+const (
+	SortByWalletId     = "wallet_id"
+	SortByWalletIdDesc = "-wallet_id"
+	SortByBalance      = "balance"
+	SortByBalanceDesc  = "-balance"
+)
+
+// listCursor is the last wallet of a page, the next page starts after it.
+type listCursor struct {
+	Sort     string  `json:"s"`
+	WalletId string  `json:"w"`
+	Balance  float64 `json:"b"`
+}
+
+// ListWallets is a method for listing the wallets of the balance view, the whole view is read on every call
+// because it is not sorted.
+func (u walletUsecase) ListWallets(ctx context.Context, query webmodel.ListWalletsQuery) (wallets []webmodel.DetailWalletResponse, page webmodel.PageMeta, err error) {
+	if query.Sort == "" {
+		query.Sort = SortByWalletId
+	}
+	page = webmodel.PageMeta{Limit: query.Limit, Sort: query.Sort}
+
+	var after *listCursor
+	if query.Cursor != "" {
+		after, err = decodeListCursor(query.Cursor, query.Sort)
+		if err != nil {
+			return
+		}
+	}
+
+	var allowed map[string]bool
+	if query.Restricted {
+		allowed = make(map[string]bool, len(query.WalletIds))
+		for _, walletId := range query.WalletIds {
+			allowed[walletId] = true
+		}
+	}
+
+	var matched []webmodel.DetailWalletResponse
+	collect := func(key string, value interface{}) bool {
+		balance, ok := value.(*entity.Wallet)
+		if !ok || (allowed != nil && !allowed[key]) {
+			return true
+		}
+		if (query.MinBalance != nil && balance.Balance < *query.MinBalance) || (query.MaxBalance != nil && balance.Balance > *query.MaxBalance) {
+			return true
+		}
+		matched = append(matched, webmodel.DetailWalletResponse{WalletId: key, Balance: balance.Balance})
+		return ctx.Err() == nil
+	}
+	if query.Prefix != "" {
+		err = u.balanceViewTable.IteratePrefix(query.Prefix, collect)
+	} else {
+		err = u.balanceViewTable.Iterate(collect)
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		u.logger.WithContext(ctx).Error(err)
+		err = exception.Wrap(exception.KindInternal, err, listUnexpectedErrMessage)
+		return
+	}
+
+	// the threshold is read for every wallet only when it filters, otherwise only for the page
+	if query.AboveThresholdOnly {
+		if matched, err = u.withThreshold(ctx, matched); err != nil {
+			return
+		}
+		filtered := matched[:0]
+		for _, wallet := range matched {
+			if wallet.AboveThreshold {
+				filtered = append(filtered, wallet)
+			}
+		}
+		matched = filtered
+	}
+
+	less := listLess(query.Sort)
+	sort.Slice(matched, func(i, j int) bool { return less(matched[i], matched[j]) })
+	page.Total = len(matched)
+
+	start := 0
+	if after != nil {
+		last := webmodel.DetailWalletResponse{WalletId: after.WalletId, Balance: after.Balance}
+		start = sort.Search(len(matched), func(i int) bool { return less(last, matched[i]) })
+	}
+	end := start + query.Limit
+	if end > len(matched) {
+		end = len(matched)
+	}
+	wallets = matched[start:end]
+	if end < len(matched) {
+		last := wallets[len(wallets)-1]
+		page.NextCursor = encodeListCursor(listCursor{Sort: query.Sort, WalletId: last.WalletId, Balance: last.Balance})
+	}
+	page.Count = len(wallets)
+
+	if !query.AboveThresholdOnly {
+		wallets, err = u.withThreshold(ctx, wallets)
+	}
+	return
+}
+
+// withThreshold will set the above threshold status of the wallets from the threshold view.
+func (u walletUsecase) withThreshold(ctx context.Context, wallets []webmodel.DetailWalletResponse) ([]webmodel.DetailWalletResponse, error) {
+	for i := range wallets {
+		data, err := u.thresholdViewTable.Get(wallets[i].WalletId)
+		if err != nil {
+			u.logger.WithContext(ctx).Error(err)
+			return nil, exception.Wrap(exception.KindInternal, err, listUnexpectedErrMessage)
+		}
+		if threshold, ok := data.(*entity.Threshold); ok {
+			wallets[i].AboveThreshold = threshold.AboveThreshold
+		}
+	}
+	return wallets, nil
+}
+
+// listLess returns the order of the sort, the wallets having the same balance are ordered by id.
+func listLess(sortBy string) func(a, b webmodel.DetailWalletResponse) bool {
+	switch sortBy {
+	case SortByWalletIdDesc:
+		return func(a, b webmodel.DetailWalletResponse) bool { return a.WalletId > b.WalletId }
+	case SortByBalance:
+		return func(a, b webmodel.DetailWalletResponse) bool {
+			if a.Balance != b.Balance {
+				return a.Balance < b.Balance
+			}
+			return a.WalletId < b.WalletId
+		}
+	case SortByBalanceDesc:
+		return func(a, b webmodel.DetailWalletResponse) bool {
+			if a.Balance != b.Balance {
+				return a.Balance > b.Balance
+			}
+			return a.WalletId < b.WalletId
+		}
+	default:
+		return func(a, b webmodel.DetailWalletResponse) bool { return a.WalletId < b.WalletId }
+	}
+}
+
+// encodeListCursor returns the opaque cursor sent to the clients.
+func encodeListCursor(cursor listCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeListCursor will read the cursor, it must have been made for the same sort.
+func decodeListCursor(value, sortBy string) (cursor *listCursor, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err == nil {
+		err = json.Unmarshal(data, &cursor)
+	}
+	if err == nil && (cursor == nil || cursor.Sort != sortBy) {
+		err = errors.New("cursor sort does not match")
+	}
+	if err != nil {
+		return nil, exception.Wrap(exception.KindBadRequest, err, listCursorErrMessage)
+	}
+	return
 }
@@ -3,16 +3,21 @@ package wallet_test
 import (
 	"context"
 	"net/http"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/ijalalfrz/coinbit-test/entity"
 	"github.com/ijalalfrz/coinbit-test/exception"
+	"github.com/ijalalfrz/coinbit-test/metrics"
 	"github.com/ijalalfrz/coinbit-test/model"
+	"github.com/ijalalfrz/coinbit-test/pubsub"
 	pubsubMock "github.com/ijalalfrz/coinbit-test/pubsub/mocks"
 	"github.com/ijalalfrz/coinbit-test/response"
 	"github.com/ijalalfrz/coinbit-test/wallet"
 	"github.com/ijalalfrz/coinbit-test/webmodel"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -32,15 +37,16 @@ func TestOnDepositWallet_Unexpected_Error_When_SendMessage(t *testing.T) {
 		ThresholdViewTable:    &viewTableMock,
 	})
 
-	publisherMock.On("Send", mock.Anything, mock.Anything, mock.Anything).Return(exception.ErrInternalServer)
+	publisherMock.On("Send", mock.Anything, mock.Anything, mock.Anything).Return(pubsub.Position{}, exception.ErrInternalServer)
 	payload := webmodel.DepositWalletPayload{
 		WalletId: "1",
 		Amount:   1000,
 	}
-	resp := usecase.Deposit(context.TODO(), payload)
+	_, err := usecase.Deposit(context.TODO(), payload)
+	resp := response.FromError(err)
 
 	assert.Error(t, resp.Error())
-	assert.Equal(t, exception.ErrInternalServer, resp.Error(), "should equal to internal server error")
+	assert.ErrorIs(t, resp.Error(), exception.ErrInternalServer, "should equal to internal server error")
 	assert.Equal(t, response.StatUnexpectedError, resp.Status(), "should equal to status unexpected error")
 	assert.Equal(t, http.StatusInternalServerError, resp.HTTPStatusCode(), "should equal to http status internal server error")
 
@@ -63,16 +69,15 @@ func TestOnDepositWallet_Success(t *testing.T) {
 		ThresholdViewTable:    &viewTableMock,
 	})
 
-	publisherMock.On("Send", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	publisherMock.On("Send", mock.Anything, mock.Anything, mock.Anything).Return(pubsub.Position{Partition: 1, Offset: 42}, nil)
 	payload := webmodel.DepositWalletPayload{
 		WalletId: "1",
 		Amount:   1000,
 	}
-	resp := usecase.Deposit(context.TODO(), payload)
+	data, err := usecase.Deposit(context.TODO(), payload)
 
-	assert.Nil(t, resp.Error())
-	assert.Equal(t, response.StatOK, resp.Status(), "should equal to status ok")
-	assert.Equal(t, http.StatusOK, resp.HTTPStatusCode(), "should equal to http status ok/200")
+	assert.Nil(t, err)
+	assert.Equal(t, "1:42", data.ConsistencyToken)
 
 	publisherMock.AssertExpectations(t)
 	viewTableMock.AssertExpectations(t)
@@ -96,10 +101,11 @@ func TestGetDetailWallet_UnexpectedError_When_GetFromBalanceGroupTable(t *testin
 
 	balanceTableMock.On("Get", mock.AnythingOfType("string")).Return(nil, exception.ErrInternalServer)
 
-	resp := usecase.GetDetail(context.TODO(), "1")
+	_, err := usecase.GetDetail(context.TODO(), "1", nil)
+	resp := response.FromError(err)
 
 	assert.Error(t, resp.Error())
-	assert.Equal(t, exception.ErrInternalServer, resp.Error(), "should equal to internal server error")
+	assert.ErrorIs(t, resp.Error(), exception.ErrInternalServer, "should equal to internal server error")
 	assert.Equal(t, response.StatUnexpectedError, resp.Status(), "should equal to status unexpected error")
 	assert.Equal(t, http.StatusInternalServerError, resp.HTTPStatusCode(), "should equal to http status internal server error")
 
@@ -125,10 +131,11 @@ func TestGetDetailWallet_UnexpectedError_When_GetFromThresholdGroupTable(t *test
 	balanceTableMock.On("Get", mock.AnythingOfType("string")).Return(entity.Wallet{}, nil)
 	thresholdTableMock.On("Get", mock.AnythingOfType("string")).Return(nil, exception.ErrInternalServer)
 
-	resp := usecase.GetDetail(context.TODO(), "1")
+	_, err := usecase.GetDetail(context.TODO(), "1", nil)
+	resp := response.FromError(err)
 
 	assert.Error(t, resp.Error())
-	assert.Equal(t, exception.ErrInternalServer, resp.Error(), "should equal to internal server error")
+	assert.ErrorIs(t, resp.Error(), exception.ErrInternalServer, "should equal to internal server error")
 	assert.Equal(t, response.StatUnexpectedError, resp.Status(), "should equal to status unexpected error")
 	assert.Equal(t, http.StatusInternalServerError, resp.HTTPStatusCode(), "should equal to http status internal server error")
 
@@ -154,10 +161,11 @@ func TestGetDetailWallet_NotFoundError_When_GroupTable_Empty(t *testing.T) {
 	balanceTableMock.On("Get", mock.AnythingOfType("string")).Return(entity.Wallet{}, nil)
 	thresholdTableMock.On("Get", mock.AnythingOfType("string")).Return(nil, nil)
 
-	resp := usecase.GetDetail(context.TODO(), "1")
+	_, err := usecase.GetDetail(context.TODO(), "1", nil)
+	resp := response.FromError(err)
 
 	assert.Error(t, resp.Error())
-	assert.Equal(t, exception.ErrNotFound, resp.Error(), "should equal to not found error")
+	assert.ErrorIs(t, resp.Error(), exception.ErrNotFound, "should equal to not found error")
 	assert.Equal(t, response.StatNotFound, resp.Status(), "should equal to status not found error")
 	assert.Equal(t, http.StatusNotFound, resp.HTTPStatusCode(), "should equal to http status not found error/404")
 
@@ -195,12 +203,9 @@ func TestGetDetailWallet_Success(t *testing.T) {
 	balanceTableMock.On("Get", mock.AnythingOfType("string")).Return(wallet, nil)
 	thresholdTableMock.On("Get", mock.AnythingOfType("string")).Return(threshold, nil)
 
-	resp := usecase.GetDetail(context.TODO(), "1")
+	data, err := usecase.GetDetail(context.TODO(), "1", nil)
 
-	assert.Nil(t, resp.Error())
-	assert.Equal(t, response.StatOK, resp.Status(), "should equal to status ok")
-	assert.Equal(t, http.StatusOK, resp.HTTPStatusCode(), "should equal to http status ok/200")
-	data := resp.Data().(webmodel.DetailWalletResponse)
+	assert.Nil(t, err)
 	assert.Equal(t, data.WalletId, "1")
 	assert.Equal(t, data.Balance, float64(1000))
 	assert.Equal(t, data.AboveThreshold, false)
@@ -229,10 +234,10 @@ func TestAddBalance_Success(t *testing.T) {
 	}
 	contextMock.On("Value").Return(nil)
 	contextMock.On("SetValue", mock.Anything).Return(nil)
-	resp := usecase.AddBalance(&contextMock, payload)
-	assert.Nil(t, resp.Error())
-	assert.Equal(t, response.StatOK, resp.Status(), "should equal to status ok")
-	assert.Equal(t, http.StatusOK, resp.HTTPStatusCode(), "should equal to http status ok/200")
+	contextMock.On("Partition").Return(int32(0))
+	contextMock.On("Offset").Return(int64(0))
+	data := usecase.AddBalance(&contextMock, payload)
+	assert.NotNil(t, data)
 
 	contextMock.AssertExpectations(t)
 }
@@ -257,15 +262,34 @@ func TestAddBalance_Success_DataAlreadyExist(t *testing.T) {
 	}
 	contextMock.On("Value").Return(&entity.Wallet{WalletId: "1", Balance: 1000})
 	contextMock.On("SetValue", mock.Anything).Return(nil)
-	resp := usecase.AddBalance(&contextMock, payload)
-	assert.Nil(t, resp.Error())
-	assert.Equal(t, response.StatOK, resp.Status(), "should equal to status ok")
-	assert.Equal(t, http.StatusOK, resp.HTTPStatusCode(), "should equal to http status ok/200")
-	data := resp.Data().(*entity.Wallet)
+	contextMock.On("Partition").Return(int32(0))
+	contextMock.On("Offset").Return(int64(0))
+	data := usecase.AddBalance(&contextMock, payload)
+	assert.NotNil(t, data)
 	assert.Equal(t, data.Balance, float64(2000))
 	contextMock.AssertExpectations(t)
 }
 
+func TestAddBalance_Success_NegativeDeposit(t *testing.T) {
+	contextMock := pubsubMock.GokaContext{}
+	usecase := wallet.NewWalletUsecase(wallet.UsecaseProperty{Logger: logrus.New()})
+	payload := &model.DepositWallet{
+		WalletId: "1",
+		Amount:   -300,
+	}
+	contextMock.On("Value").Return(&entity.Wallet{WalletId: "1", Balance: 1000})
+	contextMock.On("SetValue", mock.Anything).Return(nil)
+	contextMock.On("Partition").Return(int32(0))
+	contextMock.On("Offset").Return(int64(1))
+	debits := testutil.ToFloat64(metrics.DepositedAmountTotal.WithLabelValues("debit"))
+
+	data := usecase.AddBalance(&contextMock, payload)
+
+	assert.Equal(t, float64(700), data.Balance)
+	assert.Equal(t, debits+300, testutil.ToFloat64(metrics.DepositedAmountTotal.WithLabelValues("debit")))
+	contextMock.AssertExpectations(t)
+}
+
 func TestProcessThreshold_Success(t *testing.T) {
 	publisherMock := pubsubMock.Publisher{}
 	balanceTableMock := pubsubMock.ViewTable{}
@@ -286,10 +310,10 @@ func TestProcessThreshold_Success(t *testing.T) {
 	}
 	contextMock.On("Value").Return(nil)
 	contextMock.On("SetValue", mock.Anything).Return(nil)
-	resp := usecase.ProcessThreshold(&contextMock, payload)
-	assert.Nil(t, resp.Error())
-	assert.Equal(t, response.StatOK, resp.Status(), "should equal to status ok")
-	assert.Equal(t, http.StatusOK, resp.HTTPStatusCode(), "should equal to http status ok/200")
+	contextMock.On("Partition").Return(int32(0))
+	contextMock.On("Offset").Return(int64(0))
+	data := usecase.ProcessThreshold(&contextMock, payload)
+	assert.NotNil(t, data)
 
 	contextMock.AssertExpectations(t)
 }
@@ -323,11 +347,10 @@ func TestProcessThreshold_Success_WithinRollingPeriod_BelowThreshold(t *testing.
 	}
 	contextMock.On("Value").Return(threshold)
 	contextMock.On("SetValue", mock.Anything).Return(nil)
-	resp := usecase.ProcessThreshold(&contextMock, payload)
-	assert.Nil(t, resp.Error())
-	assert.Equal(t, response.StatOK, resp.Status(), "should equal to status ok")
-	assert.Equal(t, http.StatusOK, resp.HTTPStatusCode(), "should equal to http status ok/200")
-	data := resp.Data().(*entity.Threshold)
+	contextMock.On("Partition").Return(int32(0))
+	contextMock.On("Offset").Return(int64(0))
+	data := usecase.ProcessThreshold(&contextMock, payload)
+	assert.NotNil(t, data)
 	assert.Equal(t, data.TotalDepositWithinWindow, float64(2000))
 	assert.Equal(t, data.AboveThreshold, false)
 	contextMock.AssertExpectations(t)
@@ -361,11 +384,10 @@ func TestProcessThreshold_Success_AboveRollingPeriod(t *testing.T) {
 	}
 	contextMock.On("Value").Return(threshold)
 	contextMock.On("SetValue", mock.Anything).Return(nil)
-	resp := usecase.ProcessThreshold(&contextMock, payload)
-	assert.Nil(t, resp.Error())
-	assert.Equal(t, response.StatOK, resp.Status(), "should equal to status ok")
-	assert.Equal(t, http.StatusOK, resp.HTTPStatusCode(), "should equal to http status ok/200")
-	data := resp.Data().(*entity.Threshold)
+	contextMock.On("Partition").Return(int32(0))
+	contextMock.On("Offset").Return(int64(0))
+	data := usecase.ProcessThreshold(&contextMock, payload)
+	assert.NotNil(t, data)
 	// rolling reset
 	assert.Equal(t, data.TotalDepositWithinWindow, float64(1000))
 	assert.Equal(t, data.AboveThreshold, false)
@@ -401,13 +423,584 @@ func TestProcessThreshold_Success_WithinRollingPeriod_AboveThreshold(t *testing.
 	}
 	contextMock.On("Value").Return(threshold)
 	contextMock.On("SetValue", mock.Anything).Return(nil)
-	resp := usecase.ProcessThreshold(&contextMock, payload)
-	assert.Nil(t, resp.Error())
-	assert.Equal(t, response.StatOK, resp.Status(), "should equal to status ok")
-	assert.Equal(t, http.StatusOK, resp.HTTPStatusCode(), "should equal to http status ok/200")
-	data := resp.Data().(*entity.Threshold)
+	contextMock.On("Partition").Return(int32(0))
+	contextMock.On("Offset").Return(int64(0))
+	data := usecase.ProcessThreshold(&contextMock, payload)
+	assert.NotNil(t, data)
 	// above threshold
 	assert.Equal(t, data.TotalDepositWithinWindow, float64(11000))
 	assert.Equal(t, data.AboveThreshold, true)
 	contextMock.AssertExpectations(t)
 }
+
+func TestReloadRule_ProcessThreshold_UseNewRule(t *testing.T) {
+	publisherMock := pubsubMock.Publisher{}
+	balanceTableMock := pubsubMock.ViewTable{}
+	thresholdTableMock := pubsubMock.ViewTable{}
+	contextMock := pubsubMock.GokaContext{}
+	usecase := wallet.NewWalletUsecase(wallet.UsecaseProperty{
+		ServiceName:           "test-service",
+		Logger:                logrus.New(),
+		DepositTopicPublisher: &publisherMock,
+		RollingPeriod:         180,
+		Threshold:             10000,
+		BalanceViewTable:      &balanceTableMock,
+		ThresholdViewTable:    &thresholdTableMock,
+	})
+	payload := &model.DepositWallet{
+		WalletId: "1",
+		Amount:   1000,
+	}
+	now := time.Now().UnixNano()
+	threshold := &entity.Threshold{
+		WalletId:                 "1",
+		Deposit:                  1000,
+		TotalDepositWithinWindow: 1000,
+		StartWindowTime:          now,
+		CreatedTime:              now,
+		AboveThreshold:           false,
+	}
+	contextMock.On("Value").Return(threshold)
+	contextMock.On("SetValue", mock.Anything).Return(nil)
+	contextMock.On("Partition").Return(int32(0))
+	contextMock.On("Offset").Return(int64(0))
+
+	usecase.ReloadRule(wallet.Rule{Threshold: 1500, RollingPeriod: 180})
+	data := usecase.ProcessThreshold(&contextMock, payload)
+
+	assert.NotNil(t, data)
+	assert.Equal(t, data.TotalDepositWithinWindow, float64(2000))
+	assert.Equal(t, data.AboveThreshold, true, "should be above the reloaded threshold")
+	contextMock.AssertExpectations(t)
+}
+
+func TestGetDetailWallet_WaitFor_ConsistencyToken(t *testing.T) {
+	publisherMock := pubsubMock.Publisher{}
+	balanceTableMock := pubsubMock.ViewTable{}
+	thresholdTableMock := pubsubMock.ViewTable{}
+	replayerMock := pubsubMock.Replayer{}
+	replayerMock.On("Partition", "1").Return(int32(1), nil)
+
+	usecase := wallet.NewWalletUsecase(wallet.UsecaseProperty{
+		ServiceName:           "test-service",
+		Logger:                logrus.New(),
+		DepositTopicPublisher: &publisherMock,
+		RollingPeriod:         180,
+		Threshold:             10000,
+		BalanceViewTable:      &balanceTableMock,
+		ThresholdViewTable:    &thresholdTableMock,
+		DepositReplayer:       &replayerMock,
+		ConsistencyTimeout:    time.Second,
+	})
+
+	stale := &entity.Wallet{WalletId: "1", Balance: 1000, LastDepositPartition: 1, LastDepositOffset: 41}
+	fresh := &entity.Wallet{WalletId: "1", Balance: 2000, LastDepositPartition: 1, LastDepositOffset: 42}
+	threshold := &entity.Threshold{WalletId: "1", LastDepositPartition: 1, LastDepositOffset: 42}
+	balanceTableMock.On("Get", "1").Return(stale, nil).Once()
+	balanceTableMock.On("Get", "1").Return(fresh, nil)
+	thresholdTableMock.On("Get", "1").Return(threshold, nil)
+
+	data, err := usecase.GetDetail(context.TODO(), "1", &pubsub.Position{Partition: 1, Offset: 42})
+
+	assert.Nil(t, err)
+	assert.Equal(t, float64(2000), data.Balance)
+	assert.Equal(t, "1:42", data.ConsistencyToken)
+	balanceTableMock.AssertNumberOfCalls(t, "Get", 2)
+}
+
+func TestGetDetailWallet_Timeout_When_View_Lags(t *testing.T) {
+	publisherMock := pubsubMock.Publisher{}
+	balanceTableMock := pubsubMock.ViewTable{}
+	thresholdTableMock := pubsubMock.ViewTable{}
+	replayerMock := pubsubMock.Replayer{}
+	replayerMock.On("Partition", "1").Return(int32(0), nil)
+
+	usecase := wallet.NewWalletUsecase(wallet.UsecaseProperty{
+		ServiceName:           "test-service",
+		Logger:                logrus.New(),
+		DepositTopicPublisher: &publisherMock,
+		RollingPeriod:         180,
+		Threshold:             10000,
+		BalanceViewTable:      &balanceTableMock,
+		ThresholdViewTable:    &thresholdTableMock,
+		DepositReplayer:       &replayerMock,
+		ConsistencyTimeout:    100 * time.Millisecond,
+	})
+
+	balanceTableMock.On("Get", "1").Return(nil, nil)
+	thresholdTableMock.On("Get", "1").Return(nil, nil)
+
+	_, err := usecase.GetDetail(context.TODO(), "1", &pubsub.Position{Partition: 0, Offset: 7})
+	resp := response.FromError(err)
+
+	assert.ErrorIs(t, resp.Error(), exception.ErrGatewayTimeout)
+	assert.Equal(t, response.StatTimeout, resp.Status(), "should equal to status timeout")
+	assert.Equal(t, http.StatusGatewayTimeout, resp.HTTPStatusCode(), "should equal to http status gateway timeout")
+}
+
+func TestGetDetailWallet_ConsistencyToken_Of_Another_Partition(t *testing.T) {
+	balanceTableMock := pubsubMock.ViewTable{}
+	thresholdTableMock := pubsubMock.ViewTable{}
+	replayerMock := pubsubMock.Replayer{}
+	replayerMock.On("Partition", "1").Return(int32(1), nil)
+
+	usecase := wallet.NewWalletUsecase(wallet.UsecaseProperty{
+		Logger:             logrus.New(),
+		BalanceViewTable:   &balanceTableMock,
+		ThresholdViewTable: &thresholdTableMock,
+		DepositReplayer:    &replayerMock,
+		ConsistencyTimeout: time.Minute,
+	})
+
+	_, err := usecase.GetDetail(context.TODO(), "1", &pubsub.Position{Partition: 0, Offset: 7})
+	resp := response.FromError(err)
+
+	assert.Equal(t, http.StatusBadRequest, resp.HTTPStatusCode(), "should fail without waiting for the timeout")
+	balanceTableMock.AssertNotCalled(t, "Get", mock.Anything)
+}
+
+func TestDepositAndWait(t *testing.T) {
+	newUsecase := func(publisherMock *pubsubMock.Publisher, balanceTableMock, thresholdTableMock *pubsubMock.ViewTable) wallet.Usecase {
+		return wallet.NewWalletUsecase(wallet.UsecaseProperty{
+			ServiceName:           "test-service",
+			Logger:                logrus.New(),
+			DepositTopicPublisher: publisherMock,
+			RollingPeriod:         180,
+			Threshold:             10000,
+			BalanceViewTable:      balanceTableMock,
+			ThresholdViewTable:    thresholdTableMock,
+			ConsistencyTimeout:    100 * time.Millisecond,
+		})
+	}
+	payload := webmodel.DepositWalletPayload{
+		WalletId: "1",
+		Amount:   1000,
+	}
+
+	t.Run("when deposit is applied", func(t *testing.T) {
+		publisherMock := pubsubMock.Publisher{}
+		balanceTableMock := pubsubMock.ViewTable{}
+		thresholdTableMock := pubsubMock.ViewTable{}
+		usecase := newUsecase(&publisherMock, &balanceTableMock, &thresholdTableMock)
+		publisherMock.On("Send", mock.Anything, "1", mock.Anything).Return(pubsub.Position{Partition: 0, Offset: 3}, nil)
+		balanceTableMock.On("Get", "1").Return(&entity.Wallet{WalletId: "1", Balance: 3000, LastDepositOffset: 3}, nil)
+		thresholdTableMock.On("Get", "1").Return(&entity.Threshold{WalletId: "1", AboveThreshold: true, LastDepositOffset: 3}, nil)
+
+		data, err := usecase.DepositAndWait(context.TODO(), payload)
+
+		assert.Nil(t, err)
+		assert.Equal(t, float64(3000), data.Wallet.Balance)
+		assert.True(t, *data.AboveThreshold)
+		assert.Equal(t, "0:3", data.ConsistencyToken)
+	})
+
+	t.Run("when deposit is not applied in time", func(t *testing.T) {
+		publisherMock := pubsubMock.Publisher{}
+		balanceTableMock := pubsubMock.ViewTable{}
+		thresholdTableMock := pubsubMock.ViewTable{}
+		usecase := newUsecase(&publisherMock, &balanceTableMock, &thresholdTableMock)
+		publisherMock.On("Send", mock.Anything, "1", mock.Anything).Return(pubsub.Position{Partition: 0, Offset: 3}, nil)
+		balanceTableMock.On("Get", "1").Return(&entity.Wallet{WalletId: "1", Balance: 2000, LastDepositOffset: 2}, nil)
+		thresholdTableMock.On("Get", "1").Return(&entity.Threshold{WalletId: "1", LastDepositOffset: 2}, nil)
+
+		data, err := usecase.DepositAndWait(context.TODO(), payload)
+
+		assert.Nil(t, err)
+		assert.Nil(t, data.Wallet)
+		assert.Equal(t, "0:3", data.ConsistencyToken)
+	})
+
+	t.Run("when send message fails", func(t *testing.T) {
+		publisherMock := pubsubMock.Publisher{}
+		balanceTableMock := pubsubMock.ViewTable{}
+		thresholdTableMock := pubsubMock.ViewTable{}
+		usecase := newUsecase(&publisherMock, &balanceTableMock, &thresholdTableMock)
+		publisherMock.On("Send", mock.Anything, "1", mock.Anything).Return(pubsub.Position{}, exception.ErrInternalServer)
+
+		_, err := usecase.DepositAndWait(context.TODO(), payload)
+		resp := response.FromError(err)
+
+		assert.ErrorIs(t, resp.Error(), exception.ErrInternalServer)
+		assert.Equal(t, http.StatusInternalServerError, resp.HTTPStatusCode())
+		balanceTableMock.AssertNotCalled(t, "Get", mock.Anything)
+	})
+}
+
+func TestListWallets(t *testing.T) {
+	newUsecase := func() wallet.Usecase {
+		balanceTableMock := pubsubMock.ViewTable{}
+		thresholdTableMock := pubsubMock.ViewTable{}
+		iteratePrefix := func(prefix string, fn func(string, interface{}) bool) error {
+			for _, balance := range []*entity.Wallet{
+				{WalletId: "user-3", Balance: 300},
+				{WalletId: "user-1", Balance: 100},
+				{WalletId: "shop-1", Balance: 500},
+				{WalletId: "user-2", Balance: 300},
+			} {
+				if strings.HasPrefix(balance.WalletId, prefix) && !fn(balance.WalletId, balance) {
+					break
+				}
+			}
+			return nil
+		}
+		balanceTableMock.On("Iterate", mock.Anything).Return(func(fn func(string, interface{}) bool) error {
+			return iteratePrefix("", fn)
+		})
+		balanceTableMock.On("IteratePrefix", mock.Anything, mock.Anything).Return(iteratePrefix)
+		thresholdTableMock.On("Get", mock.Anything).Return(func(walletId string) interface{} {
+			return &entity.Threshold{WalletId: walletId, AboveThreshold: walletId == "user-2" || walletId == "shop-1"}
+		}, nil)
+		return wallet.NewWalletUsecase(wallet.UsecaseProperty{
+			Logger:             logrus.New(),
+			BalanceViewTable:   &balanceTableMock,
+			ThresholdViewTable: &thresholdTableMock,
+		})
+	}
+	ids := func(wallets []webmodel.DetailWalletResponse) (walletIds []string) {
+		for _, wallet := range wallets {
+			walletIds = append(walletIds, wallet.WalletId)
+		}
+		return
+	}
+
+	t.Run("when wallets are paginated by balance", func(t *testing.T) {
+		usecase := newUsecase()
+		query := webmodel.ListWalletsQuery{Sort: wallet.SortByBalanceDesc, Limit: 2}
+
+		first, page, err := usecase.ListWallets(context.TODO(), query)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"shop-1", "user-2"}, ids(first))
+		assert.True(t, first[1].AboveThreshold)
+		assert.Equal(t, webmodel.PageMeta{Limit: 2, Count: 2, Total: 4, Sort: wallet.SortByBalanceDesc, NextCursor: page.NextCursor}, page)
+		assert.NotEmpty(t, page.NextCursor)
+
+		query.Cursor = page.NextCursor
+		second, page, err := usecase.ListWallets(context.TODO(), query)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"user-3", "user-1"}, ids(second))
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("when wallets are filtered", func(t *testing.T) {
+		minBalance, maxBalance := float64(200), float64(400)
+		wallets, page, err := newUsecase().ListWallets(context.TODO(), webmodel.ListWalletsQuery{
+			Prefix:     "user-",
+			MinBalance: &minBalance,
+			MaxBalance: &maxBalance,
+			Limit:      10,
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"user-2", "user-3"}, ids(wallets))
+		assert.Equal(t, wallet.SortByWalletId, page.Sort)
+	})
+
+	t.Run("when only wallets above threshold are listed", func(t *testing.T) {
+		wallets, page, err := newUsecase().ListWallets(context.TODO(), webmodel.ListWalletsQuery{AboveThresholdOnly: true, Limit: 10})
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"shop-1", "user-2"}, ids(wallets))
+		assert.Equal(t, 2, page.Total)
+	})
+
+	t.Run("when wallets are restricted", func(t *testing.T) {
+		wallets, _, err := newUsecase().ListWallets(context.TODO(), webmodel.ListWalletsQuery{Restricted: true, Limit: 10})
+
+		assert.Nil(t, err)
+		assert.Empty(t, wallets)
+	})
+
+	t.Run("when cursor is of another sort", func(t *testing.T) {
+		usecase := newUsecase()
+		_, page, _ := usecase.ListWallets(context.TODO(), webmodel.ListWalletsQuery{Limit: 1})
+
+		_, _, err := usecase.ListWallets(context.TODO(), webmodel.ListWalletsQuery{Sort: wallet.SortByBalance, Cursor: page.NextCursor, Limit: 1})
+
+		assert.ErrorIs(t, err, exception.ErrBadRequest)
+	})
+
+	t.Run("when view is not readable", func(t *testing.T) {
+		balanceTableMock := pubsubMock.ViewTable{}
+		balanceTableMock.On("Iterate", mock.Anything).Return(exception.ErrServiceUnavailable)
+		usecase := wallet.NewWalletUsecase(wallet.UsecaseProperty{Logger: logrus.New(), BalanceViewTable: &balanceTableMock})
+
+		_, _, err := usecase.ListWallets(context.TODO(), webmodel.ListWalletsQuery{Limit: 10})
+
+		assert.Equal(t, exception.KindInternal, exception.KindOf(err))
+	})
+}
+
+func TestGetDetails(t *testing.T) {
+	t.Run("when some wallets are not found", func(t *testing.T) {
+		balanceTableMock := pubsubMock.ViewTable{}
+		thresholdTableMock := pubsubMock.ViewTable{}
+		var inFlight, maxInFlight int32
+		balanceTableMock.On("Get", mock.Anything).Return(func(walletId string) interface{} {
+			n := atomic.AddInt32(&inFlight, 1)
+			defer atomic.AddInt32(&inFlight, -1)
+			for {
+				max := atomic.LoadInt32(&maxInFlight)
+				if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			if walletId == "missing" {
+				return nil
+			}
+			return &entity.Wallet{WalletId: walletId, Balance: 100}
+		}, nil)
+		thresholdTableMock.On("Get", mock.Anything).Return(func(walletId string) interface{} {
+			return &entity.Threshold{WalletId: walletId, AboveThreshold: true}
+		}, nil)
+		usecase := wallet.NewWalletUsecase(wallet.UsecaseProperty{
+			Logger:             logrus.New(),
+			BalanceViewTable:   &balanceTableMock,
+			ThresholdViewTable: &thresholdTableMock,
+			BatchLookupWorkers: 2,
+		})
+
+		details, err := usecase.GetDetails(context.TODO(), []string{"1", "missing", "2", "3", "4"})
+
+		assert.Nil(t, err)
+		assert.Len(t, details, 5)
+		assert.Equal(t, webmodel.BatchDetailWalletItem{
+			WalletId: "1",
+			Found:    true,
+			Wallet:   &webmodel.DetailWalletResponse{WalletId: "1", Balance: 100, AboveThreshold: true, ConsistencyToken: "0:0"},
+		}, details[0])
+		assert.Equal(t, webmodel.BatchDetailWalletItem{WalletId: "missing", ErrorCode: exception.CodeNotFound}, details[1])
+		assert.Equal(t, "4", details[4].WalletId)
+		assert.LessOrEqual(t, atomic.LoadInt32(&maxInFlight), int32(2), "should not read with more than the workers")
+	})
+
+	t.Run("when a view is not readable", func(t *testing.T) {
+		balanceTableMock := pubsubMock.ViewTable{}
+		balanceTableMock.On("Get", mock.Anything).Return(nil, exception.ErrServiceUnavailable)
+		usecase := wallet.NewWalletUsecase(wallet.UsecaseProperty{
+			Logger:             logrus.New(),
+			BalanceViewTable:   &balanceTableMock,
+			BatchLookupWorkers: 4,
+		})
+
+		details, err := usecase.GetDetails(context.TODO(), []string{"1", "2", "3"})
+
+		assert.Nil(t, details)
+		assert.Equal(t, exception.KindInternal, exception.KindOf(err))
+	})
+}
+
+func TestGetBalanceAt(t *testing.T) {
+	start := time.Date(2022, 8, 1, 10, 0, 0, 0, time.UTC)
+	messages := []pubsub.ReplayedMessage{
+		{Position: pubsub.Position{Partition: 0, Offset: 0}, Timestamp: start, Value: &model.DepositWallet{WalletId: "1", Amount: 6000}},
+		{Position: pubsub.Position{Partition: 0, Offset: 4}, Timestamp: start.Add(time.Minute), Value: &model.DepositWallet{WalletId: "1", Amount: 5000}},
+		{Position: pubsub.Position{Partition: 0, Offset: 9}, Timestamp: start.Add(time.Hour), Value: &model.DepositWallet{WalletId: "1", Amount: 100}},
+	}
+	newUsecase := func(replayerMock *pubsubMock.Replayer) wallet.Usecase {
+		return wallet.NewWalletUsecase(wallet.UsecaseProperty{
+			Logger:          logrus.New(),
+			RollingPeriod:   120,
+			Threshold:       10000,
+			DepositReplayer: replayerMock,
+			ReplayTimeout:   time.Second,
+		})
+	}
+	replay := func(replayerMock *pubsubMock.Replayer) {
+		replayerMock.On("Replay", mock.Anything, "1", mock.Anything).Return(int64(0), nil).Run(func(args mock.Arguments) {
+			fn := args.Get(2).(func(pubsub.ReplayedMessage) bool)
+			for _, message := range messages {
+				if !fn(message) {
+					return
+				}
+			}
+		})
+	}
+
+	t.Run("at a timestamp within the rolling period", func(t *testing.T) {
+		replayerMock := pubsubMock.Replayer{}
+		replay(&replayerMock)
+
+		balance, err := newUsecase(&replayerMock).GetBalanceAt(context.TODO(), "1", wallet.PointInTime{Time: start.Add(90 * time.Second)})
+
+		assert.Nil(t, err)
+		assert.Equal(t, webmodel.BalanceAtResponse{
+			WalletId:         "1",
+			Balance:          11000,
+			AboveThreshold:   true,
+			Deposits:         2,
+			LastDepositTime:  start.Add(time.Minute),
+			ConsistencyToken: "0:4",
+		}, balance)
+	})
+
+	t.Run("at a timestamp after the rolling period", func(t *testing.T) {
+		replayerMock := pubsubMock.Replayer{}
+		replay(&replayerMock)
+
+		balance, err := newUsecase(&replayerMock).GetBalanceAt(context.TODO(), "1", wallet.PointInTime{Time: start.Add(24 * time.Hour)})
+
+		assert.Nil(t, err)
+		assert.Equal(t, float64(11100), balance.Balance)
+		assert.False(t, balance.AboveThreshold)
+		assert.Equal(t, 3, balance.Deposits)
+	})
+
+	t.Run("at a consistency token", func(t *testing.T) {
+		replayerMock := pubsubMock.Replayer{}
+		replayerMock.On("Partition", "1").Return(int32(0), nil)
+		replay(&replayerMock)
+
+		balance, err := newUsecase(&replayerMock).GetBalanceAt(context.TODO(), "1", wallet.PointInTime{Position: &pubsub.Position{Partition: 0, Offset: 3}})
+
+		assert.Nil(t, err)
+		assert.Equal(t, float64(6000), balance.Balance)
+		assert.Equal(t, "0:0", balance.ConsistencyToken)
+	})
+
+	t.Run("when the consistency token is of another partition", func(t *testing.T) {
+		replayerMock := pubsubMock.Replayer{}
+		replayerMock.On("Partition", "1").Return(int32(2), nil)
+
+		_, err := newUsecase(&replayerMock).GetBalanceAt(context.TODO(), "1", wallet.PointInTime{Position: &pubsub.Position{Partition: 0, Offset: 3}})
+
+		assert.ErrorIs(t, err, exception.ErrBadRequest)
+		replayerMock.AssertNotCalled(t, "Replay", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("when there is no deposit before the timestamp", func(t *testing.T) {
+		replayerMock := pubsubMock.Replayer{}
+		replay(&replayerMock)
+
+		_, err := newUsecase(&replayerMock).GetBalanceAt(context.TODO(), "1", wallet.PointInTime{Time: start.Add(-time.Second)})
+
+		assert.ErrorIs(t, err, exception.ErrNotFound)
+	})
+
+	t.Run("when the deposits before the timestamp are not retained", func(t *testing.T) {
+		replayerMock := pubsubMock.Replayer{}
+		replayerMock.On("Replay", mock.Anything, "1", mock.Anything).Return(int64(4), nil).Run(func(args mock.Arguments) {
+			fn := args.Get(2).(func(pubsub.ReplayedMessage) bool)
+			fn(messages[1])
+		})
+
+		_, err := newUsecase(&replayerMock).GetBalanceAt(context.TODO(), "1", wallet.PointInTime{Time: start})
+		resp := response.FromError(err)
+
+		assert.Equal(t, exception.CodeHistoryNotRetained, exception.Code(err))
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.HTTPStatusCode())
+	})
+
+	t.Run("when the replay takes too long", func(t *testing.T) {
+		replayerMock := pubsubMock.Replayer{}
+		replayerMock.On("Replay", mock.Anything, "1", mock.Anything).Return(int64(0), context.DeadlineExceeded)
+
+		_, err := newUsecase(&replayerMock).GetBalanceAt(context.TODO(), "1", wallet.PointInTime{Time: start})
+
+		assert.ErrorIs(t, err, exception.ErrGatewayTimeout)
+	})
+
+	t.Run("when the stream is not readable", func(t *testing.T) {
+		replayerMock := pubsubMock.Replayer{}
+		replayerMock.On("Replay", mock.Anything, "1", mock.Anything).Return(int64(0), exception.ErrServiceUnavailable)
+
+		_, err := newUsecase(&replayerMock).GetBalanceAt(context.TODO(), "1", wallet.PointInTime{Time: start})
+
+		assert.Equal(t, exception.KindInternal, exception.KindOf(err))
+	})
+}
+
+func TestAggregateStatement(t *testing.T) {
+	usecase := wallet.NewWalletUsecase(wallet.UsecaseProperty{
+		Logger:          logrus.New(),
+		StatementDays:   2,
+		StatementMonths: 12,
+	})
+	deposit := func(statement *entity.Statement, at time.Time, amount float64) *entity.Statement {
+		contextMock := pubsubMock.GokaContext{}
+		if statement == nil {
+			contextMock.On("Value").Return(nil)
+		} else {
+			contextMock.On("Value").Return(statement)
+		}
+		contextMock.On("Timestamp").Return(at)
+		contextMock.On("SetValue", mock.Anything).Return(nil)
+		contextMock.On("Partition").Return(int32(0))
+		contextMock.On("Offset").Return(int64(1))
+		return usecase.AggregateStatement(&contextMock, &model.DepositWallet{WalletId: "1", Amount: amount})
+	}
+
+	day := time.Date(2022, 8, 31, 23, 0, 0, 0, time.UTC)
+	statement := deposit(nil, day, 1000)
+	statement = deposit(statement, day.Add(30*time.Minute), -200)
+	statement = deposit(statement, day.Add(2*time.Hour), 500)
+	statement = deposit(statement, day.Add(26*time.Hour), 300)
+	// a deposit written before the last bucket is booked in it
+	statement = deposit(statement, day.Add(10*time.Minute), 100)
+
+	assert.Equal(t, "1", statement.WalletId)
+	assert.Equal(t, float64(1700), statement.Balance)
+	assert.Equal(t, []entity.StatementBucket{
+		{Period: "2022-09-01", OpeningBalance: 800, Credits: 500, ClosingBalance: 1300, Deposits: 1},
+		{Period: "2022-09-02", OpeningBalance: 1300, Credits: 400, ClosingBalance: 1700, Deposits: 2},
+	}, statement.Daily, "should keep the last 2 days")
+	assert.Equal(t, []entity.StatementBucket{
+		{Period: "2022-08", OpeningBalance: 0, Credits: 1000, Debits: 200, ClosingBalance: 800, Deposits: 2},
+		{Period: "2022-09", OpeningBalance: 800, Credits: 900, ClosingBalance: 1700, Deposits: 3},
+	}, statement.Monthly)
+}
+
+func TestGetStatements(t *testing.T) {
+	statement := &entity.Statement{
+		WalletId: "1",
+		Daily:    []entity.StatementBucket{{Period: "2022-08-31", Credits: 100}},
+		Monthly:  []entity.StatementBucket{{Period: "2022-08", Credits: 100}},
+	}
+	newUsecase := func(statementTableMock *pubsubMock.ViewTable) wallet.Usecase {
+		return wallet.NewWalletUsecase(wallet.UsecaseProperty{Logger: logrus.New(), StatementViewTable: statementTableMock})
+	}
+
+	t.Run("daily and monthly", func(t *testing.T) {
+		statementTableMock := pubsubMock.ViewTable{}
+		statementTableMock.On("Get", "1").Return(statement, nil)
+		usecase := newUsecase(&statementTableMock)
+
+		daily, err := usecase.GetStatements(context.TODO(), "1", wallet.StatementPeriodDaily)
+		assert.Nil(t, err)
+		assert.Equal(t, webmodel.StatementResponse{WalletId: "1", Period: "daily", Statements: statement.Daily}, daily)
+
+		monthly, err := usecase.GetStatements(context.TODO(), "1", wallet.StatementPeriodMonthly)
+		assert.Nil(t, err)
+		assert.Equal(t, statement.Monthly, monthly.Statements)
+	})
+
+	t.Run("when wallet is not found", func(t *testing.T) {
+		statementTableMock := pubsubMock.ViewTable{}
+		statementTableMock.On("Get", "2").Return(nil, nil)
+
+		_, err := newUsecase(&statementTableMock).GetStatements(context.TODO(), "2", wallet.StatementPeriodDaily)
+
+		assert.ErrorIs(t, err, exception.ErrNotFound)
+	})
+
+	t.Run("when view is not readable", func(t *testing.T) {
+		statementTableMock := pubsubMock.ViewTable{}
+		statementTableMock.On("Get", "1").Return(nil, exception.ErrServiceUnavailable)
+
+		_, err := newUsecase(&statementTableMock).GetStatements(context.TODO(), "1", wallet.StatementPeriodDaily)
+
+		assert.Equal(t, exception.KindInternal, exception.KindOf(err))
+	})
+}
+
+func TestApplyCorrection_Keeps_Last_Deposit_Position(t *testing.T) {
+	contextMock := pubsubMock.GokaContext{}
+	usecase := wallet.NewWalletUsecase(wallet.UsecaseProperty{Logger: logrus.New()})
+	contextMock.On("Value").Return(&entity.Wallet{WalletId: "1", Balance: 200, LastDepositPartition: 1, LastDepositOffset: 9})
+	contextMock.On("SetValue", mock.Anything).Return(nil)
+	contextMock.On("Offset").Return(int64(4))
+
+	data := usecase.ApplyCorrection(&contextMock, &model.DepositWallet{WalletId: "1", Amount: -50})
+
+	assert.Equal(t, &entity.Wallet{WalletId: "1", Balance: 150, LastDepositPartition: 1, LastDepositOffset: 9, CorrectionOffset: 5}, data)
+}
@@ -0,0 +1,8 @@
+package webmodel
+
+import "github.com/ijalalfrz/coinbit-test/pubsub"
+
+// HealthResponse is response for liveness and readiness check
+type HealthResponse struct {
+	Checks []pubsub.Health `json:"checks"`
+}
@@ -1,5 +1,11 @@
 package webmodel
 
+import (
+	"time"
+
+	"github.com/ijalalfrz/coinbit-test/entity"
+)
+
 // DepositWalletPayload is model for deposit wallet http request payload
 type DepositWalletPayload struct {
 	WalletId string  `json:"wallet_id" validate:"required"`
@@ -7,9 +13,93 @@ type DepositWalletPayload struct {
 }
 
 // tes
-// DetailWalletResponse is response for get detail wallet
+// DetailWalletResponse is response for get detail wallet, ConsistencyToken is the position of the last deposit
+// applied by both views when it is known
 type DetailWalletResponse struct {
-	WalletId       string  `json:"wallet_id"`
-	Balance        float64 `json:"balance"`
-	AboveThreshold bool    `json:"above_threshold"`
+	WalletId         string  `json:"wallet_id"`
+	Balance          float64 `json:"balance"`
+	AboveThreshold   bool    `json:"above_threshold"`
+	ConsistencyToken string  `json:"consistency_token,omitempty"`
+}
+
+// BalanceAtResponse is response for get balance at a point in time, ConsistencyToken is the position of the last
+// deposit applied and Deposits is the number of deposits applied
+type BalanceAtResponse struct {
+	WalletId         string    `json:"wallet_id"`
+	Balance          float64   `json:"balance"`
+	AboveThreshold   bool      `json:"above_threshold"`
+	Deposits         int       `json:"deposits"`
+	LastDepositTime  time.Time `json:"last_deposit_time"`
+	ConsistencyToken string    `json:"consistency_token"`
+}
+
+// StatementResponse is response for get wallet statements, Period is daily or monthly
+type StatementResponse struct {
+	WalletId   string                   `json:"wallet_id"`
+	Period     string                   `json:"period"`
+	Statements []entity.StatementBucket `json:"statements"`
+}
+
+// DepositWalletResponse is response for deposit wallet, ConsistencyToken can be sent as min_offset
+// of get detail wallet to read the deposit. Wallet and AboveThreshold are only set when the deposit is waited for.
+type DepositWalletResponse struct {
+	Partition        int32          `json:"partition"`
+	Offset           int64          `json:"offset"`
+	ConsistencyToken string         `json:"consistency_token"`
+	Wallet           *entity.Wallet `json:"wallet,omitempty"`
+	AboveThreshold   *bool          `json:"above_threshold,omitempty"`
+}
+
+// WalletSubscriptionRequest is model for wallet subscriptions websocket message sent by the client,
+// Action is either subscribe or unsubscribe.
+type WalletSubscriptionRequest struct {
+	Action    string   `json:"action"`
+	WalletIds []string `json:"wallet_ids"`
+}
+
+// WalletSubscriptionMessage is model for wallet subscriptions websocket message sent by the server,
+// Type is subscribed, unsubscribed, wallet or error.
+type WalletSubscriptionMessage struct {
+	Type             string                `json:"type"`
+	WalletIds        []string              `json:"wallet_ids,omitempty"`
+	Wallet           *DetailWalletResponse `json:"wallet,omitempty"`
+	ConsistencyToken string                `json:"consistency_token,omitempty"`
+	Message          string                `json:"message,omitempty"`
+}
+
+// ListWalletsQuery is model for list wallets http request query, the nil balances and an empty prefix do not filter.
+// Restricted limits the wallets to WalletIds, it is set for the users which only own some wallets.
+type ListWalletsQuery struct {
+	MinBalance         *float64
+	MaxBalance         *float64
+	AboveThresholdOnly bool
+	Prefix             string
+	Sort               string
+	Cursor             string
+	Limit              int
+	Restricted         bool
+	WalletIds          []string
+}
+
+// PageMeta is the pagination metadata of a list response, NextCursor is only set when there is a next page.
+type PageMeta struct {
+	Limit      int    `json:"limit"`
+	Count      int    `json:"count"`
+	Total      int    `json:"total"`
+	Sort       string `json:"sort"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// BatchDetailWalletPayload is model for batch detail wallet http request payload
+type BatchDetailWalletPayload struct {
+	WalletIds []string `json:"wallet_ids" validate:"required,min=1,max=500,dive,required"`
+}
+
+// BatchDetailWalletItem is the detail of a wallet of a batch, Wallet is only set when it is found
+// and ErrorCode tells why it is not, e.g. not_found or forbidden.
+type BatchDetailWalletItem struct {
+	WalletId  string                `json:"wallet_id"`
+	Found     bool                  `json:"found"`
+	Wallet    *DetailWalletResponse `json:"wallet,omitempty"`
+	ErrorCode string                `json:"error_code,omitempty"`
 }